@@ -1 +1,12 @@
 data "omni_default_machine_join_config" "example" {}
+
+data "omni_default_machine_join_config" "custom_token" {
+  join_token_id   = "my-join-token-id"
+  use_grpc_tunnel = false
+}
+
+data "omni_default_machine_join_config" "pxe" {
+  extra_kernel_args  = ["talos.platform=metal", "console=tty0"]
+  ipxe_kernel_url    = "https://pxe.example.com/talos/v1.11.2/vmlinuz-amd64"
+  ipxe_initramfs_url = "https://pxe.example.com/talos/v1.11.2/initramfs-amd64.xz"
+}
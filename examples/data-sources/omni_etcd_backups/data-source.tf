@@ -0,0 +1,13 @@
+# Example shown lists the etcd backups of the cluster "example"
+data "omni_etcd_backups" "example" {
+  cluster = "example"
+}
+
+# Example shown lists the etcd backups of a cluster which no longer exists
+data "omni_etcd_backups" "deleted" {
+  cluster_uuid = "6e2ab8c5-0b5e-4bc4-9d36-3f0b1b3c7a2d"
+}
+
+output "latest_snapshot" {
+  value = data.omni_etcd_backups.example.backups[0].snapshot
+}
@@ -0,0 +1,28 @@
+# Example shown looks up the ISO for amd64 machines built from the default
+# schematic Omni creates for the installation media
+data "omni_installation_media" "iso" {
+  talos_version = "1.11.2"
+  platform      = "iso"
+  architecture  = "amd64"
+}
+
+# Example shown looks up the SecureBoot ISO built from a custom schematic
+resource "omni_schematic" "example" {
+  talos_version = "1.11.2"
+  secure_boot   = true
+  extensions = [
+    "siderolabs/iscsi-tools",
+  ]
+}
+
+data "omni_installation_media" "secureboot_iso" {
+  talos_version = "1.11.2"
+  platform      = "iso"
+  architecture  = "amd64"
+  secure_boot   = true
+  schematic_id  = omni_schematic.example.id
+}
+
+output "iso_download_url" {
+  value = data.omni_installation_media.iso.media[0].download_url
+}
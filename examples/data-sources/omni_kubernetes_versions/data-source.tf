@@ -0,0 +1,21 @@
+# Example shown resolves the latest Kubernetes version compatible with the
+# latest Talos 1.11 patch release
+data "omni_talos_versions" "talos_1_11" {
+  constraint = "1.11.x"
+}
+
+data "omni_kubernetes_versions" "compatible" {
+  talos_version = data.omni_talos_versions.talos_1_11.latest
+}
+
+# Example shown checks the versions of the cluster template against Omni
+data "omni_cluster_template" "example" {
+  name = "example"
+  talos = {
+    version = data.omni_talos_versions.talos_1_11.latest
+  }
+  kubernetes = {
+    version = data.omni_kubernetes_versions.compatible.latest
+  }
+  validate_versions = true
+}
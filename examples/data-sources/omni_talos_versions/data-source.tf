@@ -0,0 +1,8 @@
+# Example shown resolves the latest Talos 1.11 patch release available in Omni
+data "omni_talos_versions" "talos_1_11" {
+  constraint = "1.11.x"
+}
+
+output "talos_version" {
+  value = data.omni_talos_versions.talos_1_11.latest
+}
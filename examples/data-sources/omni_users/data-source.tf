@@ -0,0 +1,8 @@
+# Example shown lists the email of every Omni admin
+data "omni_users" "admins" {
+  role = "Admin"
+}
+
+output "admins" {
+  value = data.omni_users.admins.users[*].email
+}
@@ -12,3 +12,88 @@ provider "omni" {
   endpoint            = var.omni_uri
   service_account_key = var.omni_service_account_key
 }
+
+# The key can also be read from a file, e.g. one mounted from a secret store
+provider "omni" {
+  alias                    = "key_file"
+  endpoint                 = var.omni_uri
+  service_account_key_file = "/run/secrets/omni-service-account-key"
+}
+
+# Authenticates with the user account of an omnictl context instead of a service
+# account, the endpoint defaults to the URL of the context
+provider "omni" {
+  alias   = "omnictl"
+  context = "staging"
+}
+
+# An on-prem Omni behind an internal CA, reached through an HTTP proxy
+provider "omni" {
+  alias                    = "on_prem"
+  endpoint                 = "https://omni.internal.example.com"
+  service_account_key_file = "/run/secrets/omni-service-account-key"
+
+  ca_bundle              = file("/etc/ssl/certs/internal-ca.pem")
+  proxy_url              = "http://proxy.internal.example.com:3128"
+  tcp_keepalive_interval = "30s"
+  max_recv_msg_size      = 67108864
+  user_agent             = "platform-pipeline"
+}
+
+# Rides out Omni restarts during upgrades
+provider "omni" {
+  alias    = "retry"
+  endpoint = var.omni_uri
+
+  retry {
+    max_attempts = 8
+    min_backoff  = "2s"
+    max_backoff  = "1m"
+  }
+}
+
+# Spares Omni when managing large fleets with a high Terraform parallelism
+provider "omni" {
+  alias    = "fleet"
+  endpoint = var.omni_uri
+
+  rate_limit     = 20
+  max_in_flight  = 8
+  list_cache_ttl = "30s"
+  machine_cache  = "watch"
+}
+
+variable "omni_eu_service_account_key" {
+  type      = string
+  sensitive = true
+}
+
+variable "omni_us_service_account_key" {
+  type      = string
+  sensitive = true
+}
+
+# Manages several Omni instances from one provider, resources pick theirs with
+# omni_instance and use the default instance otherwise
+provider "omni" {
+  alias    = "regions"
+  endpoint = var.omni_uri
+
+  instances {
+    name                = "eu"
+    endpoint            = "https://eu.omni.example.com"
+    service_account_key = var.omni_eu_service_account_key
+  }
+
+  instances {
+    name                = "us"
+    endpoint            = "https://us.omni.example.com"
+    service_account_key = var.omni_us_service_account_key
+  }
+}
+
+resource "omni_cluster_kubeconfig" "eu" {
+  provider      = omni.regions
+  omni_instance = "eu"
+  cluster       = "production"
+}
@@ -0,0 +1 @@
+terraform import omni_access_policy.this "access-policy"
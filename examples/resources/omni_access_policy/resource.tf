@@ -0,0 +1,41 @@
+resource "omni_access_policy" "this" {
+  user_groups = {
+    support = {
+      users = [
+        { name = "lead@example.com" },
+        { match = "*@support.example.com" },
+        { label_selectors = ["saml.omni.sidero.dev/groups=support"] },
+      ]
+    }
+  }
+  cluster_groups = {
+    dev = {
+      clusters = [
+        { name = "staging" },
+        { match = "dev-*" },
+      ]
+    }
+  }
+  rules = [
+    {
+      users                         = ["group/support"]
+      clusters                      = ["group/dev"]
+      role                          = "Operator"
+      kubernetes_impersonate_groups = ["system:masters"]
+    }
+  ]
+  tests = [
+    {
+      name                                   = "support operates dev clusters"
+      user                                   = "engineer@support.example.com"
+      cluster                                = "dev-1"
+      expected_role                          = "Operator"
+      expected_kubernetes_impersonate_groups = ["system:masters"]
+    },
+    {
+      name    = "support has no access to production"
+      user    = "engineer@support.example.com"
+      cluster = "production"
+    },
+  ]
+}
@@ -1 +1,5 @@
-terraform import omni_cluster.my_cluster "name_of_cluster"
\ No newline at end of file
+terraform import omni_cluster.my_cluster "name_of_cluster"
+
+# Resources of a named Omni instance of the provider are imported with the name of
+# the instance as a prefix
+terraform import omni_cluster.my_cluster "eu/name_of_cluster"
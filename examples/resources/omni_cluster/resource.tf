@@ -43,5 +43,5 @@ resource "omni_cluster" "my_cluster" {
       { for machine in omni_cluster_machine_template.workers : machine.name => machine.yaml }
     )
   )
-  delete_machine_links = true
+  machine_removal = "remove"
 }
@@ -32,4 +32,32 @@ resource "omni_cluster_machine_set_template" "example" {
   system_extensions = [
     "siderolabs/util-linux-tools"
   ]
+  update_strategy = {
+    type            = "Rolling"
+    max_parallelism = 2
+  }
+  delete_strategy = {
+    type = "Unset"
+  }
+}
+
+# Example shown restores the control plane of a new cluster from the latest
+# etcd backup of a cluster which no longer exists
+variable "source_cluster_uuid" {
+  type = string
+}
+
+data "omni_etcd_backups" "source" {
+  cluster_uuid = var.source_cluster_uuid
+}
+
+resource "omni_cluster_machine_set_template" "restored_control_plane" {
+  kind = "controlplane"
+  machines = [
+    "22222222-2222-2222-2222-222222222222",
+  ]
+  restore_from_backup = {
+    cluster_uuid = var.source_cluster_uuid
+    snapshot     = data.omni_etcd_backups.source.backups[0].snapshot
+  }
 }
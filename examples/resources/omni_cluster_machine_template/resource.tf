@@ -10,6 +10,9 @@ resource "omni_cluster_machine_template" "example" {
   install = {
     disk = "/dev/sda"
   }
+  kernel_args = [
+    "console=ttyS0",
+  ]
   locked = false
   patches = [
     {
@@ -28,3 +31,17 @@ resource "omni_cluster_machine_template" "example" {
     "siderolabs/util-linux-tools"
   ]
 }
+
+
+# Example shown installs Talos to the only NVMe disk of at least 500GB, whatever
+# name the machine gives it
+resource "omni_cluster_machine_template" "disk_selector" {
+  name = "00000000-0000-0000-0000-000000000000"
+  role = "worker"
+  install = {
+    disk_selector = {
+      type     = "nvme"
+      min_size = "500GB"
+    }
+  }
+}
@@ -0,0 +1 @@
+terraform import omni_etcd_backup_s3_config.backups "etcd-backup-s3-conf"
\ No newline at end of file
@@ -0,0 +1,33 @@
+variable "backup_secret_access_key" {
+  type      = string
+  sensitive = true
+  ephemeral = true
+}
+
+# Example shown stores etcd backups in a MinIO bucket. The secret access key
+# is write-only, bump credentials_wo_version to rotate it.
+resource "omni_etcd_backup_s3_config" "backups" {
+  bucket                 = "omni-etcd-backups"
+  region                 = "us-east-1"
+  endpoint               = "https://minio.example.com:9000"
+  access_key_id          = "omni-backups"
+  secret_access_key_wo   = var.backup_secret_access_key
+  credentials_wo_version = 1
+}
+
+# Example shown enables hourly backups for a cluster once the S3 storage is
+# configured
+data "omni_cluster_template" "example" {
+  name = "example"
+  talos = {
+    version = "v1.11.2"
+  }
+  kubernetes = {
+    version = "v1.33.4"
+  }
+  features = {
+    backup_configuration = {
+      interval = "1h"
+    }
+  }
+}
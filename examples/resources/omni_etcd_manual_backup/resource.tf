@@ -0,0 +1,12 @@
+# Example shown takes a new etcd backup of the cluster every time the cluster
+# template changes, before the template is applied
+resource "omni_etcd_manual_backup" "before_upgrade" {
+  cluster = "example"
+  triggers = {
+    template = sha256(data.omni_cluster_template.example.yaml)
+  }
+}
+
+output "backup_snapshot" {
+  value = omni_etcd_manual_backup.before_upgrade.snapshot
+}
@@ -0,0 +1 @@
+terraform import omni_identity.engineer "jane@example.com"
@@ -0,0 +1,11 @@
+# Example shown lets an engineer sign in with both their work and their contractor
+# email as the same Omni user
+resource "omni_user" "engineer" {
+  role = "Reader"
+}
+
+resource "omni_identity" "engineer" {
+  for_each = toset(["jane@example.com", "jane@contractor.example.com"])
+  email    = each.value
+  user_id  = omni_user.engineer.id
+}
@@ -0,0 +1 @@
+terraform import omni_join_token.staging "id_of_join_token"
\ No newline at end of file
@@ -0,0 +1,9 @@
+resource "omni_join_token" "staging" {
+  name            = "staging"
+  expiration_time = "2027-01-01T00:00:00Z"
+}
+
+data "omni_default_machine_join_config" "staging" {
+  join_token_id   = omni_join_token.staging.id
+  use_grpc_tunnel = false
+}
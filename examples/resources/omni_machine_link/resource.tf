@@ -0,0 +1,5 @@
+# Example shown removes a decommissioned node from Omni once the machine was taken out
+# of its cluster, by destroying the resource
+resource "omni_machine_link" "node" {
+  machine_id = "00000000-0000-0000-0000-000000000000"
+}
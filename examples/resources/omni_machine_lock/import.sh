@@ -0,0 +1 @@
+terraform import omni_machine_lock.maintenance "00000000-0000-0000-0000-000000000000"
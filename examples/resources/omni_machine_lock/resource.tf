@@ -0,0 +1,5 @@
+# Example shown locks a machine of a cluster during a maintenance window, so that
+# Omni doesn't apply config changes or upgrades to it
+resource "omni_machine_lock" "maintenance" {
+  machine_id = "00000000-0000-0000-0000-000000000000"
+}
@@ -0,0 +1,6 @@
+# Example shown upgrades a machine in maintenance mode to the Talos version of the
+# cluster it is about to join
+resource "omni_machine_maintenance_upgrade" "example" {
+  machine_id    = "00000000-0000-0000-0000-000000000000"
+  talos_version = "1.10.5"
+}
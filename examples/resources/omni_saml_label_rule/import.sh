@@ -0,0 +1 @@
+terraform import omni_saml_label_rule.platform "platform-operators"
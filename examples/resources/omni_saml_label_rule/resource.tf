@@ -0,0 +1,7 @@
+# Example shown makes members of the platform group of the IdP operators when they sign
+# in to Omni for the first time
+resource "omni_saml_label_rule" "platform" {
+  name                        = "platform-operators"
+  match_labels                = ["saml.omni.sidero.dev/groups/platform"]
+  assign_role_on_registration = "Operator"
+}
@@ -0,0 +1,17 @@
+resource "omni_schematic" "workers" {
+  talos_version = "1.11.2"
+  extensions = [
+    "siderolabs/iscsi-tools",
+    "siderolabs/qemu-guest-agent",
+  ]
+  extra_kernel_args = ["console=ttyS0"]
+  initial_labels = {
+    environment = "staging"
+  }
+}
+
+resource "omni_schematic" "raspberry_pi" {
+  talos_version = "1.11.2"
+  architecture  = "arm64"
+  media_id      = "rpi_generic-arm64"
+}
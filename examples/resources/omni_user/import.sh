@@ -0,0 +1 @@
+terraform import omni_user.jane "00000000-0000-0000-0000-000000000000"
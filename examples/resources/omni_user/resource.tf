@@ -0,0 +1,8 @@
+resource "omni_user" "jane" {
+  role = "Operator"
+}
+
+resource "omni_identity" "jane" {
+  email   = "jane@example.com"
+  user_id = omni_user.jane.id
+}
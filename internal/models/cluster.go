@@ -12,7 +12,7 @@ type ClusterYAML struct {
 	Annotations      map[string]string     `tfsdk:"annotations" yaml:"annotations,omitempty"`
 	Kubernetes       ClusterKubernetesYAML `tfsdk:"kubernetes" yaml:"kubernetes"`
 	Talos            ClusterTalosYAML      `tfsdk:"talos" yaml:"talos"`
-	Features         ClusterFeaturesYAML   `tfsdk:"featuers" yaml:"features,omitempty"`
+	Features         ClusterFeaturesYAML   `tfsdk:"features" yaml:"features,omitempty"`
 	Patches          []PatchYAML           `tfsdk:"patches" yaml:"patches,omitempty"`
 	SystemExtensions []string              `tfsdk:"system_extensions" yaml:"systemExtensions,omitempty"`
 }
@@ -32,20 +32,20 @@ type ClusterTalosYAML struct {
 }
 
 type ClusterFeatures struct {
-	DiskEncryption              types.Bool                 `tfsdk:"disk_encryption"`
-	EnableWorkloadProxy         types.Bool                 `tfsdk:"enable_workload_proxy"`
-	UseEmbeddedDiscoveryService types.Bool                 `tfsdk:"use_embedded_discovery_service"`
-	BackupConfiguration         ClusterBackupConfiguration `tfsdk:"backup_configuration"`
+	DiskEncryption              types.Bool                  `tfsdk:"disk_encryption"`
+	EnableWorkloadProxy         types.Bool                  `tfsdk:"enable_workload_proxy"`
+	UseEmbeddedDiscoveryService types.Bool                  `tfsdk:"use_embedded_discovery_service"`
+	BackupConfiguration         *ClusterBackupConfiguration `tfsdk:"backup_configuration"`
 }
 type ClusterFeaturesYAML struct {
-	DiskEncryption              bool                           `tfsdk:"disk_encryption" yaml:"diskEncryption,omitempty"`
-	EnableWorkloadProxy         bool                           `tfsdk:"enable_workload_proxy" yaml:"enableWorkloadProxy,omitempty"`
-	UseEmbeddedDiscoveryService bool                           `tfsdk:"use_embedded_discovery_service" yaml:"useEmbeddedDiscoveryService,omitempty"`
-	BackupConfiguration         ClusterBackupConfigurationYAML `tfsdk:"backup_configuration" yaml:"backupConfiguration,omitempty"`
+	DiskEncryption              bool                            `tfsdk:"disk_encryption" yaml:"diskEncryption,omitempty"`
+	EnableWorkloadProxy         bool                            `tfsdk:"enable_workload_proxy" yaml:"enableWorkloadProxy,omitempty"`
+	UseEmbeddedDiscoveryService bool                            `tfsdk:"use_embedded_discovery_service" yaml:"useEmbeddedDiscoveryService,omitempty"`
+	BackupConfiguration         *ClusterBackupConfigurationYAML `tfsdk:"backup_configuration" yaml:"backupConfiguration,omitempty"`
 }
 
 type ClusterBackupConfigurationYAML struct {
-	Interval string `tfsdk:"interval" yaml:"interval"`
+	Interval string `tfsdk:"interval" yaml:"interval,omitempty"`
 }
 type ClusterBackupConfiguration struct {
 	Interval timetypes.GoDuration `tfsdk:"interval"`
@@ -1,18 +1,35 @@
 package models
 
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
 type MachineIDList []string
 
 type MachineInstall struct {
-	Disk string `tfsdk:"disk" yaml:"disk"`
+	Disk         types.String         `tfsdk:"disk"`
+	DiskSelector *InstallDiskSelector `tfsdk:"disk_selector"`
+}
+
+type InstallDiskSelector struct {
+	MinSize types.String `tfsdk:"min_size"`
+	MaxSize types.String `tfsdk:"max_size"`
+	Model   types.String `tfsdk:"model"`
+	Serial  types.String `tfsdk:"serial"`
+	Type    types.String `tfsdk:"type"`
+	BusPath types.String `tfsdk:"bus_path"`
+	WWID    types.String `tfsdk:"wwid"`
+}
+
+type MachineInstallYAML struct {
+	Disk string `yaml:"disk"`
 }
 
 type MachinesYAML struct {
-	Kind             string            `tfsdk:"kind" yaml:"kind"`
-	SystemExtensions []string          `tfsdk:"system_extensions" yaml:"systemExtensions,omitempty"`
-	Name             string            `tfsdk:"name" yaml:"name"`
-	Labels           map[string]string `tfsdk:"labels" yaml:"labels,omitempty"`
-	Annotations      map[string]string `tfsdk:"annotations" yaml:"annotations,omitempty"`
-	Locked           bool              `tfsdk:"locked" yaml:"locked,omitempty"`
-	Install          map[string]any    `tfsdk:"install" yaml:"install,omitempty"`
-	Patches          []PatchYAML       `tfsdk:"patches" yaml:"patches,omitempty"`
+	Kind             string              `tfsdk:"kind" yaml:"kind"`
+	SystemExtensions []string            `tfsdk:"system_extensions" yaml:"systemExtensions,omitempty"`
+	Name             string              `tfsdk:"name" yaml:"name"`
+	Labels           map[string]string   `tfsdk:"labels" yaml:"labels,omitempty"`
+	Annotations      map[string]string   `tfsdk:"annotations" yaml:"annotations,omitempty"`
+	Locked           bool                `tfsdk:"locked" yaml:"locked,omitempty"`
+	Install          *MachineInstallYAML `tfsdk:"install" yaml:"install,omitempty"`
+	Patches          []PatchYAML         `tfsdk:"patches" yaml:"patches,omitempty"`
 }
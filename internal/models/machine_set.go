@@ -1,11 +1,47 @@
 package models
 
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
 type MachineSetYAML struct {
-	Kind             string            `yaml:"kind"`
-	SystemExtensions []string          `yaml:"systemExtensions,omitempty"`
-	Name             string            `yaml:"name,omitempty"`
-	Labels           map[string]string `yaml:"labels,omitempty"`
-	Annotations      map[string]string `yaml:"annotations,omitempty"`
-	Machines         []string          `yaml:"machines"`
-	Patches          []PatchYAML       `yaml:"patches,omitempty"`
+	Kind             string              `yaml:"kind"`
+	SystemExtensions []string            `yaml:"systemExtensions,omitempty"`
+	Name             string              `yaml:"name,omitempty"`
+	Labels           map[string]string   `yaml:"labels,omitempty"`
+	Annotations      map[string]string   `yaml:"annotations,omitempty"`
+	BootstrapSpec    *BootstrapSpecYAML  `yaml:"bootstrapSpec,omitempty"`
+	Machines         []string            `yaml:"machines,omitempty"`
+	MachineClass     *MachineClassYAML   `yaml:"machineClass,omitempty"`
+	UpdateStrategy   *UpdateStrategyYAML `yaml:"updateStrategy,omitempty"`
+	DeleteStrategy   *UpdateStrategyYAML `yaml:"deleteStrategy,omitempty"`
+	Patches          []PatchYAML         `yaml:"patches,omitempty"`
+}
+
+type BootstrapSpec struct {
+	ClusterUUID types.String `tfsdk:"cluster_uuid"`
+	Snapshot    types.String `tfsdk:"snapshot"`
+}
+type BootstrapSpecYAML struct {
+	ClusterUUID string `yaml:"clusterUUID"`
+	Snapshot    string `yaml:"snapshot"`
+}
+
+type MachineClass struct {
+	Name types.String `tfsdk:"name"`
+	Size types.String `tfsdk:"size"`
+}
+type MachineClassYAML struct {
+	Name string `yaml:"name"`
+	Size string `yaml:"size"`
+}
+
+type UpdateStrategy struct {
+	Type           types.String `tfsdk:"type"`
+	MaxParallelism types.Int64  `tfsdk:"max_parallelism"`
+}
+type UpdateStrategyYAML struct {
+	Type    *string                    `yaml:"type,omitempty"`
+	Rolling *RollingUpdateStrategyYAML `yaml:"rolling,omitempty"`
+}
+type RollingUpdateStrategyYAML struct {
+	MaxParallelism uint32 `yaml:"maxParallelism,omitempty"`
 }
@@ -11,6 +11,7 @@ type Annotations map[string]string
 
 type PatchList []Patch
 type Patch struct {
+	Name        *string     `tfsdk:"name" yaml:"name,omitempty"`
 	IDOverride  string      `tfsdk:"id_override" yaml:"idOverride"`
 	Labels      Labels      `tfsdk:"labels" yaml:"labels,omitempty"`
 	Annotations Annotations `tfsdk:"annotations" yaml:"annotations,omitempty"`
@@ -18,7 +19,8 @@ type Patch struct {
 	Inline      *string     `tfsdk:"inline" yaml:"inline,omitempty"`
 }
 type PatchYAML struct {
-	IDOverride  string            `yaml:"idOverride"`
+	Name        string            `yaml:"name,omitempty"`
+	IDOverride  string            `yaml:"idOverride,omitempty"`
 	Labels      map[string]string `yaml:"labels,omitempty"`
 	Annotations map[string]string `yaml:"annotations,omitempty"`
 	File        *string           `yaml:"file,omitempty"`
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestTemplateModelsCompatibility compares the YAML fields of the template models with
+// the cluster template models of the pinned Omni client, so that template options
+// added or removed by a client upgrade fail the tests.
+func TestTemplateModelsCompatibility(t *testing.T) {
+	omniTypes := parseOmniTemplateModels(t)
+
+	for _, tc := range []struct {
+		kind  string
+		model any
+	}{
+		{kind: "Cluster", model: ClusterYAML{}},
+		{kind: "ControlPlane", model: MachineSetYAML{}},
+		{kind: "Workers", model: MachineSetYAML{}},
+		{kind: "Machine", model: MachinesYAML{}},
+	} {
+		t.Run(tc.kind, func(t *testing.T) {
+			expected := omniYAMLPaths(omniTypes, &ast.Ident{Name: tc.kind}, "")
+			actual := modelYAMLPaths(reflect.TypeOf(tc.model), "")
+
+			for _, path := range expected {
+				if !slices.Contains(actual, path) {
+					t.Errorf("template field %q of kind %s is not covered by %T", path, tc.kind, tc.model)
+				}
+			}
+
+			for _, path := range actual {
+				if !slices.Contains(expected, path) {
+					t.Errorf("field %q of %T is not a template field of kind %s", path, tc.model, tc.kind)
+				}
+			}
+		})
+	}
+}
+
+// parseOmniTemplateModels returns the type declarations of the template models in the
+// Omni client module the provider is built with.
+func parseOmniTemplateModels(t *testing.T) map[string]ast.Expr {
+	t.Helper()
+
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "github.com/siderolabs/omni/client").Output()
+	if err != nil {
+		t.Fatalf("failed to locate the Omni client module: %s", err)
+	}
+
+	dir := filepath.Join(strings.TrimSpace(string(out)), "pkg", "template", "internal", "models")
+
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, nil, 0)
+	if err != nil {
+		t.Fatalf("failed to parse the Omni template models: %s", err)
+	}
+
+	types := map[string]ast.Expr{}
+
+	for _, pkg := range pkgs {
+		for name, file := range pkg.Files {
+			if strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+
+			ast.Inspect(file, func(node ast.Node) bool {
+				if spec, ok := node.(*ast.TypeSpec); ok {
+					types[spec.Name.Name] = spec.Type
+				}
+
+				return true
+			})
+		}
+	}
+
+	return types
+}
+
+// omniYAMLPaths returns the YAML field paths of a type declared in the Omni template
+// models. Slices are marked with "[]".
+func omniYAMLPaths(types map[string]ast.Expr, expr ast.Expr, prefix string) []string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return omniYAMLPaths(types, e.X, prefix)
+	case *ast.ArrayType:
+		return omniYAMLPaths(types, e.Elt, prefix+"[]")
+	case *ast.Ident:
+		if underlying, ok := types[e.Name]; ok {
+			return omniYAMLPaths(types, underlying, prefix)
+		}
+	case *ast.StructType:
+		var paths []string
+
+		for _, field := range e.Fields.List {
+			if field.Tag == nil {
+				continue
+			}
+
+			tag, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				continue
+			}
+
+			name, inline, ok := parseYAMLTag(reflect.StructTag(tag).Get("yaml"))
+			if !ok {
+				continue
+			}
+
+			if inline {
+				paths = append(paths, omniYAMLPaths(types, field.Type, prefix)...)
+				continue
+			}
+
+			paths = append(paths, omniYAMLPaths(types, field.Type, joinYAMLPath(prefix, name))...)
+		}
+
+		if paths != nil {
+			return paths
+		}
+	}
+
+	return []string{prefix}
+}
+
+// modelYAMLPaths returns the YAML field paths of a provider model, in the same form as
+// omniYAMLPaths.
+func modelYAMLPaths(typ reflect.Type, prefix string) []string {
+	switch typ.Kind() { //nolint:exhaustive
+	case reflect.Pointer:
+		return modelYAMLPaths(typ.Elem(), prefix)
+	case reflect.Slice:
+		return modelYAMLPaths(typ.Elem(), prefix+"[]")
+	case reflect.Struct:
+		var paths []string
+
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+
+			name, inline, ok := parseYAMLTag(field.Tag.Get("yaml"))
+			if !ok {
+				continue
+			}
+
+			if inline {
+				paths = append(paths, modelYAMLPaths(field.Type, prefix)...)
+				continue
+			}
+
+			paths = append(paths, modelYAMLPaths(field.Type, joinYAMLPath(prefix, name))...)
+		}
+
+		if paths != nil {
+			return paths
+		}
+	}
+
+	return []string{prefix}
+}
+
+func parseYAMLTag(tag string) (name string, inline bool, ok bool) {
+	if tag == "" || tag == "-" {
+		return "", false, false
+	}
+
+	name, options, _ := strings.Cut(tag, ",")
+
+	return name, slices.Contains(strings.Split(options, ","), "inline"), true
+}
+
+func joinYAMLPath(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "." + name
+}
@@ -0,0 +1,217 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	cosiresource "github.com/cosi-project/runtime/pkg/resource"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/cosi/labels"
+)
+
+// accessPolicyGroupPrefix marks the users and clusters of an access policy rule which
+// reference a group instead of a single user or cluster.
+const accessPolicyGroupPrefix = "group/"
+
+// accessPolicyResult is what an access policy grants a user on a cluster.
+type accessPolicyResult struct {
+	role              string
+	impersonateGroups []string
+}
+
+// userRoleRank orders the roles by the access they grant.
+func userRoleRank(role string) int {
+	return slices.Index(userRoles, role)
+}
+
+// validateAccessPolicy checks the structure of an access policy: group entries, group
+// references, glob patterns, label selectors and roles.
+func validateAccessPolicy(spec *specs.AccessPolicySpec) error {
+	var errs []error
+
+	for _, groupName := range slices.Sorted(maps.Keys(spec.GetUserGroups())) {
+		for i, user := range spec.GetUserGroups()[groupName].GetUsers() {
+			set := 0
+
+			for _, ok := range []bool{user.GetName() != "", user.GetMatch() != "", len(user.GetLabelSelectors()) > 0} {
+				if ok {
+					set++
+				}
+			}
+
+			if set != 1 {
+				errs = append(errs, fmt.Errorf("user %d of user group %q must set exactly one of name, match or label_selectors", i, groupName))
+			}
+
+			if _, err := path.Match(user.GetMatch(), ""); err != nil {
+				errs = append(errs, fmt.Errorf("user %d of user group %q has an invalid match pattern %q", i, groupName, user.GetMatch()))
+			}
+
+			if _, err := labels.ParseSelectors(user.GetLabelSelectors()); err != nil {
+				errs = append(errs, fmt.Errorf("user %d of user group %q has invalid label selectors: %w", i, groupName, err))
+			}
+		}
+	}
+
+	for _, groupName := range slices.Sorted(maps.Keys(spec.GetClusterGroups())) {
+		for i, cluster := range spec.GetClusterGroups()[groupName].GetClusters() {
+			if (cluster.GetName() == "") == (cluster.GetMatch() == "") {
+				errs = append(errs, fmt.Errorf("cluster %d of cluster group %q must set exactly one of name or match", i, groupName))
+			}
+
+			if _, err := path.Match(cluster.GetMatch(), ""); err != nil {
+				errs = append(errs, fmt.Errorf("cluster %d of cluster group %q has an invalid match pattern %q", i, groupName, cluster.GetMatch()))
+			}
+		}
+	}
+
+	for i, rule := range spec.GetRules() {
+		if rule.GetRole() != "" && userRoleRank(rule.GetRole()) < 0 {
+			errs = append(errs, fmt.Errorf("rule %d has an unknown role %q", i, rule.GetRole()))
+		}
+
+		for _, user := range rule.GetUsers() {
+			if groupName, ok := strings.CutPrefix(user, accessPolicyGroupPrefix); ok {
+				if _, exists := spec.GetUserGroups()[groupName]; !exists {
+					errs = append(errs, fmt.Errorf("rule %d references unknown user group %q", i, groupName))
+				}
+			} else if _, err := path.Match(user, ""); err != nil {
+				errs = append(errs, fmt.Errorf("rule %d has an invalid user pattern %q", i, user))
+			}
+		}
+
+		for _, cluster := range rule.GetClusters() {
+			if groupName, ok := strings.CutPrefix(cluster, accessPolicyGroupPrefix); ok {
+				if _, exists := spec.GetClusterGroups()[groupName]; !exists {
+					errs = append(errs, fmt.Errorf("rule %d references unknown cluster group %q", i, groupName))
+				}
+			} else if _, err := path.Match(cluster, ""); err != nil {
+				errs = append(errs, fmt.Errorf("rule %d has an invalid cluster pattern %q", i, cluster))
+			}
+		}
+	}
+
+	for i, test := range spec.GetTests() {
+		if expectedRole := test.GetExpected().GetRole(); expectedRole != "" && userRoleRank(expectedRole) < 0 {
+			errs = append(errs, fmt.Errorf("test %d has an unknown expected role %q", i, expectedRole))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkAccessPolicy evaluates an access policy for a user identity with the given
+// labels on a cluster. The role is the highest role of the matching rules, the
+// impersonation groups are the union of their groups.
+func checkAccessPolicy(spec *specs.AccessPolicySpec, identity string, identityLabels map[string]string, cluster string) accessPolicyResult {
+	result := accessPolicyResult{role: userRoleNone}
+
+	var userLabels cosiresource.Labels
+	for key, value := range identityLabels {
+		userLabels.Set(key, value)
+	}
+
+	for _, rule := range spec.GetRules() {
+		matchesUser := slices.ContainsFunc(rule.GetUsers(), func(user string) bool {
+			if groupName, ok := strings.CutPrefix(user, accessPolicyGroupPrefix); ok {
+				return userGroupMatches(spec.GetUserGroups()[groupName], identity, userLabels)
+			}
+
+			return globMatches(user, identity)
+		})
+
+		matchesCluster := slices.ContainsFunc(rule.GetClusters(), func(clusterPattern string) bool {
+			if groupName, ok := strings.CutPrefix(clusterPattern, accessPolicyGroupPrefix); ok {
+				return clusterGroupMatches(spec.GetClusterGroups()[groupName], cluster)
+			}
+
+			return globMatches(clusterPattern, cluster)
+		})
+
+		if !matchesUser || !matchesCluster {
+			continue
+		}
+
+		if userRoleRank(rule.GetRole()) > userRoleRank(result.role) {
+			result.role = rule.GetRole()
+		}
+
+		result.impersonateGroups = append(result.impersonateGroups, rule.GetKubernetes().GetImpersonate().GetGroups()...)
+	}
+
+	slices.Sort(result.impersonateGroups)
+	result.impersonateGroups = slices.Compact(result.impersonateGroups)
+
+	return result
+}
+
+// runAccessPolicyTests evaluates the tests of an access policy and returns an error for
+// each failing test.
+func runAccessPolicyTests(spec *specs.AccessPolicySpec) []error {
+	var errs []error
+
+	for i, test := range spec.GetTests() {
+		name := test.GetName()
+		if name == "" {
+			name = fmt.Sprintf("test %d", i)
+		}
+
+		result := checkAccessPolicy(spec, test.GetUser().GetName(), test.GetUser().GetLabels(), test.GetCluster().GetName())
+
+		expectedRole := test.GetExpected().GetRole()
+		if expectedRole == "" {
+			expectedRole = userRoleNone
+		}
+
+		if result.role != expectedRole {
+			errs = append(errs, fmt.Errorf("%s: expected role %q, got %q", name, expectedRole, result.role))
+		}
+
+		expectedGroups := slices.Clone(test.GetExpected().GetKubernetes().GetImpersonate().GetGroups())
+		slices.Sort(expectedGroups)
+		expectedGroups = slices.Compact(expectedGroups)
+
+		if !slices.Equal(result.impersonateGroups, expectedGroups) {
+			errs = append(errs, fmt.Errorf("%s: expected impersonate groups %v, got %v", name, expectedGroups, result.impersonateGroups))
+		}
+	}
+
+	return errs
+}
+
+func userGroupMatches(group *specs.AccessPolicyUserGroup, identity string, identityLabels cosiresource.Labels) bool {
+	return slices.ContainsFunc(group.GetUsers(), func(user *specs.AccessPolicyUserGroup_User) bool {
+		switch {
+		case user.GetName() != "":
+			return user.GetName() == identity
+		case user.GetMatch() != "":
+			return globMatches(user.GetMatch(), identity)
+		case len(user.GetLabelSelectors()) > 0:
+			queries, err := labels.ParseSelectors(user.GetLabelSelectors())
+
+			return err == nil && queries.Matches(identityLabels)
+		default:
+			return false
+		}
+	})
+}
+
+func clusterGroupMatches(group *specs.AccessPolicyClusterGroup, cluster string) bool {
+	return slices.ContainsFunc(group.GetClusters(), func(c *specs.AccessPolicyClusterGroup_Cluster) bool {
+		if c.GetName() != "" {
+			return c.GetName() == cluster
+		}
+
+		return globMatches(c.GetMatch(), cluster)
+	})
+}
+
+func globMatches(pattern string, value string) bool {
+	matched, err := path.Match(pattern, value)
+
+	return err == nil && matched
+}
@@ -0,0 +1,614 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/auth"
+)
+
+var (
+	_ resource.Resource                   = &omniAccessPolicyResource{}
+	_ resource.ResourceWithConfigure      = &omniAccessPolicyResource{}
+	_ resource.ResourceWithImportState    = &omniAccessPolicyResource{}
+	_ resource.ResourceWithValidateConfig = &omniAccessPolicyResource{}
+)
+
+type omniAccessPolicyResource struct {
+	providerData *omniProviderData
+}
+
+type OmniAccessPolicyUserModel struct {
+	Name           types.String `tfsdk:"name"`
+	Match          types.String `tfsdk:"match"`
+	LabelSelectors []string     `tfsdk:"label_selectors"`
+}
+
+type OmniAccessPolicyUserGroupModel struct {
+	Users []OmniAccessPolicyUserModel `tfsdk:"users"`
+}
+
+type OmniAccessPolicyClusterModel struct {
+	Name  types.String `tfsdk:"name"`
+	Match types.String `tfsdk:"match"`
+}
+
+type OmniAccessPolicyClusterGroupModel struct {
+	Clusters []OmniAccessPolicyClusterModel `tfsdk:"clusters"`
+}
+
+type OmniAccessPolicyRuleModel struct {
+	Users                       []string     `tfsdk:"users"`
+	Clusters                    []string     `tfsdk:"clusters"`
+	Role                        types.String `tfsdk:"role"`
+	KubernetesImpersonateGroups []string     `tfsdk:"kubernetes_impersonate_groups"`
+}
+
+type OmniAccessPolicyTestModel struct {
+	Name                                types.String      `tfsdk:"name"`
+	User                                types.String      `tfsdk:"user"`
+	UserLabels                          map[string]string `tfsdk:"user_labels"`
+	Cluster                             types.String      `tfsdk:"cluster"`
+	ExpectedRole                        types.String      `tfsdk:"expected_role"`
+	ExpectedKubernetesImpersonateGroups []string          `tfsdk:"expected_kubernetes_impersonate_groups"`
+}
+
+type OmniAccessPolicyModelV0 struct {
+	ID            types.String                                 `tfsdk:"id"`
+	CreatedAt     types.String                                 `tfsdk:"created_at"`
+	LastUpdated   types.String                                 `tfsdk:"last_updated"`
+	UserGroups    map[string]OmniAccessPolicyUserGroupModel    `tfsdk:"user_groups"`
+	ClusterGroups map[string]OmniAccessPolicyClusterGroupModel `tfsdk:"cluster_groups"`
+	Rules         []OmniAccessPolicyRuleModel                  `tfsdk:"rules"`
+	Tests         []OmniAccessPolicyTestModel                  `tfsdk:"tests"`
+	OmniInstance  types.String                                 `tfsdk:"omni_instance"`
+}
+
+func NewOmniAccessPolicyResource() resource.Resource {
+	return &omniAccessPolicyResource{}
+}
+
+func (r *omniAccessPolicyResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_access_policy"
+}
+
+func (r *omniAccessPolicyResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni access policy resource")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.providerData = providerData
+}
+
+func (r *omniAccessPolicyResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	roleValidators := []validator.String{
+		stringvalidator.OneOf(userRoles...),
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "The access policy (ACL) of Omni, which grants users access to clusters on top of their role. " +
+			"Omni holds a single access policy, so only one instance of the resource may exist. " +
+			"The tests of the policy run when the configuration is validated, so a failing test fails the plan. " +
+			"The provider evaluates the tests with its own implementation of the Omni policy evaluation, which may differ from Omni in edge cases. " +
+			"Omni evaluates the tests again when the policy is saved and rejects a policy with failing tests, so Omni's result is authoritative.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
+			"id": schema.StringAttribute{
+				Description: "ID of the access policy.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"last_updated": schema.StringAttribute{
+				Computed: true,
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"user_groups": schema.MapNestedAttribute{
+				Optional:    true,
+				Description: "Named groups of users, referenced in rules as `group/<name>`.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"users": schema.ListNestedAttribute{
+							Required:    true,
+							Description: "Users of the group, each entry sets exactly one of `name`, `match` or `label_selectors`.",
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"name": schema.StringAttribute{
+										Optional:    true,
+										Description: "Identity of the user.",
+									},
+									"match": schema.StringAttribute{
+										Optional:    true,
+										Description: "Glob pattern matching identities, e.g. `*@example.com`.",
+									},
+									"label_selectors": schema.ListAttribute{
+										ElementType: types.StringType,
+										Optional:    true,
+										Description: "Label selectors matching the identity labels, e.g. the SAML attributes. A user matches if any of the selectors matches.",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"cluster_groups": schema.MapNestedAttribute{
+				Optional:    true,
+				Description: "Named groups of clusters, referenced in rules as `group/<name>`.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"clusters": schema.ListNestedAttribute{
+							Required:    true,
+							Description: "Clusters of the group, each entry sets exactly one of `name` or `match`.",
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"name": schema.StringAttribute{
+										Optional:    true,
+										Description: "Name of the cluster.",
+									},
+									"match": schema.StringAttribute{
+										Optional:    true,
+										Description: "Glob pattern matching cluster names, e.g. `dev-*`.",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"rules": schema.ListNestedAttribute{
+				Optional:    true,
+				Description: "Rules granting the matching users access to the matching clusters.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"users": schema.ListAttribute{
+							ElementType: types.StringType,
+							Required:    true,
+							Description: "User groups as `group/<name>` or glob patterns matching identities.",
+						},
+						"clusters": schema.ListAttribute{
+							ElementType: types.StringType,
+							Required:    true,
+							Description: "Cluster groups as `group/<name>` or glob patterns matching cluster names.",
+						},
+						"role": schema.StringAttribute{
+							Optional:    true,
+							Description: "Role granted on the clusters, one of `None`, `Reader`, `Operator` or `Admin`.",
+							Validators:  roleValidators,
+						},
+						"kubernetes_impersonate_groups": schema.ListAttribute{
+							ElementType: types.StringType,
+							Optional:    true,
+							Description: "Kubernetes groups the users impersonate on the clusters.",
+						},
+					},
+				},
+			},
+			"tests": schema.ListNestedAttribute{
+				Optional:    true,
+				Description: "Tests of the policy, checked at plan time and by Omni when the policy is saved.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Required:    true,
+							Description: "Name of the test.",
+						},
+						"user": schema.StringAttribute{
+							Required:    true,
+							Description: "Identity of the user.",
+						},
+						"user_labels": schema.MapAttribute{
+							ElementType: types.StringType,
+							Optional:    true,
+							Description: "Labels of the identity, e.g. the SAML attributes.",
+						},
+						"cluster": schema.StringAttribute{
+							Required:    true,
+							Description: "Name of the cluster.",
+						},
+						"expected_role": schema.StringAttribute{
+							Optional:    true,
+							Description: "Role the user is expected to have on the cluster, `None` if unset.",
+							Validators:  roleValidators,
+						},
+						"expected_kubernetes_impersonate_groups": schema.ListAttribute{
+							ElementType: types.StringType,
+							Optional:    true,
+							Description: "Kubernetes groups the user is expected to impersonate on the cluster.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ValidateConfig checks the policy and runs its tests. Configurations with unknown
+// values are checked once they are known.
+func (r *omniAccessPolicyResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	if !req.Config.Raw.IsFullyKnown() {
+		return
+	}
+
+	var config OmniAccessPolicyModelV0
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	spec := accessPolicyModelToSpec(config)
+
+	if err := validateAccessPolicy(spec); err != nil {
+		resp.Diagnostics.AddError("Invalid access policy", err.Error())
+		return
+	}
+
+	for _, err := range runAccessPolicyTests(spec) {
+		resp.Diagnostics.AddAttributeError(path.Root("tests"), "Access policy test failed", err.Error())
+	}
+}
+
+func (r *omniAccessPolicyResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniAccessPolicyModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	accessPolicy := auth.NewAccessPolicy()
+	accessPolicy.TypedSpec().Value = accessPolicyModelToSpec(plan)
+
+	if err := instance.state.Create(ctx, accessPolicy); err != nil {
+		if cosistate.IsConflictError(err) {
+			resp.Diagnostics.AddError("Access policy already exists", "Omni already holds an access policy, import it to manage it with Terraform.")
+			return
+		}
+
+		resp.Diagnostics.AddError("Error creating access policy", err.Error())
+		return
+	}
+
+	tflog.Debug(ctx, "created access policy")
+
+	plan.ID = types.StringValue(accessPolicy.Metadata().ID())
+
+	timeNow := types.StringValue(time.Now().Format(time.RFC850))
+	plan.CreatedAt = timeNow
+	plan.LastUpdated = timeNow
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniAccessPolicyResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniAccessPolicyModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	accessPolicy, err := safe.StateGetByID[*auth.AccessPolicy](ctx, instance.state, auth.AccessPolicyID)
+	if err != nil {
+		if cosistate.IsNotFoundError(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.AddError("Error reading access policy", err.Error())
+		return
+	}
+
+	accessPolicySpecToModel(accessPolicy.TypedSpec().Value, &config)
+	config.ID = types.StringValue(accessPolicy.Metadata().ID())
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
+
+func (r *omniAccessPolicyResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniAccessPolicyModelV0
+	var state OmniAccessPolicyModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, err := safe.StateUpdateWithConflicts(ctx, instance.state, auth.NewAccessPolicy().Metadata(), func(accessPolicy *auth.AccessPolicy) error {
+		accessPolicy.TypedSpec().Value = accessPolicyModelToSpec(plan)
+
+		return nil
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Error updating access policy", err.Error())
+		return
+	}
+
+	plan.ID = state.ID
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniAccessPolicyResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := instance.state.TeardownAndDestroy(ctx, auth.NewAccessPolicy().Metadata())
+	if err != nil && !cosistate.IsNotFoundError(err) {
+		resp.Diagnostics.AddError("Error deleting access policy", err.Error())
+		return
+	}
+}
+
+func (r *omniAccessPolicyResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importStateWithInstance(ctx, r.providerData, req, resp)
+}
+
+func accessPolicyModelToSpec(model OmniAccessPolicyModelV0) *specs.AccessPolicySpec {
+	spec := &specs.AccessPolicySpec{}
+
+	if model.UserGroups != nil {
+		spec.UserGroups = make(map[string]*specs.AccessPolicyUserGroup, len(model.UserGroups))
+	}
+
+	for name, group := range model.UserGroups {
+		userGroup := &specs.AccessPolicyUserGroup{}
+
+		for _, user := range group.Users {
+			userGroup.Users = append(userGroup.Users, &specs.AccessPolicyUserGroup_User{
+				Name:           user.Name.ValueString(),
+				Match:          user.Match.ValueString(),
+				LabelSelectors: user.LabelSelectors,
+			})
+		}
+
+		spec.UserGroups[name] = userGroup
+	}
+
+	if model.ClusterGroups != nil {
+		spec.ClusterGroups = make(map[string]*specs.AccessPolicyClusterGroup, len(model.ClusterGroups))
+	}
+
+	for name, group := range model.ClusterGroups {
+		clusterGroup := &specs.AccessPolicyClusterGroup{}
+
+		for _, cluster := range group.Clusters {
+			clusterGroup.Clusters = append(clusterGroup.Clusters, &specs.AccessPolicyClusterGroup_Cluster{
+				Name:  cluster.Name.ValueString(),
+				Match: cluster.Match.ValueString(),
+			})
+		}
+
+		spec.ClusterGroups[name] = clusterGroup
+	}
+
+	for _, rule := range model.Rules {
+		policyRule := &specs.AccessPolicyRule{
+			Users:    rule.Users,
+			Clusters: rule.Clusters,
+			Role:     rule.Role.ValueString(),
+		}
+
+		if rule.KubernetesImpersonateGroups != nil {
+			policyRule.Kubernetes = &specs.AccessPolicyRule_Kubernetes{
+				Impersonate: &specs.AccessPolicyRule_Kubernetes_Impersonate{
+					Groups: rule.KubernetesImpersonateGroups,
+				},
+			}
+		}
+
+		spec.Rules = append(spec.Rules, policyRule)
+	}
+
+	for _, test := range model.Tests {
+		policyTest := &specs.AccessPolicyTest{
+			Name: test.Name.ValueString(),
+			User: &specs.AccessPolicyTest_User{
+				Name:   test.User.ValueString(),
+				Labels: test.UserLabels,
+			},
+			Cluster: &specs.AccessPolicyTest_Cluster{
+				Name: test.Cluster.ValueString(),
+			},
+			Expected: &specs.AccessPolicyTest_Expected{
+				Role: test.ExpectedRole.ValueString(),
+			},
+		}
+
+		if test.ExpectedKubernetesImpersonateGroups != nil {
+			policyTest.Expected.Kubernetes = &specs.AccessPolicyTest_Expected_Kubernetes{
+				Impersonate: &specs.AccessPolicyTest_Expected_Kubernetes_Impersonate{
+					Groups: test.ExpectedKubernetesImpersonateGroups,
+				},
+			}
+		}
+
+		spec.Tests = append(spec.Tests, policyTest)
+	}
+
+	return spec
+}
+
+// accessPolicySpecToModel updates the model from the access policy in Omni, so that
+// changes made outside of Terraform show up as drift. Protobuf doesn't distinguish empty
+// from unset lists and maps, so empty ones are kept empty where the model had them set.
+func accessPolicySpecToModel(spec *specs.AccessPolicySpec, model *OmniAccessPolicyModelV0) {
+	prior := *model
+
+	model.UserGroups = emptyMapAsPrior(map[string]OmniAccessPolicyUserGroupModel{}, prior.UserGroups)
+	for name, group := range spec.GetUserGroups() {
+		priorUsers := prior.UserGroups[name].Users
+		userGroup := OmniAccessPolicyUserGroupModel{Users: []OmniAccessPolicyUserModel{}}
+
+		for i, user := range group.GetUsers() {
+			var priorUser OmniAccessPolicyUserModel
+			if i < len(priorUsers) {
+				priorUser = priorUsers[i]
+			}
+
+			userGroup.Users = append(userGroup.Users, OmniAccessPolicyUserModel{
+				Name:           optionalString(user.GetName()),
+				Match:          optionalString(user.GetMatch()),
+				LabelSelectors: emptyAsPrior(user.GetLabelSelectors(), priorUser.LabelSelectors),
+			})
+		}
+
+		if model.UserGroups == nil {
+			model.UserGroups = make(map[string]OmniAccessPolicyUserGroupModel, len(spec.GetUserGroups()))
+		}
+
+		model.UserGroups[name] = userGroup
+	}
+
+	model.ClusterGroups = emptyMapAsPrior(map[string]OmniAccessPolicyClusterGroupModel{}, prior.ClusterGroups)
+	for name, group := range spec.GetClusterGroups() {
+		clusterGroup := OmniAccessPolicyClusterGroupModel{Clusters: []OmniAccessPolicyClusterModel{}}
+
+		for _, cluster := range group.GetClusters() {
+			clusterGroup.Clusters = append(clusterGroup.Clusters, OmniAccessPolicyClusterModel{
+				Name:  optionalString(cluster.GetName()),
+				Match: optionalString(cluster.GetMatch()),
+			})
+		}
+
+		if model.ClusterGroups == nil {
+			model.ClusterGroups = make(map[string]OmniAccessPolicyClusterGroupModel, len(spec.GetClusterGroups()))
+		}
+
+		model.ClusterGroups[name] = clusterGroup
+	}
+
+	model.Rules = emptyAsPrior([]OmniAccessPolicyRuleModel{}, prior.Rules)
+	for i, rule := range spec.GetRules() {
+		var priorRule OmniAccessPolicyRuleModel
+		if i < len(prior.Rules) {
+			priorRule = prior.Rules[i]
+		}
+
+		model.Rules = append(model.Rules, OmniAccessPolicyRuleModel{
+			Users:                       nonNilStrings(rule.GetUsers()),
+			Clusters:                    nonNilStrings(rule.GetClusters()),
+			Role:                        optionalString(rule.GetRole()),
+			KubernetesImpersonateGroups: emptyAsPrior(rule.GetKubernetes().GetImpersonate().GetGroups(), priorRule.KubernetesImpersonateGroups),
+		})
+	}
+
+	model.Tests = emptyAsPrior([]OmniAccessPolicyTestModel{}, prior.Tests)
+	for i, test := range spec.GetTests() {
+		var priorTest OmniAccessPolicyTestModel
+		if i < len(prior.Tests) {
+			priorTest = prior.Tests[i]
+		}
+
+		model.Tests = append(model.Tests, OmniAccessPolicyTestModel{
+			Name:                                types.StringValue(test.GetName()),
+			User:                                types.StringValue(test.GetUser().GetName()),
+			UserLabels:                          emptyMapAsPrior(test.GetUser().GetLabels(), priorTest.UserLabels),
+			Cluster:                             types.StringValue(test.GetCluster().GetName()),
+			ExpectedRole:                        optionalString(test.GetExpected().GetRole()),
+			ExpectedKubernetesImpersonateGroups: emptyAsPrior(test.GetExpected().GetKubernetes().GetImpersonate().GetGroups(), priorTest.ExpectedKubernetesImpersonateGroups),
+		})
+	}
+}
+
+// emptyAsPrior returns an empty list as null, or as empty if the prior list was set.
+func emptyAsPrior[T any](values []T, prior []T) []T {
+	switch {
+	case len(values) > 0:
+		return values
+	case prior != nil:
+		return []T{}
+	default:
+		return nil
+	}
+}
+
+// emptyMapAsPrior returns an empty map as null, or as empty if the prior map was set.
+func emptyMapAsPrior[K comparable, V any](values map[K]V, prior map[K]V) map[K]V {
+	switch {
+	case len(values) > 0:
+		return values
+	case prior != nil:
+		return map[K]V{}
+	default:
+		return nil
+	}
+}
+
+// optionalString maps the empty value of an optional protobuf field to null.
+func optionalString(value string) types.String {
+	if value == "" {
+		return types.StringNull()
+	}
+
+	return types.StringValue(value)
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+
+	return values
+}
@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestAccessPolicySpecToModel(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		model OmniAccessPolicyModelV0
+	}{
+		{
+			name: "unset",
+			model: OmniAccessPolicyModelV0{
+				UserGroups: map[string]OmniAccessPolicyUserGroupModel{
+					"support": {Users: []OmniAccessPolicyUserModel{{Name: types.StringValue("lead@example.com"), Match: types.StringNull()}}},
+				},
+				Rules: []OmniAccessPolicyRuleModel{
+					{Users: []string{"group/support"}, Clusters: []string{"*"}, Role: types.StringValue(userRoleReader)},
+				},
+				Tests: []OmniAccessPolicyTestModel{
+					{
+						Name:         types.StringValue("lead reads clusters"),
+						User:         types.StringValue("lead@example.com"),
+						Cluster:      types.StringValue("prod"),
+						ExpectedRole: types.StringValue(userRoleReader),
+					},
+				},
+			},
+		},
+		{
+			name: "empty",
+			model: OmniAccessPolicyModelV0{
+				UserGroups: map[string]OmniAccessPolicyUserGroupModel{
+					"support": {Users: []OmniAccessPolicyUserModel{{Name: types.StringNull(), Match: types.StringNull(), LabelSelectors: []string{}}}},
+					"empty":   {Users: []OmniAccessPolicyUserModel{}},
+				},
+				ClusterGroups: map[string]OmniAccessPolicyClusterGroupModel{},
+				Rules: []OmniAccessPolicyRuleModel{
+					{Users: []string{}, Clusters: []string{}, Role: types.StringNull(), KubernetesImpersonateGroups: []string{}},
+				},
+				Tests: []OmniAccessPolicyTestModel{
+					{
+						Name:                                types.StringValue("nobody has access"),
+						User:                                types.StringValue("lead@example.com"),
+						UserLabels:                          map[string]string{},
+						Cluster:                             types.StringValue("prod"),
+						ExpectedRole:                        types.StringNull(),
+						ExpectedKubernetesImpersonateGroups: []string{},
+					},
+				},
+			},
+		},
+		{
+			name: "no rules",
+			model: OmniAccessPolicyModelV0{
+				Rules: []OmniAccessPolicyRuleModel{},
+				Tests: []OmniAccessPolicyTestModel{},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			model := tc.model
+
+			accessPolicySpecToModel(accessPolicyModelToSpec(tc.model), &model)
+
+			if !reflect.DeepEqual(model, tc.model) {
+				t.Errorf("expected %+v, got %+v", tc.model, model)
+			}
+		})
+	}
+}
+
+func TestAccessPolicySpecToModelImport(t *testing.T) {
+	var model OmniAccessPolicyModelV0
+
+	accessPolicySpecToModel(testAccessPolicy(), &model)
+
+	if model.ClusterGroups["dev"].Clusters[1].Match.ValueString() != "dev-*" || !model.ClusterGroups["dev"].Clusters[1].Name.IsNull() {
+		t.Errorf("unexpected cluster group %+v", model.ClusterGroups["dev"])
+	}
+
+	if model.Tests != nil || model.UserGroups["support"].Users[0].LabelSelectors != nil {
+		t.Errorf("expected unset lists to be null, got %+v", model)
+	}
+
+	if !reflect.DeepEqual(accessPolicyModelToSpec(model).GetRules()[0].GetKubernetes().GetImpersonate().GetGroups(), []string{"system:masters"}) {
+		t.Errorf("unexpected rules %+v", model.Rules)
+	}
+}
@@ -0,0 +1,237 @@
+package provider
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/siderolabs/omni/client/api/omni/specs"
+)
+
+func testAccessPolicy() *specs.AccessPolicySpec {
+	return &specs.AccessPolicySpec{
+		UserGroups: map[string]*specs.AccessPolicyUserGroup{
+			"support": {
+				Users: []*specs.AccessPolicyUserGroup_User{
+					{Name: "lead@example.com"},
+					{Match: "*@support.example.com"},
+					{LabelSelectors: []string{"saml.omni.sidero.dev/groups=support"}},
+				},
+			},
+		},
+		ClusterGroups: map[string]*specs.AccessPolicyClusterGroup{
+			"dev": {
+				Clusters: []*specs.AccessPolicyClusterGroup_Cluster{
+					{Name: "staging"},
+					{Match: "dev-*"},
+				},
+			},
+		},
+		Rules: []*specs.AccessPolicyRule{
+			{
+				Users:    []string{"group/support"},
+				Clusters: []string{"group/dev"},
+				Role:     userRoleOperator,
+				Kubernetes: &specs.AccessPolicyRule_Kubernetes{
+					Impersonate: &specs.AccessPolicyRule_Kubernetes_Impersonate{Groups: []string{"system:masters"}},
+				},
+			},
+			{
+				Users:    []string{"*@example.com"},
+				Clusters: []string{"*"},
+				Role:     userRoleReader,
+				Kubernetes: &specs.AccessPolicyRule_Kubernetes{
+					Impersonate: &specs.AccessPolicyRule_Kubernetes_Impersonate{Groups: []string{"view"}},
+				},
+			},
+		},
+	}
+}
+
+func TestCheckAccessPolicy(t *testing.T) {
+	spec := testAccessPolicy()
+
+	for _, tc := range []struct {
+		name           string
+		identity       string
+		labels         map[string]string
+		cluster        string
+		expectedRole   string
+		expectedGroups []string
+	}{
+		{name: "group member by name", identity: "lead@example.com", cluster: "staging", expectedRole: userRoleOperator, expectedGroups: []string{"system:masters", "view"}},
+		{name: "group member by pattern", identity: "a@support.example.com", cluster: "dev-1", expectedRole: userRoleOperator, expectedGroups: []string{"system:masters"}},
+		{name: "group member by label", identity: "b@other.com", labels: map[string]string{"saml.omni.sidero.dev/groups": "support"}, cluster: "dev-2", expectedRole: userRoleOperator, expectedGroups: []string{"system:masters"}},
+		{name: "cluster outside of group", identity: "lead@example.com", cluster: "prod", expectedRole: userRoleReader, expectedGroups: []string{"view"}},
+		{name: "no matching rule", identity: "b@other.com", cluster: "prod", expectedRole: userRoleNone},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := checkAccessPolicy(spec, tc.identity, tc.labels, tc.cluster)
+
+			if result.role != tc.expectedRole {
+				t.Errorf("expected role %q, got %q", tc.expectedRole, result.role)
+			}
+
+			if !slices.Equal(result.impersonateGroups, tc.expectedGroups) {
+				t.Errorf("expected groups %v, got %v", tc.expectedGroups, result.impersonateGroups)
+			}
+		})
+	}
+}
+
+func TestRunAccessPolicyTests(t *testing.T) {
+	spec := testAccessPolicy()
+	spec.Tests = []*specs.AccessPolicyTest{
+		{
+			Name:    "support operates dev clusters",
+			User:    &specs.AccessPolicyTest_User{Name: "a@support.example.com"},
+			Cluster: &specs.AccessPolicyTest_Cluster{Name: "dev-1"},
+			Expected: &specs.AccessPolicyTest_Expected{
+				Role: userRoleOperator,
+				Kubernetes: &specs.AccessPolicyTest_Expected_Kubernetes{
+					Impersonate: &specs.AccessPolicyTest_Expected_Kubernetes_Impersonate{Groups: []string{"system:masters"}},
+				},
+			},
+		},
+		{
+			Name:     "outsiders have no access",
+			User:     &specs.AccessPolicyTest_User{Name: "b@other.com"},
+			Cluster:  &specs.AccessPolicyTest_Cluster{Name: "prod"},
+			Expected: &specs.AccessPolicyTest_Expected{},
+		},
+	}
+
+	if errs := runAccessPolicyTests(spec); len(errs) != 0 {
+		t.Fatalf("expected the tests to pass, got %v", errs)
+	}
+
+	spec.Tests[1].Expected.Role = userRoleReader
+
+	errs := runAccessPolicyTests(spec)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "outsiders have no access") {
+		t.Fatalf("expected the second test to fail, got %v", errs)
+	}
+}
+
+func TestValidateAccessPolicy(t *testing.T) {
+	if err := validateAccessPolicy(testAccessPolicy()); err != nil {
+		t.Fatalf("expected a valid policy, got %s", err)
+	}
+
+	spec := testAccessPolicy()
+	spec.UserGroups["support"].Users = append(spec.UserGroups["support"].Users, &specs.AccessPolicyUserGroup_User{Name: "a", Match: "b"})
+	spec.ClusterGroups["dev"].Clusters = append(spec.ClusterGroups["dev"].Clusters, &specs.AccessPolicyClusterGroup_Cluster{Match: "["})
+	spec.Rules = append(spec.Rules, &specs.AccessPolicyRule{Users: []string{"group/missing"}, Clusters: []string{"group/dev"}, Role: "Owner"})
+
+	err := validateAccessPolicy(spec)
+	if err == nil {
+		t.Fatal("expected an invalid policy")
+	}
+
+	for _, expected := range []string{
+		`user 3 of user group "support" must set exactly one of`,
+		`cluster 2 of cluster group "dev" has an invalid match pattern`,
+		`rule 2 has an unknown role "Owner"`,
+		`rule 2 references unknown user group "missing"`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in %q", expected, err)
+		}
+	}
+}
+
+// TestAccessPolicyDocumentationExample pins the evaluation to the results the Omni
+// access policy documentation gives for its example policy.
+func TestAccessPolicyDocumentationExample(t *testing.T) {
+	users := func(names ...string) *specs.AccessPolicyUserGroup {
+		group := &specs.AccessPolicyUserGroup{}
+		for _, name := range names {
+			group.Users = append(group.Users, &specs.AccessPolicyUserGroup_User{Name: name})
+		}
+
+		return group
+	}
+
+	clusters := func(names ...string) *specs.AccessPolicyClusterGroup {
+		group := &specs.AccessPolicyClusterGroup{}
+		for _, name := range names {
+			group.Clusters = append(group.Clusters, &specs.AccessPolicyClusterGroup_Cluster{Name: name})
+		}
+
+		return group
+	}
+
+	impersonate := func(groups ...string) *specs.AccessPolicyRule_Kubernetes {
+		return &specs.AccessPolicyRule_Kubernetes{Impersonate: &specs.AccessPolicyRule_Kubernetes_Impersonate{Groups: groups}}
+	}
+
+	expected := func(role string, groups ...string) *specs.AccessPolicyTest_Expected {
+		result := &specs.AccessPolicyTest_Expected{Role: role}
+		if groups != nil {
+			result.Kubernetes = &specs.AccessPolicyTest_Expected_Kubernetes{
+				Impersonate: &specs.AccessPolicyTest_Expected_Kubernetes_Impersonate{Groups: groups},
+			}
+		}
+
+		return result
+	}
+
+	spec := &specs.AccessPolicySpec{
+		UserGroups: map[string]*specs.AccessPolicyUserGroup{
+			"support": users("a@example.com", "b@example.com"),
+			"qa":      users("c@example.com", "d@example.com"),
+			"admins":  users("e@example.com"),
+		},
+		ClusterGroups: map[string]*specs.AccessPolicyClusterGroup{
+			"dev":        clusters("dev-1", "dev-2"),
+			"staging":    clusters("staging-1", "staging-2"),
+			"production": clusters("prod-1", "prod-2"),
+		},
+		Rules: []*specs.AccessPolicyRule{
+			{Users: []string{"group/support"}, Clusters: []string{"group/dev"}, Role: userRoleOperator, Kubernetes: impersonate("system:masters")},
+			{Users: []string{"group/qa"}, Clusters: []string{"group/dev", "group/staging"}, Kubernetes: impersonate("my-app-read-only")},
+			{Users: []string{"group/admins"}, Clusters: []string{"group/production"}, Role: userRoleAdmin, Kubernetes: impersonate("system:masters")},
+			{Users: []string{"vault-admin@example.com"}, Clusters: []string{"group/dev"}, Role: userRoleAdmin},
+		},
+		Tests: []*specs.AccessPolicyTest{
+			{
+				Name:     "support engineer has full access to dev cluster",
+				User:     &specs.AccessPolicyTest_User{Name: "a@example.com"},
+				Cluster:  &specs.AccessPolicyTest_Cluster{Name: "dev-1"},
+				Expected: expected(userRoleOperator, "system:masters"),
+			},
+			{
+				Name:     "support engineer has no access to staging cluster",
+				User:     &specs.AccessPolicyTest_User{Name: "b@example.com"},
+				Cluster:  &specs.AccessPolicyTest_Cluster{Name: "staging-1"},
+				Expected: expected(""),
+			},
+			{
+				Name:     "qa engineer has read-only access to staging cluster",
+				User:     &specs.AccessPolicyTest_User{Name: "c@example.com"},
+				Cluster:  &specs.AccessPolicyTest_Cluster{Name: "staging-2"},
+				Expected: expected("", "my-app-read-only"),
+			},
+			{
+				Name:     "admin has full access to production cluster",
+				User:     &specs.AccessPolicyTest_User{Name: "e@example.com"},
+				Cluster:  &specs.AccessPolicyTest_Cluster{Name: "prod-1"},
+				Expected: expected(userRoleAdmin, "system:masters"),
+			},
+			{
+				Name:     "vault-admin has admin role on dev cluster",
+				User:     &specs.AccessPolicyTest_User{Name: "vault-admin@example.com"},
+				Cluster:  &specs.AccessPolicyTest_Cluster{Name: "dev-2"},
+				Expected: expected(userRoleAdmin),
+			},
+		},
+	}
+
+	if err := validateAccessPolicy(spec); err != nil {
+		t.Fatalf("expected a valid policy, got %s", err)
+	}
+
+	for _, err := range runAccessPolicyTests(spec) {
+		t.Error(err)
+	}
+}
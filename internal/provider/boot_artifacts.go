@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/siderolabs/talos/pkg/machinery/config/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/container"
+	"github.com/siderolabs/talos/pkg/machinery/config/encoder"
+	"github.com/siderolabs/talos/pkg/machinery/config/types/meta"
+	"github.com/siderolabs/talos/pkg/machinery/config/types/runtime"
+	"github.com/siderolabs/talos/pkg/machinery/config/types/siderolink"
+	"github.com/siderolabs/talos/pkg/machinery/constants"
+)
+
+// omniKmsgLogName matches the name Omni itself uses for the KmsgLogConfig document.
+const omniKmsgLogName = "omni-kmsg"
+
+var ipxeScriptTemplate = template.Must(template.New("ipxe").Parse(`#!ipxe
+imgfree
+kernel {{ .KernelURL }} {{ .KernelCmdline }}
+initrd {{ .InitramfsURL }}
+boot
+`))
+
+// renderKernelCmdline joins the join config kernel args and any extra args into a
+// single kernel command line.
+func renderKernelCmdline(kernelArgs []string, extraKernelArgs []string) string {
+	args := make([]string, 0, len(extraKernelArgs)+len(kernelArgs))
+	args = append(args, extraKernelArgs...)
+	args = append(args, kernelArgs...)
+
+	return strings.Join(args, " ")
+}
+
+// renderIPXEScript renders an iPXE script booting the given kernel and initramfs with
+// the provided kernel command line.
+func renderIPXEScript(kernelURL string, initramfsURL string, kernelCmdline string) (string, error) {
+	if kernelURL == "" || initramfsURL == "" {
+		return "", fmt.Errorf("both kernel and initramfs URLs are required to render an iPXE script")
+	}
+
+	var buf bytes.Buffer
+	err := ipxeScriptTemplate.Execute(&buf, struct {
+		KernelURL     string
+		InitramfsURL  string
+		KernelCmdline string
+	}{
+		KernelURL:     kernelURL,
+		InitramfsURL:  initramfsURL,
+		KernelCmdline: kernelCmdline,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// renderJoinUserData renders the SideroLinkConfig, EventSinkConfig and KmsgLogConfig
+// documents described by the join config kernel args as a multi-document Talos config.
+func renderJoinUserData(kernelArgs []string) (string, error) {
+	params := map[string]string{}
+	for _, arg := range kernelArgs {
+		key, value, found := strings.Cut(arg, "=")
+		if !found {
+			continue
+		}
+
+		params[key] = value
+	}
+
+	var docs []config.Document
+
+	if apiURL, ok := params[constants.KernelParamSideroLink]; ok {
+		parsed, err := url.Parse(apiURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", constants.KernelParamSideroLink, err)
+		}
+
+		siderolinkConfig := siderolink.NewConfigV1Alpha1()
+		siderolinkConfig.APIUrlConfig.URL = parsed
+
+		docs = append(docs, siderolinkConfig)
+	}
+
+	if endpoint, ok := params[constants.KernelParamEventsSink]; ok {
+		eventSinkConfig := runtime.NewEventSinkV1Alpha1()
+		eventSinkConfig.Endpoint = endpoint
+
+		docs = append(docs, eventSinkConfig)
+	}
+
+	if logURL, ok := params[constants.KernelParamLoggingKernel]; ok {
+		parsed, err := url.Parse(logURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", constants.KernelParamLoggingKernel, err)
+		}
+
+		kmsgLogConfig := runtime.NewKmsgLogV1Alpha1()
+		kmsgLogConfig.MetaName = omniKmsgLogName
+		kmsgLogConfig.KmsgLogURL = meta.URL{URL: parsed}
+
+		docs = append(docs, kmsgLogConfig)
+	}
+
+	if len(docs) == 0 {
+		return "", fmt.Errorf("join config kernel args do not contain any of %s, %s or %s",
+			constants.KernelParamSideroLink, constants.KernelParamEventsSink, constants.KernelParamLoggingKernel)
+	}
+
+	configContainer, err := container.New(docs...)
+	if err != nil {
+		return "", fmt.Errorf("failed to create config container: %w", err)
+	}
+
+	out, err := configContainer.EncodeBytes(encoder.WithComments(encoder.CommentsDisabled))
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+func encodeBase64(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+var bootArtifactsTestKernelArgs = []string{
+	"siderolink.api=https://omni.example.com:8090?jointoken=w7uVuW3zbVKIYQuzEcyetAHeYMeo5q2L9RvkAVfCfSCD",
+	"talos.events.sink=[fdae:41e4:649b:9303::1]:8090",
+	"talos.logging.kernel=tcp://[fdae:41e4:649b:9303::1]:8092",
+}
+
+func assertGolden(t *testing.T, name string, actual string) {
+	t.Helper()
+
+	goldenPath := filepath.Join("testdata", "boot_artifacts", name+".golden")
+
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(goldenPath, []byte(actual), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("failed to read golden file %s: %s", goldenPath, err)
+	}
+
+	if string(expected) != actual {
+		t.Errorf("output does not match %s\n--- expected\n%s\n--- actual\n%s", goldenPath, expected, actual)
+	}
+}
+
+func TestRenderKernelCmdline(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		extraArgs []string
+	}{
+		{name: "kernel_cmdline"},
+		{name: "kernel_cmdline_extra_args", extraArgs: []string{"talos.platform=metal", "console=ttyS0"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cmdline := renderKernelCmdline(bootArtifactsTestKernelArgs, tc.extraArgs)
+
+			assertGolden(t, tc.name, cmdline)
+			assertGolden(t, tc.name+"_base64", encodeBase64(cmdline))
+		})
+	}
+}
+
+func TestRenderIPXEScript(t *testing.T) {
+	cmdline := renderKernelCmdline(bootArtifactsTestKernelArgs, []string{"talos.platform=metal"})
+
+	script, err := renderIPXEScript(
+		"https://factory.talos.dev/image/376567988ad370138ad8b2698212367b8edcb69b5fd68c80be1f2ec7d603b4ba/v1.11.2/kernel-amd64",
+		"https://factory.talos.dev/image/376567988ad370138ad8b2698212367b8edcb69b5fd68c80be1f2ec7d603b4ba/v1.11.2/initramfs-amd64.xz",
+		cmdline,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertGolden(t, "ipxe_script", script)
+	assertGolden(t, "ipxe_script_base64", encodeBase64(script))
+
+	if _, err := renderIPXEScript("", "https://example.com/initramfs-amd64.xz", cmdline); err == nil {
+		t.Error("expected an error when the kernel URL is missing")
+	}
+}
+
+func TestRenderJoinUserData(t *testing.T) {
+	userData, err := renderJoinUserData(bootArtifactsTestKernelArgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertGolden(t, "user_data", userData)
+	assertGolden(t, "user_data_base64", encodeBase64(userData))
+
+	if _, err := renderJoinUserData([]string{"console=ttyS0"}); err == nil {
+		t.Error("expected an error when no join kernel args are present")
+	}
+}
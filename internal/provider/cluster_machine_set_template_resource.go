@@ -3,12 +3,16 @@ package provider
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"regexp"
 	"strings"
 	"terraform-provider-omni/internal/models"
 	"terraform-provider-omni/internal/validators"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -18,20 +22,23 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
-	"github.com/siderolabs/omni/client/pkg/client"
 	yamlv3 "gopkg.in/yaml.v3"
 )
 
 var (
-	_ resource.Resource              = &omniClusterMachineSetTemplate{}
-	_ resource.ResourceWithConfigure = &omniClusterMachineSetTemplate{}
+	_ resource.Resource                   = &omniClusterMachineSetTemplate{}
+	_ resource.ResourceWithConfigure      = &omniClusterMachineSetTemplate{}
+	_ resource.ResourceWithValidateConfig = &omniClusterMachineSetTemplate{}
+	_ resource.ResourceWithModifyPlan     = &omniClusterMachineSetTemplate{}
+	_ resource.ResourceWithUpgradeState   = &omniClusterMachineSetTemplate{}
 )
 
 type omniClusterMachineSetTemplate struct {
-	omniClient *client.Client
-	context    context.Context
+	providerData *omniProviderData
+	context      context.Context
 }
 
+// OmniClusterMachineSetTemplateModelV0 is the state of version 0, before the omni_instance attribute was added.
 type OmniClusterMachineSetTemplateModelV0 struct {
 	ID               types.String            `tfsdk:"id"`
 	CreatedAt        types.String            `tfsdk:"created_at"`
@@ -42,10 +49,32 @@ type OmniClusterMachineSetTemplateModelV0 struct {
 	Labels           models.Labels           `tfsdk:"labels"`
 	Annotations      models.Annotations      `tfsdk:"annotations"`
 	Machines         models.MachineIDList    `tfsdk:"machines"`
-	Patches          []models.Patch          `tfsdk:"patches"`
+	Patches          []patchV0               `tfsdk:"patches"`
 	YAML             types.String            `tfsdk:"yaml"`
 }
 
+type OmniClusterMachineSetTemplateModelV1 struct {
+	ID                types.String            `tfsdk:"id"`
+	CreatedAt         types.String            `tfsdk:"created_at"`
+	LastUpdated       types.String            `tfsdk:"last_updated"`
+	Name              types.String            `tfsdk:"name"`
+	Kind              types.String            `tfsdk:"kind"`
+	SystemExtensions  models.SystemExtensions `tfsdk:"system_extensions"`
+	Labels            models.Labels           `tfsdk:"labels"`
+	Annotations       models.Annotations      `tfsdk:"annotations"`
+	Machines          models.MachineIDList    `tfsdk:"machines"`
+	MachineClass      *models.MachineClass    `tfsdk:"machine_class"`
+	Patches           []models.Patch          `tfsdk:"patches"`
+	RestoreFromBackup *models.BootstrapSpec   `tfsdk:"restore_from_backup"`
+	UpdateStrategy    *models.UpdateStrategy  `tfsdk:"update_strategy"`
+	DeleteStrategy    *models.UpdateStrategy  `tfsdk:"delete_strategy"`
+	YAML              types.String            `tfsdk:"yaml"`
+	OmniInstance      types.String            `tfsdk:"omni_instance"`
+}
+
+// machineClassSizeRegexp matches the machine class sizes Omni accepts in cluster templates.
+var machineClassSizeRegexp = regexp.MustCompile(`^([0-9]+|unlimited)$`)
+
 func NewOmniClusterMachineSetTemplateResource() resource.Resource {
 	return &omniClusterMachineSetTemplate{}
 }
@@ -59,25 +88,27 @@ func (r *omniClusterMachineSetTemplate) Configure(_ context.Context, req resourc
 		return
 	}
 
-	omniClient, ok := req.ProviderData.(*client.Client)
+	providerData, ok := req.ProviderData.(*omniProviderData)
 	tflog.Debug(context.Background(), "Configuring omni machine status data source")
 	if !ok {
 		resp.Diagnostics.AddError(
 			"failed to get image factory client",
-			fmt.Sprintf("Expected *client.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
 	r.context = context.Background()
-	r.omniClient = omniClient
+	r.providerData = providerData
 }
 
 func (r *omniClusterMachineSetTemplate) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version:     1,
 		Description: "Omni cluster machine set template definition.",
 		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
 			"id": schema.StringAttribute{
 				Description: "Name (ID) of cluster.",
 				Computed:    true,
@@ -96,7 +127,7 @@ func (r *omniClusterMachineSetTemplate) Schema(_ context.Context, _ resource.Sch
 			},
 			"name": schema.StringAttribute{
 				Optional:    true,
-				Description: "Name (ID) of the machine. Only required on Worker machine sets.",
+				Description: "Name (ID) of the machine set. Required on worker machine sets and not allowed on control plane machine sets.",
 				Validators: []validator.String{
 					validators.RequiredWhenValueIs(path.MatchRoot("kind"), types.StringValue("worker")),
 				},
@@ -120,12 +151,33 @@ func (r *omniClusterMachineSetTemplate) Schema(_ context.Context, _ resource.Sch
 			},
 			"machines": schema.ListAttribute{
 				ElementType: types.StringType,
-				Description: "List of machines belonging to machine set.",
-				Required:    true,
+				Description: "List of machines belonging to machine set. Exactly one of `machines` and `machine_class` has to be set.",
+				Optional:    true,
+			},
+			"machine_class": schema.SingleNestedAttribute{
+				Attributes: map[string]schema.Attribute{
+					"name": schema.StringAttribute{
+						Required:    true,
+						Description: "Name of the machine class to allocate the machines from.",
+					},
+					"size": schema.StringAttribute{
+						Required:    true,
+						Description: "Number of machines to allocate from the machine class, or `unlimited` to allocate all of its machines.",
+						Validators: []validator.String{
+							stringvalidator.RegexMatches(machineClassSizeRegexp, "must be a number of machines or unlimited"),
+						},
+					},
+				},
+				Optional:    true,
+				Description: "Machine class the machines of the machine set are allocated from. Exactly one of `machines` and `machine_class` has to be set.",
 			},
 			"patches": schema.ListNestedAttribute{
 				NestedObject: schema.NestedAttributeObject{
 					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Optional:    true,
+							Description: "The name of the patch. Required for inline patches unless `id_override` is set.",
+						},
 						"id_override": schema.StringAttribute{
 							Optional:    true,
 							Description: "The name (ID) of the patch.",
@@ -158,6 +210,30 @@ func (r *omniClusterMachineSetTemplate) Schema(_ context.Context, _ resource.Sch
 				Description: "List of system extensions installed to machine.",
 				Optional:    true,
 			},
+			"restore_from_backup": schema.SingleNestedAttribute{
+				Attributes: map[string]schema.Attribute{
+					"cluster_uuid": schema.StringAttribute{
+						Required:    true,
+						Description: "UUID of the cluster the etcd backup was taken from.",
+					},
+					"snapshot": schema.StringAttribute{
+						Required:    true,
+						Description: "Snapshot file name of the etcd backup to restore.",
+					},
+				},
+				Optional:    true,
+				Description: "Bootstraps the cluster from an etcd backup Omni holds. Only valid on control plane machine sets, and only applied when the cluster is created.",
+			},
+			"update_strategy": schema.SingleNestedAttribute{
+				Attributes:  machineSetStrategySchemaAttributes(),
+				Optional:    true,
+				Description: "Strategy used to roll out configuration changes to the machines. Only valid on worker machine sets. Omni updates one machine at a time when not set.",
+			},
+			"delete_strategy": schema.SingleNestedAttribute{
+				Attributes:  machineSetStrategySchemaAttributes(),
+				Optional:    true,
+				Description: "Strategy used to remove machines from the machine set. Only valid on worker machine sets.",
+			},
 			"yaml": schema.StringAttribute{
 				Description: "Rendered YAML cluster machine template.",
 				Computed:    true,
@@ -166,8 +242,269 @@ func (r *omniClusterMachineSetTemplate) Schema(_ context.Context, _ resource.Sch
 	}
 }
 
+// schemaV0 returns the version 0 schema, which decodes the prior state in upgrades.
+func (r *omniClusterMachineSetTemplate) schemaV0() *schema.Schema {
+	return &schema.Schema{
+		Description: "Omni cluster machine set template definition.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "Name (ID) of cluster.",
+				Computed:    true,
+			},
+			"last_updated": schema.StringAttribute{
+				Computed: true,
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+			},
+			"name": schema.StringAttribute{
+				Optional:    true,
+				Description: "Name (ID) of the machine. Only required on Worker machine sets.",
+			},
+			"kind": schema.StringAttribute{
+				Required:    true,
+				Description: "Kind of Omni machine set.",
+			},
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Labels to add to the machine.",
+			},
+			"annotations": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Labels to add to the machine.",
+			},
+			"machines": schema.ListAttribute{
+				ElementType: types.StringType,
+				Description: "List of machines belonging to machine set.",
+				Required:    true,
+			},
+			"patches": patchesAttributeV0(),
+			"system_extensions": schema.ListAttribute{
+				ElementType: types.StringType,
+				Description: "List of system extensions installed to machine.",
+				Optional:    true,
+			},
+			"yaml": schema.StringAttribute{
+				Description: "Rendered YAML cluster machine template.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// UpgradeState upgrades the state written before the omni_instance attribute was added.
+func (r *omniClusterMachineSetTemplate) UpgradeState(context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: stateUpgrader(r.schemaV0(), func(prior OmniClusterMachineSetTemplateModelV0) OmniClusterMachineSetTemplateModelV1 {
+			return OmniClusterMachineSetTemplateModelV1{
+				ID:               prior.ID,
+				CreatedAt:        prior.CreatedAt,
+				LastUpdated:      prior.LastUpdated,
+				Name:             prior.Name,
+				Kind:             prior.Kind,
+				SystemExtensions: prior.SystemExtensions,
+				Labels:           prior.Labels,
+				Annotations:      prior.Annotations,
+				Machines:         prior.Machines,
+				Patches:          upgradePatchesV0(prior.Patches),
+				YAML:             prior.YAML,
+				OmniInstance:     types.StringNull(),
+			}
+		}),
+	}
+}
+
+func machineSetStrategySchemaAttributes() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"type": schema.StringAttribute{
+			Optional:    true,
+			Description: "Type of the strategy. `Rolling` processes the machines in batches of `max_parallelism`, `Unset` processes all machines at once.",
+			Validators: []validator.String{
+				stringvalidator.OneOf(machineSetStrategyRolling, machineSetStrategyUnset),
+			},
+		},
+		"max_parallelism": schema.Int64Attribute{
+			Optional:    true,
+			Description: "Maximum number of machines processed at once by the `Rolling` strategy.",
+			Validators: []validator.Int64{
+				int64validator.Between(1, math.MaxUint32),
+			},
+		},
+	}
+}
+
+// ValidateConfig reads the attributes one by one rather than into the model, as the
+// machine IDs are often unknown during validation.
+func (r *omniClusterMachineSetTemplate) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var (
+		kind              types.String
+		name              types.String
+		machines          types.List
+		machineClass      types.Object
+		restoreFromBackup types.Object
+	)
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("kind"), &kind)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("name"), &name)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("machines"), &machines)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("machine_class"), &machineClass)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("restore_from_backup"), &restoreFromBackup)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	switch {
+	case !machines.IsNull() && !machineClass.IsNull():
+		resp.Diagnostics.AddAttributeError(
+			path.Root("machine_class"),
+			"Conflicting machine selection",
+			"A machine set either lists its machines or allocates them from a machine class, machines and machine_class can not be set together.",
+		)
+	case machines.IsNull() && machineClass.IsNull():
+		resp.Diagnostics.AddAttributeError(
+			path.Root("machines"),
+			"Missing machine selection",
+			"Either machines or machine_class has to be set.",
+		)
+	}
+
+	if kind.IsUnknown() {
+		return
+	}
+
+	controlPlane := kind.ValueString() == "controlplane"
+
+	if !restoreFromBackup.IsNull() && !controlPlane {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("restore_from_backup"),
+			"Invalid restore from backup",
+			"Only control plane machine sets can restore the cluster from an etcd backup.",
+		)
+	}
+
+	for _, attribute := range []string{"update_strategy", "delete_strategy"} {
+		var (
+			strategy       types.Object
+			strategyType   types.String
+			maxParallelism types.Int64
+		)
+
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root(attribute), &strategy)...)
+		if resp.Diagnostics.HasError() || strategy.IsNull() {
+			continue
+		}
+
+		if controlPlane {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attribute),
+				"Invalid machine set strategy",
+				"Omni manages the control plane update and delete strategies itself, they can only be set on worker machine sets.",
+			)
+		}
+
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root(attribute).AtName("type"), &strategyType)...)
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root(attribute).AtName("max_parallelism"), &maxParallelism)...)
+
+		if strategyType.ValueString() == machineSetStrategyUnset && !maxParallelism.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attribute).AtName("max_parallelism"),
+				"Invalid machine set strategy",
+				"max_parallelism only applies to the Rolling strategy.",
+			)
+		}
+	}
+
+	if !controlPlane {
+		return
+	}
+
+	if !name.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("name"),
+			"Invalid control plane name",
+			"Omni names the control plane machine set after the cluster, a custom name is only allowed on worker machine sets.",
+		)
+	}
+
+	var (
+		sizePath   = path.Root("machines")
+		machineSet models.MachineSetYAML
+	)
+
+	switch {
+	case !machineClass.IsNull():
+		var machineClassSize types.String
+
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("machine_class").AtName("size"), &machineClassSize)...)
+		if resp.Diagnostics.HasError() || machineClassSize.IsUnknown() {
+			return
+		}
+
+		sizePath = path.Root("machine_class").AtName("size")
+		machineSet.MachineClass = &models.MachineClassYAML{Size: machineClassSize.ValueString()}
+	case machines.IsNull() || machines.IsUnknown():
+		return
+	default:
+		machineSet.Machines = make([]string, len(machines.Elements()))
+	}
+
+	warning, err := checkControlPlaneTemplate(machineSet)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(sizePath, "Invalid control plane", err.Error())
+	}
+
+	if warning != "" {
+		resp.Diagnostics.AddAttributeWarning(sizePath, "Even number of control plane machines", warning)
+	}
+}
+
+// ModifyPlan checks that Omni holds the etcd backup to restore from, whenever the
+// backup selection changes.
+func (r *omniClusterMachineSetTemplate) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.providerData == nil {
+		return
+	}
+
+	var plan OmniClusterMachineSetTemplateModelV1
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.RestoreFromBackup == nil {
+		return
+	}
+
+	if !req.State.Raw.IsNull() {
+		var state OmniClusterMachineSetTemplateModelV1
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		if state.RestoreFromBackup != nil &&
+			state.RestoreFromBackup.ClusterUUID.Equal(plan.RestoreFromBackup.ClusterUUID) &&
+			state.RestoreFromBackup.Snapshot.Equal(plan.RestoreFromBackup.Snapshot) {
+			return
+		}
+	}
+
+	if plan.RestoreFromBackup.ClusterUUID.IsUnknown() || plan.RestoreFromBackup.Snapshot.IsUnknown() {
+		return
+	}
+
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() || instance == nil {
+		return
+	}
+
+	err := checkEtcdBackupSnapshot(ctx, instance.state, plan.RestoreFromBackup.ClusterUUID.ValueString(), plan.RestoreFromBackup.Snapshot.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("restore_from_backup"), "Etcd backup not found", err.Error())
+	}
+}
+
 func (r *omniClusterMachineSetTemplate) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var plan OmniClusterMachineSetTemplateModelV0
+	var plan OmniClusterMachineSetTemplateModelV1
 
 	diags := req.Plan.Get(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
@@ -178,6 +515,7 @@ func (r *omniClusterMachineSetTemplate) Create(ctx context.Context, req resource
 	finalPlan, err := compileMachineSetTemplate(plan)
 	if err != nil {
 		resp.Diagnostics.AddError("Error encountered compiling machine set template.", fmt.Sprintf("Error: %s", err))
+		return
 	}
 
 	plan = finalPlan
@@ -193,12 +531,12 @@ func (r *omniClusterMachineSetTemplate) Create(ctx context.Context, req resource
 }
 
 func (r *omniClusterMachineSetTemplate) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
-	if r.omniClient == nil {
+	if r.providerData == nil {
 		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
 		return
 	}
 
-	var config OmniClusterMachineSetTemplateModelV0
+	var config OmniClusterMachineSetTemplateModelV1
 	resp.Diagnostics.Append(req.State.Get(ctx, &config)...)
 	if resp.Diagnostics.HasError() {
 		return
@@ -211,8 +549,8 @@ func (r *omniClusterMachineSetTemplate) Read(ctx context.Context, req resource.R
 }
 
 func (r *omniClusterMachineSetTemplate) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var plan OmniClusterMachineSetTemplateModelV0
-	var state OmniClusterMachineSetTemplateModelV0
+	var plan OmniClusterMachineSetTemplateModelV1
+	var state OmniClusterMachineSetTemplateModelV1
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 	if resp.Diagnostics.HasError() {
@@ -227,6 +565,7 @@ func (r *omniClusterMachineSetTemplate) Update(ctx context.Context, req resource
 	finalPlan, err := compileMachineSetTemplate(plan)
 	if err != nil {
 		resp.Diagnostics.AddError("Error encountered compiling machine set template.", fmt.Sprintf("Error: %s", err))
+		return
 	}
 
 	plan = finalPlan
@@ -238,7 +577,7 @@ func (r *omniClusterMachineSetTemplate) Update(ctx context.Context, req resource
 }
 
 func (r *omniClusterMachineSetTemplate) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
-	var state OmniClusterMachineSetTemplateModelV0
+	var state OmniClusterMachineSetTemplateModelV1
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
@@ -261,7 +600,7 @@ func sanitizeMachineIDs(machineIDs []string) ([]string, error) {
 	return machineIDList, nil
 }
 
-func compileMachineSetTemplate(plan OmniClusterMachineSetTemplateModelV0) (OmniClusterMachineSetTemplateModelV0, error) {
+func compileMachineSetTemplate(plan OmniClusterMachineSetTemplateModelV1) (OmniClusterMachineSetTemplateModelV1, error) {
 	var machineSetKind string
 	if plan.Kind.ValueString() == "controlplane" {
 		machineSetKind = KindControlPlane
@@ -272,11 +611,21 @@ func compileMachineSetTemplate(plan OmniClusterMachineSetTemplateModelV0) (OmniC
 		return plan, fmt.Errorf("Could not determine the Kind of the machine set. Machine set kind: %s", plan.Kind.ValueString())
 	}
 
-	sanitizedMachines, err := sanitizeMachineIDs(plan.Machines)
-	if err != nil {
+	if err := validateMachineSetKind(machineSetKind, plan); err != nil {
 		return plan, err
 	}
 
+	var sanitizedMachines []string
+
+	if plan.Machines != nil {
+		var err error
+
+		sanitizedMachines, err = sanitizeMachineIDs(plan.Machines)
+		if err != nil {
+			return plan, err
+		}
+	}
+
 	var buf bytes.Buffer
 	enc := yamlv3.NewEncoder(&buf)
 	enc.Encode(models.MachineSetYAML{
@@ -285,7 +634,11 @@ func compileMachineSetTemplate(plan OmniClusterMachineSetTemplateModelV0) (OmniC
 		Name:             plan.Name.ValueString(),
 		Labels:           plan.Labels,
 		Annotations:      plan.Annotations,
+		BootstrapSpec:    convertBootstrapSpecToYAML(plan.RestoreFromBackup),
 		Machines:         sanitizedMachines,
+		MachineClass:     convertMachineClassToYAML(plan.MachineClass),
+		UpdateStrategy:   convertUpdateStrategyToYAML(plan.UpdateStrategy),
+		DeleteStrategy:   convertUpdateStrategyToYAML(plan.DeleteStrategy),
 		Patches:          convertPatchToYAML(plan.Patches),
 	})
 	yamlOutput := buf.String()
@@ -295,3 +648,60 @@ func compileMachineSetTemplate(plan OmniClusterMachineSetTemplateModelV0) (OmniC
 
 	return plan, nil
 }
+
+// validateMachineSetKind checks the machine set options against the rules Omni applies
+// to control plane and worker machine sets.
+func validateMachineSetKind(machineSetKind string, plan OmniClusterMachineSetTemplateModelV1) error {
+	var errs []error
+
+	switch {
+	case plan.Machines != nil && plan.MachineClass != nil:
+		errs = append(errs, errors.New("machines and machine_class can not be set together"))
+	case plan.Machines == nil && plan.MachineClass == nil:
+		errs = append(errs, errors.New("either machines or machine_class has to be set"))
+	}
+
+	switch machineSetKind {
+	case KindControlPlane:
+		if plan.Name.ValueString() != "" {
+			errs = append(errs, errors.New("name is not allowed on control plane machine sets"))
+		}
+
+		if _, err := checkControlPlaneTemplate(models.MachineSetYAML{
+			Machines:     plan.Machines,
+			MachineClass: convertMachineClassToYAML(plan.MachineClass),
+		}); err != nil {
+			errs = append(errs, err)
+		}
+
+		if plan.UpdateStrategy != nil {
+			errs = append(errs, errors.New("update_strategy is not allowed on control plane machine sets"))
+		}
+
+		if plan.DeleteStrategy != nil {
+			errs = append(errs, errors.New("delete_strategy is not allowed on control plane machine sets"))
+		}
+	case KindWorkers:
+		if plan.Name.ValueString() == "" {
+			errs = append(errs, errors.New("name is required on worker machine sets"))
+		}
+
+		if plan.RestoreFromBackup != nil {
+			errs = append(errs, errors.New("restore_from_backup is not allowed on worker machine sets"))
+		}
+	}
+
+	for _, strategy := range []struct {
+		attribute string
+		value     *models.UpdateStrategy
+	}{
+		{attribute: "update_strategy", value: plan.UpdateStrategy},
+		{attribute: "delete_strategy", value: plan.DeleteStrategy},
+	} {
+		if strategy.value != nil && strategy.value.Type.ValueString() == machineSetStrategyUnset && !strategy.value.MaxParallelism.IsNull() {
+			errs = append(errs, fmt.Errorf("%s: max_parallelism only applies to the Rolling strategy", strategy.attribute))
+		}
+	}
+
+	return errors.Join(errs...)
+}
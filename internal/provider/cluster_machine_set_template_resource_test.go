@@ -2,11 +2,19 @@ package provider
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"terraform-provider-omni/internal/models"
 	"testing"
 
+	tfresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/siderolabs/omni/client/pkg/template"
 )
 
 var machineSetTestcontrolPlaneMachines = []string{
@@ -37,10 +45,40 @@ func TestAccMachineSetTemplateResource(t *testing.T) {
 	})
 }
 
+func TestAccWorkerMachineSetTemplateResource(t *testing.T) {
+	rName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testMachineSetTemplate(rName, "worker", strings.Join(machineSetTestcontrolPlaneMachines, ", ")),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("omni_cluster_machine_set_template.test", "machines.#", "3"),
+					resource.TestCheckResourceAttr("omni_cluster_machine_set_template.test", "patches.#", "1"),
+					resource.TestCheckResourceAttr("omni_cluster_machine_set_template.test", "system_extensions.#", "1"),
+					resource.TestCheckResourceAttrSet("omni_cluster_machine_set_template.test", "id"),
+					resource.TestCheckResourceAttrSet("omni_cluster_machine_set_template.test", "last_updated"),
+					resource.TestCheckResourceAttrSet("omni_cluster_machine_set_template.test", "created_at"),
+				),
+			},
+		},
+	})
+}
+
+// testMachineSetTemplate renders the name only on worker machine sets, as Omni names the
+// control plane machine set after the cluster.
 func testMachineSetTemplate(name string, kind string, machines string) string {
+	if kind == "controlplane" {
+		name = "null"
+	} else {
+		name = strconv.Quote(name)
+	}
+
 	return fmt.Sprintf(`
 resource "omni_cluster_machine_set_template" "test" {
-  name = "%s"
+  name = %s
   kind = "%s"
   machines = [%s]
   patches = [
@@ -69,3 +107,332 @@ resource "omni_cluster_machine_set_template" "test" {
   ]
 }`, name, kind, machines)
 }
+
+func TestCompileMachineSetTemplateRestoreFromBackup(t *testing.T) {
+	plan, err := compileMachineSetTemplate(OmniClusterMachineSetTemplateModelV1{
+		Kind:     types.StringValue("controlplane"),
+		Machines: []string{"00000000-0000-0000-0000-000000000000"},
+		RestoreFromBackup: &models.BootstrapSpec{
+			ClusterUUID: types.StringValue("6e2ab8c5-0b5e-4bc4-9d36-3f0b1b3c7a2d"),
+			Snapshot:    types.StringValue("FFFFFFFF9A99FBFE.snapshot"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clusterYAML := `kind: Cluster
+name: restored
+kubernetes:
+  version: v1.33.4
+talos:
+  version: v1.11.2
+---
+`
+
+	tmpl, err := template.Load(strings.NewReader(clusterYAML + plan.YAML.ValueString()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tmpl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	resources, err := tmpl.Translate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, res := range resources {
+		machineSet, ok := res.(*omni.MachineSet)
+		if !ok || machineSet.Metadata().ID() != omni.ControlPlanesResourceID("restored") {
+			continue
+		}
+
+		bootstrapSpec := machineSet.TypedSpec().Value.BootstrapSpec
+		if bootstrapSpec.GetClusterUuid() != "6e2ab8c5-0b5e-4bc4-9d36-3f0b1b3c7a2d" || bootstrapSpec.GetSnapshot() != "FFFFFFFF9A99FBFE.snapshot" {
+			t.Errorf("unexpected bootstrap spec %v", bootstrapSpec)
+		}
+
+		return
+	}
+
+	t.Fatal("control plane machine set not found in the translated template")
+}
+
+func TestCompileMachineSetTemplateStrategies(t *testing.T) {
+	plan, err := compileMachineSetTemplate(OmniClusterMachineSetTemplateModelV1{
+		Name:     types.StringValue("workers"),
+		Kind:     types.StringValue("worker"),
+		Machines: []string{"00000000-0000-0000-0000-000000000000"},
+		UpdateStrategy: &models.UpdateStrategy{
+			Type:           types.StringValue("Rolling"),
+			MaxParallelism: types.Int64Value(3),
+		},
+		DeleteStrategy: &models.UpdateStrategy{
+			Type:           types.StringValue("Unset"),
+			MaxParallelism: types.Int64Null(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clusterYAML := `kind: Cluster
+name: strategies
+kubernetes:
+  version: v1.33.4
+talos:
+  version: v1.11.2
+---
+kind: ControlPlane
+machines:
+  - 11111111-1111-1111-1111-111111111111
+---
+`
+
+	tmpl, err := template.Load(strings.NewReader(clusterYAML + plan.YAML.ValueString()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tmpl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	resources, err := tmpl.Translate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, res := range resources {
+		machineSet, ok := res.(*omni.MachineSet)
+		if !ok || machineSet.Metadata().ID() != omni.AdditionalWorkersResourceID("strategies", "workers") {
+			continue
+		}
+
+		spec := machineSet.TypedSpec().Value
+
+		if spec.UpdateStrategy != specs.MachineSetSpec_Rolling || spec.GetUpdateStrategyConfig().GetRolling().GetMaxParallelism() != 3 {
+			t.Errorf("unexpected update strategy %v %v", spec.UpdateStrategy, spec.UpdateStrategyConfig)
+		}
+
+		if spec.DeleteStrategy != specs.MachineSetSpec_Unset || spec.DeleteStrategyConfig != nil {
+			t.Errorf("unexpected delete strategy %v %v", spec.DeleteStrategy, spec.DeleteStrategyConfig)
+		}
+
+		return
+	}
+
+	t.Fatal("worker machine set not found in the translated template")
+}
+
+func TestCompileMachineSetTemplateMachineClass(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		size           string
+		machineCount   uint32
+		allocationType specs.MachineSetSpec_MachineAllocation_Type
+	}{
+		{name: "static", size: "3", machineCount: 3, allocationType: specs.MachineSetSpec_MachineAllocation_Static},
+		{name: "unlimited", size: "unlimited", allocationType: specs.MachineSetSpec_MachineAllocation_Unlimited},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			plan, err := compileMachineSetTemplate(OmniClusterMachineSetTemplateModelV1{
+				Name: types.StringValue("workers"),
+				Kind: types.StringValue("worker"),
+				MachineClass: &models.MachineClass{
+					Name: types.StringValue("bare-metal"),
+					Size: types.StringValue(tc.size),
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			clusterYAML := `kind: Cluster
+name: machine-class
+kubernetes:
+  version: v1.33.4
+talos:
+  version: v1.11.2
+---
+kind: ControlPlane
+machines:
+  - 11111111-1111-1111-1111-111111111111
+---
+`
+
+			tmpl, err := template.Load(strings.NewReader(clusterYAML + plan.YAML.ValueString()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err = tmpl.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			resources, err := tmpl.Translate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, res := range resources {
+				machineSet, ok := res.(*omni.MachineSet)
+				if !ok || machineSet.Metadata().ID() != omni.AdditionalWorkersResourceID("machine-class", "workers") {
+					continue
+				}
+
+				allocation := machineSet.TypedSpec().Value.MachineAllocation
+				if allocation.GetName() != "bare-metal" || allocation.GetMachineCount() != tc.machineCount || allocation.GetAllocationType() != tc.allocationType {
+					t.Errorf("unexpected machine allocation %v", allocation)
+				}
+
+				return
+			}
+
+			t.Fatal("worker machine set not found in the translated template")
+		})
+	}
+}
+
+func TestMachineSetTemplateValidateConfig(t *testing.T) {
+	machines := tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{
+		tftypes.NewValue(tftypes.String, "00000000-0000-0000-0000-000000000000"),
+	})
+
+	machineClass := func(size string) tftypes.Value {
+		return tftypes.NewValue(tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+			"name": tftypes.String,
+			"size": tftypes.String,
+		}}, map[string]tftypes.Value{
+			"name": tftypes.NewValue(tftypes.String, "bare-metal"),
+			"size": tftypes.NewValue(tftypes.String, size),
+		})
+	}
+
+	for _, tc := range []struct {
+		name    string
+		kind    string
+		values  map[string]tftypes.Value
+		summary string
+		warning bool
+	}{
+		{name: "machines", kind: "controlplane", values: map[string]tftypes.Value{"machines": machines}},
+		{name: "machine class", kind: "controlplane", values: map[string]tftypes.Value{"machine_class": machineClass("3")}},
+		{name: "unlimited machine class", kind: "controlplane", values: map[string]tftypes.Value{"machine_class": machineClass("unlimited")}},
+		{name: "even machine class", kind: "controlplane", values: map[string]tftypes.Value{"machine_class": machineClass("2")}, warning: true},
+		{name: "empty machine class", kind: "controlplane", values: map[string]tftypes.Value{"machine_class": machineClass("0")}, summary: "Invalid control plane"},
+		{name: "empty worker machine class", kind: "worker", values: map[string]tftypes.Value{"machine_class": machineClass("0")}},
+		{
+			name:    "machines and machine class",
+			kind:    "worker",
+			values:  map[string]tftypes.Value{"machines": machines, "machine_class": machineClass("1")},
+			summary: "Conflicting machine selection",
+		},
+		{name: "no machines", kind: "worker", values: map[string]tftypes.Value{}, summary: "Missing machine selection"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &omniClusterMachineSetTemplate{}
+
+			tc.values["kind"] = tftypes.NewValue(tftypes.String, tc.kind)
+			if tc.kind == "worker" {
+				tc.values["name"] = tftypes.NewValue(tftypes.String, "workers")
+			}
+
+			var resp tfresource.ValidateConfigResponse
+
+			r.ValidateConfig(t.Context(), tfresource.ValidateConfigRequest{Config: testResourceConfig(t, r, tc.values)}, &resp)
+
+			switch {
+			case tc.summary == "" && resp.Diagnostics.HasError():
+				t.Errorf("unexpected error: %v", resp.Diagnostics)
+			case tc.summary != "" && (!resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != tc.summary):
+				t.Errorf("expected %q, got %v", tc.summary, resp.Diagnostics)
+			}
+
+			if warning := resp.Diagnostics.WarningsCount() > 0; warning != tc.warning {
+				t.Errorf("expected warning %v, got %v", tc.warning, resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestCompileMachineSetTemplateInvalid(t *testing.T) {
+	for name, plan := range map[string]OmniClusterMachineSetTemplateModelV1{
+		"named control plane": {
+			Name:     types.StringValue("cp"),
+			Kind:     types.StringValue("controlplane"),
+			Machines: []string{"00000000-0000-0000-0000-000000000000"},
+		},
+		"empty control plane": {
+			Kind:     types.StringValue("controlplane"),
+			Machines: []string{},
+		},
+		"unnamed workers": {
+			Kind:     types.StringValue("worker"),
+			Machines: []string{"00000000-0000-0000-0000-000000000000"},
+		},
+		"control plane update strategy": {
+			Kind:     types.StringValue("controlplane"),
+			Machines: []string{"00000000-0000-0000-0000-000000000000"},
+			UpdateStrategy: &models.UpdateStrategy{
+				Type:           types.StringValue("Rolling"),
+				MaxParallelism: types.Int64Value(1),
+			},
+		},
+		"control plane delete strategy": {
+			Kind:     types.StringValue("controlplane"),
+			Machines: []string{"00000000-0000-0000-0000-000000000000"},
+			DeleteStrategy: &models.UpdateStrategy{
+				Type:           types.StringValue("Unset"),
+				MaxParallelism: types.Int64Null(),
+			},
+		},
+		"unset strategy with max parallelism": {
+			Name:     types.StringValue("workers"),
+			Kind:     types.StringValue("worker"),
+			Machines: []string{"00000000-0000-0000-0000-000000000000"},
+			UpdateStrategy: &models.UpdateStrategy{
+				Type:           types.StringValue("Unset"),
+				MaxParallelism: types.Int64Value(2),
+			},
+		},
+		"machines and machine class": {
+			Name:     types.StringValue("workers"),
+			Kind:     types.StringValue("worker"),
+			Machines: []string{"00000000-0000-0000-0000-000000000000"},
+			MachineClass: &models.MachineClass{
+				Name: types.StringValue("bare-metal"),
+				Size: types.StringValue("1"),
+			},
+		},
+		"no machines": {
+			Name: types.StringValue("workers"),
+			Kind: types.StringValue("worker"),
+		},
+		"empty control plane machine class": {
+			Kind: types.StringValue("controlplane"),
+			MachineClass: &models.MachineClass{
+				Name: types.StringValue("bare-metal"),
+				Size: types.StringValue("0"),
+			},
+		},
+		"worker restore from backup": {
+			Name:     types.StringValue("workers"),
+			Kind:     types.StringValue("worker"),
+			Machines: []string{"00000000-0000-0000-0000-000000000000"},
+			RestoreFromBackup: &models.BootstrapSpec{
+				ClusterUUID: types.StringValue("6e2ab8c5-0b5e-4bc4-9d36-3f0b1b3c7a2d"),
+				Snapshot:    types.StringValue("FFFFFFFF9A99FBFE.snapshot"),
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := compileMachineSetTemplate(plan); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
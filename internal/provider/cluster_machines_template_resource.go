@@ -7,38 +7,59 @@ import (
 	"terraform-provider-omni/internal/models"
 	"time"
 
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
-	"github.com/siderolabs/omni/client/pkg/client"
 	"gopkg.in/yaml.v3"
 )
 
 var (
-	_ resource.Resource              = &omniClusterMachinesTemplate{}
-	_ resource.ResourceWithConfigure = &omniClusterMachinesTemplate{}
+	_ resource.Resource                   = &omniClusterMachinesTemplate{}
+	_ resource.ResourceWithConfigure      = &omniClusterMachinesTemplate{}
+	_ resource.ResourceWithValidateConfig = &omniClusterMachinesTemplate{}
+	_ resource.ResourceWithModifyPlan     = &omniClusterMachinesTemplate{}
+	_ resource.ResourceWithUpgradeState   = &omniClusterMachinesTemplate{}
 )
 
 type omniClusterMachinesTemplate struct {
-	omniClient *client.Client
-	context    context.Context
+	providerData *omniProviderData
+	context      context.Context
 }
 
-type ClusterMachinesTemplate struct {
-	Kind             string                  `yaml:"kind"`
-	SystemExtensions models.SystemExtensions `yaml:"systemExtensions,omitempty"`
-	Name             string                  `yaml:"name"`
-	Labels           map[string]string       `yaml:"labels,omitempty"`
-	Annotations      map[string]string       `yaml:"annotations,omitempty"`
-	Locked           bool                    `yaml:"locked,omitempty"`
-	Install          *models.MachineInstall  `yaml:"install,omitempty"`
-	Patches          []models.PatchYAML      `yaml:"patches,omitempty"`
+// OmniClusterMachinesTemplateModelV0 is the state of version 0, before the omni_instance attribute was added.
+type OmniClusterMachinesTemplateModelV0 struct {
+	ID               types.String            `tfsdk:"id"`
+	CreatedAt        types.String            `tfsdk:"created_at"`
+	LastUpdated      types.String            `tfsdk:"last_updated"`
+	Kind             types.String            `tfsdk:"kind"`
+	SystemExtensions models.SystemExtensions `tfsdk:"system_extensions"`
+	Name             types.String            `tfsdk:"name"`
+	Role             types.String            `tfsdk:"role"`
+	Labels           models.Labels           `tfsdk:"labels"`
+	Annotations      models.Annotations      `tfsdk:"annotations"`
+	Locked           types.Bool              `tfsdk:"locked"`
+	Install          *machineInstallV0       `tfsdk:"install"`
+	Patches          []patchV0               `tfsdk:"patches"`
+	YAML             types.String            `tfsdk:"yaml"`
 }
 
-type OmniClusterMachinesTemplateModelV0 struct {
+// machineInstallV0 is the install attribute of the version 0 state, before the disk
+// selector was added.
+type machineInstallV0 struct {
+	Disk types.String `tfsdk:"disk"`
+}
+
+type OmniClusterMachinesTemplateModelV1 struct {
 	ID               types.String            `tfsdk:"id"`
 	CreatedAt        types.String            `tfsdk:"created_at"`
 	LastUpdated      types.String            `tfsdk:"last_updated"`
@@ -50,8 +71,14 @@ type OmniClusterMachinesTemplateModelV0 struct {
 	Annotations      models.Annotations      `tfsdk:"annotations"`
 	Locked           types.Bool              `tfsdk:"locked"`
 	Install          *models.MachineInstall  `tfsdk:"install"`
+	KernelArgs       []string                `tfsdk:"kernel_args"`
 	Patches          []models.Patch          `tfsdk:"patches"`
 	YAML             types.String            `tfsdk:"yaml"`
+
+	SchematicID               types.String `tfsdk:"schematic_id"`
+	InstalledSystemExtensions types.List   `tfsdk:"installed_system_extensions"`
+	InstalledKernelArgs       types.List   `tfsdk:"installed_kernel_args"`
+	OmniInstance              types.String `tfsdk:"omni_instance"`
 }
 
 func NewOmniClusterMachinesTemplateResource() resource.Resource {
@@ -67,24 +94,26 @@ func (r *omniClusterMachinesTemplate) Configure(_ context.Context, req resource.
 		return
 	}
 
-	omniClient, ok := req.ProviderData.(*client.Client)
+	providerData, ok := req.ProviderData.(*omniProviderData)
 	tflog.Debug(context.Background(), "Configuring omni machine template resource")
 	if !ok {
 		resp.Diagnostics.AddError(
 			"failed to get image factory client",
-			fmt.Sprintf("Expected *client.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	r.omniClient = omniClient
+	r.providerData = providerData
 }
 
 func (r *omniClusterMachinesTemplate) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version:     1,
 		Description: "Omni cluster machine set template definition.",
 		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
 			"id": schema.StringAttribute{
 				Description: "Name (ID) of cluster.",
 				Computed:    true,
@@ -131,7 +160,46 @@ func (r *omniClusterMachinesTemplate) Schema(_ context.Context, _ resource.Schem
 				Attributes: map[string]schema.Attribute{
 					"disk": schema.StringAttribute{
 						Optional:    true,
-						Description: "Disk the Talos system is installed to.",
+						Computed:    true,
+						Description: "Disk the Talos system is installed to. Set to the matching disk when `disk_selector` is used.",
+						Validators: []validator.String{
+							stringvalidator.ConflictsWith(path.MatchRelative().AtParent().AtName("disk_selector")),
+						},
+					},
+					"disk_selector": schema.SingleNestedAttribute{
+						Attributes: map[string]schema.Attribute{
+							"min_size": schema.StringAttribute{
+								Optional:    true,
+								Description: "Minimum size of the disk, e.g. `100GB`.",
+							},
+							"max_size": schema.StringAttribute{
+								Optional:    true,
+								Description: "Maximum size of the disk, e.g. `2TB`.",
+							},
+							"model": schema.StringAttribute{
+								Optional:    true,
+								Description: "Model of the disk. Supports glob patterns.",
+							},
+							"serial": schema.StringAttribute{
+								Optional:    true,
+								Description: "Serial number of the disk.",
+							},
+							"type": schema.StringAttribute{
+								Optional:    true,
+								Description: "Type of the disk as reported by the machine, e.g. `ssd`, `hdd` or `nvme`.",
+							},
+							"bus_path": schema.StringAttribute{
+								Optional:    true,
+								Description: "Bus path of the disk. Supports glob patterns.",
+							},
+							"wwid": schema.StringAttribute{
+								Optional:    true,
+								Description: "World Wide Identifier of the disk.",
+							},
+						},
+						Optional: true,
+						Description: "Selects the install disk by its attributes rather than its path. " +
+							"The selector is resolved against the disks the machine reports to Omni when planning, and must match exactly one disk.",
 					},
 				},
 				Optional:    true,
@@ -150,6 +218,10 @@ func (r *omniClusterMachinesTemplate) Schema(_ context.Context, _ resource.Schem
 							Optional:    true,
 							Description: "The annotations of the patch.",
 						},
+						"name": schema.StringAttribute{
+							Optional:    true,
+							Description: "The name of the patch. Required for inline patches unless `id_override` is set.",
+						},
 						"id_override": schema.StringAttribute{
 							Optional:    true,
 							Description: "The name (ID) of the patch.",
@@ -167,6 +239,101 @@ func (r *omniClusterMachinesTemplate) Schema(_ context.Context, _ resource.Schem
 				Optional:    true,
 				Description: "Machine-specific patches.",
 			},
+			"system_extensions": schema.ListAttribute{
+				ElementType: types.StringType,
+				Description: "List of system extensions installed to machine. The extensions the machine runs are reported in `installed_system_extensions`, and the plan warns when they differ.",
+				Optional:    true,
+			},
+			"kernel_args": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Extra kernel arguments the Talos installer adds to the boot entry of the machine, applied as the `" + kernelArgsPatchName + "` machine patch. " +
+					"The kernel args the machine is configured with are reported in `installed_kernel_args`, and the plan warns when they differ. " +
+					"Not supported by machines booting with UKIs, e.g. with secure boot.",
+			},
+			"schematic_id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Image factory schematic Omni configured for the machine. Null until the machine is part of a cluster.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"installed_system_extensions": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "System extensions Omni reports as installed or being installed on the machine. Null until the machine is part of a cluster.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"installed_kernel_args": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "Extra kernel arguments of the machine config Omni applied to the machine. Null until the machine is part of a cluster.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"yaml": schema.StringAttribute{
+				Description: "Rendered YAML cluster machine template.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// schemaV0 returns the version 0 schema, which decodes the prior state in upgrades.
+func (r *omniClusterMachinesTemplate) schemaV0() *schema.Schema {
+	return &schema.Schema{
+		Description: "Omni cluster machine set template definition.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "Name (ID) of cluster.",
+				Computed:    true,
+			},
+			"last_updated": schema.StringAttribute{
+				Computed: true,
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+			},
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "Name (ID) of the machine.",
+			},
+			"kind": schema.StringAttribute{
+				Computed:    true,
+				Description: "Kind of Omni resource.",
+			},
+			"role": schema.StringAttribute{
+				Required:    true,
+				Description: "Role of the machine in the cluster.",
+			},
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Labels to add to the machine.",
+			},
+			"annotations": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Labels to add to the machine.",
+			},
+			"locked": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Controls whether the machine is locked from configuration changes.",
+			},
+			"install": schema.SingleNestedAttribute{
+				Attributes: map[string]schema.Attribute{
+					"disk": schema.StringAttribute{
+						Optional:    true,
+						Description: "Disk the Talos system is installed to.",
+					},
+				},
+				Optional:    true,
+				Description: "Machine installation details.",
+			},
+			"patches": patchesAttributeV0(),
 			"system_extensions": schema.ListAttribute{
 				ElementType: types.StringType,
 				Description: "List of system extensions installed to machine.",
@@ -180,26 +347,258 @@ func (r *omniClusterMachinesTemplate) Schema(_ context.Context, _ resource.Schem
 	}
 }
 
+// UpgradeState upgrades the state written before the omni_instance attribute was added.
+// The attributes read from Omni are filled by the next refresh.
+func (r *omniClusterMachinesTemplate) UpgradeState(context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: stateUpgrader(r.schemaV0(), func(prior OmniClusterMachinesTemplateModelV0) OmniClusterMachinesTemplateModelV1 {
+			var install *models.MachineInstall
+			if prior.Install != nil {
+				install = &models.MachineInstall{Disk: prior.Install.Disk}
+			}
+
+			return OmniClusterMachinesTemplateModelV1{
+				ID:                        prior.ID,
+				CreatedAt:                 prior.CreatedAt,
+				LastUpdated:               prior.LastUpdated,
+				Kind:                      prior.Kind,
+				SystemExtensions:          prior.SystemExtensions,
+				Name:                      prior.Name,
+				Role:                      prior.Role,
+				Labels:                    prior.Labels,
+				Annotations:               prior.Annotations,
+				Locked:                    prior.Locked,
+				Install:                   install,
+				Patches:                   upgradePatchesV0(prior.Patches),
+				YAML:                      prior.YAML,
+				SchematicID:               types.StringNull(),
+				InstalledSystemExtensions: types.ListNull(types.StringType),
+				InstalledKernelArgs:       types.ListNull(types.StringType),
+				OmniInstance:              types.StringNull(),
+			}
+		}),
+	}
+}
+
+func (r *omniClusterMachinesTemplate) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var selector *models.InstallDiskSelector
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("install").AtName("disk_selector"), &selector)...)
+	if resp.Diagnostics.HasError() || selector == nil {
+		return
+	}
+
+	sizes := map[string]uint64{}
+
+	for _, size := range []struct {
+		attribute string
+		value     types.String
+	}{
+		{attribute: "min_size", value: selector.MinSize},
+		{attribute: "max_size", value: selector.MaxSize},
+	} {
+		if size.value.IsNull() || size.value.IsUnknown() {
+			continue
+		}
+
+		bytes, err := parseDiskSize(size.value.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("install").AtName("disk_selector").AtName(size.attribute), "Invalid disk size", err.Error())
+			continue
+		}
+
+		sizes[size.attribute] = bytes
+	}
+
+	minSize, minOK := sizes["min_size"]
+	maxSize, maxOK := sizes["max_size"]
+
+	if minOK && maxOK && minSize > maxSize {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("install").AtName("disk_selector"),
+			"Invalid disk size range",
+			fmt.Sprintf("min_size %s is larger than max_size %s.", selector.MinSize.ValueString(), selector.MaxSize.ValueString()),
+		)
+	}
+}
+
+// ModifyPlan resolves the install disk selector against the disks of the machine.
+// The disk in the state is kept as long as the machine and the selector are unchanged.
+// The schematic, the installed extensions and the installed kernel args are only kept
+// while the machine, its extensions and its kernel args are unchanged. The plan warns
+// when the refreshed extensions or kernel args differ from the declared ones.
+func (r *omniClusterMachinesTemplate) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	if !req.State.Raw.IsNull() {
+		var (
+			plannedName, stateName             types.String
+			plannedExtensions, stateExtensions types.List
+			plannedKernelArgs, stateKernelArgs types.List
+			installedExtensions                types.List
+			installedKernelArgs                types.List
+		)
+
+		resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("name"), &plannedName)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("name"), &stateName)...)
+		resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("system_extensions"), &plannedExtensions)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("system_extensions"), &stateExtensions)...)
+		resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("kernel_args"), &plannedKernelArgs)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("kernel_args"), &stateKernelArgs)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("installed_system_extensions"), &installedExtensions)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("installed_kernel_args"), &installedKernelArgs)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		if plannedName.Equal(stateName) {
+			resp.Diagnostics.Append(driftWarnings(ctx, plannedName.ValueString(), plannedExtensions, installedExtensions, plannedKernelArgs, installedKernelArgs)...)
+		}
+
+		if !plannedName.Equal(stateName) || !plannedExtensions.Equal(stateExtensions) || !plannedKernelArgs.Equal(stateKernelArgs) {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("schematic_id"), types.StringUnknown())...)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("installed_system_extensions"), types.ListUnknown(types.StringType))...)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("installed_kernel_args"), types.ListUnknown(types.StringType))...)
+		}
+	}
+
+	diskPath := path.Root("install").AtName("disk")
+	selectorPath := path.Root("install").AtName("disk_selector")
+
+	var (
+		name     types.String
+		install  types.Object
+		disk     types.String
+		selector types.Object
+	)
+
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("name"), &name)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("install"), &install)...)
+	if resp.Diagnostics.HasError() || install.IsNull() || install.IsUnknown() {
+		return
+	}
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, diskPath, &disk)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, selectorPath, &selector)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if selector.IsNull() {
+		if disk.IsNull() {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, diskPath, types.StringNull())...)
+		}
+
+		return
+	}
+
+	if !req.State.Raw.IsNull() {
+		var (
+			stateName     types.String
+			stateDisk     types.String
+			stateSelector types.Object
+		)
+
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("name"), &stateName)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, diskPath, &stateDisk)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, selectorPath, &stateSelector)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		if stateName.Equal(name) && stateSelector.Equal(selector) && !stateDisk.IsNull() {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, diskPath, stateDisk)...)
+			return
+		}
+	}
+
+	if r.providerData == nil || name.IsUnknown() || selector.IsUnknown() {
+		return
+	}
+
+	for _, value := range selector.Attributes() {
+		if value.IsUnknown() {
+			return
+		}
+	}
+
+	var diskSelector models.InstallDiskSelector
+	resp.Diagnostics.Append(selector.As(ctx, &diskSelector, basetypes.ObjectAsOptions{})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() || instance == nil {
+		return
+	}
+
+	resolved, err := resolveInstallDisk(ctx, instance.state, name.ValueString(), &diskSelector)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(selectorPath, "Install disk not resolved", err.Error())
+		return
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("install disk of machine %s resolved to %s", name.ValueString(), resolved))
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, diskPath, types.StringValue(resolved))...)
+}
+
+// resolvePlannedInstallDisk resolves the install disk selector when the machine was not
+// known while planning.
+func (r *omniClusterMachinesTemplate) resolvePlannedInstallDisk(ctx context.Context, st cosistate.State, plan *OmniClusterMachinesTemplateModelV1) error {
+	if plan.Install == nil || !plan.Install.Disk.IsUnknown() {
+		return nil
+	}
+
+	if plan.Install.DiskSelector == nil {
+		plan.Install.Disk = types.StringNull()
+		return nil
+	}
+
+	disk, err := resolveInstallDisk(ctx, st, plan.Name.ValueString(), plan.Install.DiskSelector)
+	if err != nil {
+		return err
+	}
+
+	plan.Install.Disk = types.StringValue(disk)
+
+	return nil
+}
+
 func (r *omniClusterMachinesTemplate) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var plan OmniClusterMachinesTemplateModelV0
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniClusterMachinesTemplateModelV1
 
-	diags := req.Plan.Get(ctx, &plan)
+	diags = req.Plan.Get(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	if err := r.resolvePlannedInstallDisk(ctx, instance.state, &plan); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("install").AtName("disk_selector"), "Install disk not resolved", err.Error())
+		return
+	}
+
 	var buf bytes.Buffer
 	encoder := yaml.NewEncoder(&buf)
-	encoder.Encode(ClusterMachinesTemplate{
+	encoder.Encode(models.MachinesYAML{
 		Kind:             KindMachine,
 		SystemExtensions: plan.SystemExtensions,
 		Name:             plan.Name.ValueString(),
 		Labels:           plan.Labels,
 		Annotations:      plan.Annotations,
 		Locked:           plan.Locked.ValueBool(),
-		Install:          plan.Install,
-		Patches:          convertPatchToYAML(plan.Patches),
+		Install:          convertMachineInstallToYAML(plan.Install),
+		Patches:          append(convertPatchToYAML(plan.Patches), convertKernelArgsToPatch(plan.KernelArgs)...),
 	})
 	yamlOutput := buf.String()
 
@@ -209,6 +608,8 @@ func (r *omniClusterMachinesTemplate) Create(ctx context.Context, req resource.C
 	plan.Kind = types.StringValue(KindMachine)
 	plan.YAML = types.StringValue(string(yamlOutput))
 
+	resp.Diagnostics.Append(r.refreshPlannedFromOmni(ctx, instance.state, &plan)...)
+
 	timeNow := types.StringValue(time.Now().Format(time.RFC850))
 	plan.CreatedAt = timeNow
 	plan.LastUpdated = timeNow
@@ -220,12 +621,18 @@ func (r *omniClusterMachinesTemplate) Create(ctx context.Context, req resource.C
 }
 
 func (r *omniClusterMachinesTemplate) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
-	if r.omniClient == nil {
+	if r.providerData == nil {
 		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
 		return
 	}
 
-	var config OmniClusterMachinesTemplateModelV0
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniClusterMachinesTemplateModelV1
 	resp.Diagnostics.Append(req.State.Get(ctx, &config)...)
 	if resp.Diagnostics.HasError() {
 		return
@@ -234,12 +641,23 @@ func (r *omniClusterMachinesTemplate) Read(ctx context.Context, req resource.Rea
 	tflog.Debug(ctx, "Read cluster machine templates from state.")
 	tflog.Trace(ctx, fmt.Sprintf("Cluster machines YAML from state:\n%s", config.YAML))
 
+	if err := r.refreshFromOmni(ctx, instance.state, &config); err != nil {
+		resp.Diagnostics.AddError("failed to read machine from omni", err.Error())
+		return
+	}
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
 }
 
 func (r *omniClusterMachinesTemplate) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var plan OmniClusterMachinesTemplateModelV0
-	var state OmniClusterMachinesTemplateModelV0
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniClusterMachinesTemplateModelV1
+	var state OmniClusterMachinesTemplateModelV1
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 	if resp.Diagnostics.HasError() {
@@ -251,15 +669,20 @@ func (r *omniClusterMachinesTemplate) Update(ctx context.Context, req resource.U
 		return
 	}
 
-	yamlOutput, err := yaml.Marshal(ClusterMachinesTemplate{
+	if err := r.resolvePlannedInstallDisk(ctx, instance.state, &plan); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("install").AtName("disk_selector"), "Install disk not resolved", err.Error())
+		return
+	}
+
+	yamlOutput, err := yaml.Marshal(models.MachinesYAML{
 		Kind:             KindMachine,
 		SystemExtensions: plan.SystemExtensions,
 		Name:             plan.Name.ValueString(),
 		Labels:           plan.Labels,
 		Annotations:      plan.Annotations,
 		Locked:           plan.Locked.ValueBool(),
-		Install:          plan.Install,
-		Patches:          convertPatchToYAML(plan.Patches),
+		Install:          convertMachineInstallToYAML(plan.Install),
+		Patches:          append(convertPatchToYAML(plan.Patches), convertKernelArgsToPatch(plan.KernelArgs)...),
 	})
 	if err != nil {
 		resp.Diagnostics.AddError("Could not template YAML", "Error encountered generating YAML from inputs.")
@@ -270,14 +693,86 @@ func (r *omniClusterMachinesTemplate) Update(ctx context.Context, req resource.U
 	plan.ID = plan.Name
 	plan.YAML = types.StringValue(string(yamlOutput))
 
+	resp.Diagnostics.Append(r.refreshPlannedFromOmni(ctx, instance.state, &plan)...)
+
 	timeNow := types.StringValue(time.Now().Format(time.RFC850))
 	plan.LastUpdated = timeNow
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
+// refreshFromOmni sets the schematic, the extensions and the kernel args Omni reports for
+// the machine. The declared extensions and kernel args are kept, ModifyPlan warns about
+// differences to the ones the machine runs.
+func (r *omniClusterMachinesTemplate) refreshFromOmni(ctx context.Context, st cosistate.State, model *OmniClusterMachinesTemplateModelV1) error {
+	observation, err := observeMachine(ctx, st, model.Name.ValueString())
+	if err != nil {
+		return err
+	}
+
+	model.SchematicID = types.StringPointerValue(observation.schematicID)
+	model.InstalledSystemExtensions = types.ListNull(types.StringType)
+	model.InstalledKernelArgs = types.ListNull(types.StringType)
+
+	if observation.extensions != nil {
+		installed := installedExtensions(model.SystemExtensions, observation.extensions)
+
+		installedList, diags := types.ListValueFrom(ctx, types.StringType, installed)
+		if diags.HasError() {
+			return fmt.Errorf("failed to convert the installed extensions: %v", diags)
+		}
+
+		model.InstalledSystemExtensions = installedList
+	}
+
+	if observation.kernelArgs != nil {
+		kernelArgsList, diags := types.ListValueFrom(ctx, types.StringType, *observation.kernelArgs)
+		if diags.HasError() {
+			return fmt.Errorf("failed to convert the installed kernel args: %v", diags)
+		}
+
+		model.InstalledKernelArgs = kernelArgsList
+	}
+
+	return nil
+}
+
+// refreshPlannedFromOmni sets the computed attributes the plan left unknown. A failed
+// lookup only warns, as the template doesn't depend on it, and leaves them null until
+// the next refresh.
+func (r *omniClusterMachinesTemplate) refreshPlannedFromOmni(ctx context.Context, st cosistate.State, plan *OmniClusterMachinesTemplateModelV1) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	observed := *plan
+
+	if err := r.refreshFromOmni(ctx, st, &observed); err != nil {
+		diags.AddWarning(
+			"Failed to read machine from Omni",
+			fmt.Sprintf("schematic_id, installed_system_extensions and installed_kernel_args of machine %s are set on the next refresh: %s", plan.Name.ValueString(), err),
+		)
+
+		observed.SchematicID = types.StringNull()
+		observed.InstalledSystemExtensions = types.ListNull(types.StringType)
+		observed.InstalledKernelArgs = types.ListNull(types.StringType)
+	}
+
+	if plan.SchematicID.IsUnknown() {
+		plan.SchematicID = observed.SchematicID
+	}
+
+	if plan.InstalledSystemExtensions.IsUnknown() {
+		plan.InstalledSystemExtensions = observed.InstalledSystemExtensions
+	}
+
+	if plan.InstalledKernelArgs.IsUnknown() {
+		plan.InstalledKernelArgs = observed.InstalledKernelArgs
+	}
+
+	return diags
+}
+
 func (r *omniClusterMachinesTemplate) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
-	var state OmniClusterMachinesTemplateModelV0
+	var state OmniClusterMachinesTemplateModelV1
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
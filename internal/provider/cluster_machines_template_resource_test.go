@@ -2,9 +2,22 @@ package provider
 
 import (
 	"fmt"
+	"slices"
 	"testing"
 
+	cosiresource "github.com/cosi-project/runtime/pkg/resource"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	tfresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 )
 
 var machineTestControlPlaneMachines = []string{
@@ -89,3 +102,175 @@ resource "omni_cluster_machine_template" "test3" {
 
 `, machines[0], machines[1], machines[2])
 }
+
+func TestMachinesTemplateRefreshFromOmni(t *testing.T) {
+	ctx := t.Context()
+	st := cosistate.WrapCore(namespaced.NewState(inmem.Build))
+
+	schematicConfiguration := omni.NewSchematicConfiguration(resources.DefaultNamespace, "machine")
+	schematicConfiguration.TypedSpec().Value.SchematicId = "schematic"
+
+	extensionsStatus := omni.NewMachineExtensionsStatus(resources.DefaultNamespace, "machine")
+	extensionsStatus.TypedSpec().Value.Extensions = []*specs.MachineExtensionsStatusSpec_Item{
+		{Name: "siderolabs/iscsi-tools", Phase: specs.MachineExtensionsStatusSpec_Item_Installed},
+	}
+
+	for _, res := range []cosiresource.Resource{schematicConfiguration, extensionsStatus} {
+		if err := st.Create(ctx, res); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &omniClusterMachinesTemplate{}
+
+	model := OmniClusterMachinesTemplateModelV1{
+		Name:             types.StringValue("machine"),
+		SystemExtensions: []string{"siderolabs/qemu-guest-agent"},
+		KernelArgs:       []string{"console=ttyS0"},
+	}
+
+	if err := r.refreshFromOmni(ctx, st, &model); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(model.SystemExtensions, []string{"siderolabs/qemu-guest-agent"}) || !slices.Equal(model.KernelArgs, []string{"console=ttyS0"}) {
+		t.Errorf("expected the configured extensions and kernel args to be kept, got %v and %v", model.SystemExtensions, model.KernelArgs)
+	}
+
+	installed := types.ListValueMust(types.StringType, []attr.Value{types.StringValue("siderolabs/iscsi-tools")})
+
+	if !model.SchematicID.Equal(types.StringValue("schematic")) || !model.InstalledSystemExtensions.Equal(installed) {
+		t.Errorf("unexpected schematic %s and installed extensions %s", model.SchematicID, model.InstalledSystemExtensions)
+	}
+
+	if !model.InstalledKernelArgs.IsNull() {
+		t.Errorf("expected null installed kernel args without an applied machine config, got %s", model.InstalledKernelArgs)
+	}
+
+	for _, tc := range []struct {
+		name               string
+		machine            string
+		schematicID        types.String
+		expectedSchematic  types.String
+		expectedExtensions types.List
+	}{
+		{
+			name:               "unknown",
+			machine:            "machine",
+			schematicID:        types.StringUnknown(),
+			expectedSchematic:  types.StringValue("schematic"),
+			expectedExtensions: installed,
+		},
+		{
+			name:               "kept from the state",
+			machine:            "machine",
+			schematicID:        types.StringValue("previous"),
+			expectedSchematic:  types.StringValue("previous"),
+			expectedExtensions: installed,
+		},
+		{
+			name:               "not part of a cluster",
+			machine:            "unallocated",
+			schematicID:        types.StringUnknown(),
+			expectedSchematic:  types.StringNull(),
+			expectedExtensions: types.ListNull(types.StringType),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := OmniClusterMachinesTemplateModelV1{
+				Name:                      types.StringValue(tc.machine),
+				SchematicID:               tc.schematicID,
+				InstalledSystemExtensions: types.ListUnknown(types.StringType),
+			}
+
+			if diags := r.refreshPlannedFromOmni(ctx, st, &plan); diags.HasError() || diags.WarningsCount() > 0 {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+
+			if !plan.SchematicID.Equal(tc.expectedSchematic) || !plan.InstalledSystemExtensions.Equal(tc.expectedExtensions) {
+				t.Errorf("unexpected schematic %s and installed extensions %s", plan.SchematicID, plan.InstalledSystemExtensions)
+			}
+		})
+	}
+}
+
+func TestMachinesTemplateModifyPlanDrift(t *testing.T) {
+	strings := func(values ...string) tftypes.Value {
+		elements := make([]tftypes.Value, 0, len(values))
+		for _, value := range values {
+			elements = append(elements, tftypes.NewValue(tftypes.String, value))
+		}
+
+		return tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, elements)
+	}
+
+	r := &omniClusterMachinesTemplate{}
+
+	for _, tc := range []struct {
+		name                string
+		installedExtensions tftypes.Value
+		installedKernelArgs tftypes.Value
+		expected            []string
+	}{
+		{
+			name:                "in sync",
+			installedExtensions: strings("siderolabs/iscsi-tools"),
+			installedKernelArgs: strings("console=ttyS0"),
+		},
+		{
+			name:                "extensions",
+			installedExtensions: strings("siderolabs/iscsi-tools", "siderolabs/qemu-guest-agent"),
+			installedKernelArgs: strings("console=ttyS0"),
+			expected:            []string{"System extensions differ from the machine"},
+		},
+		{
+			name:                "kernel args",
+			installedExtensions: strings("siderolabs/iscsi-tools"),
+			installedKernelArgs: strings("console=ttyS0", "net.ifnames=0"),
+			expected:            []string{"Kernel args differ from the machine"},
+		},
+		{
+			name:                "not part of a cluster",
+			installedExtensions: tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+			installedKernelArgs: tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			declared := map[string]tftypes.Value{
+				"name":              tftypes.NewValue(tftypes.String, "machine"),
+				"role":              tftypes.NewValue(tftypes.String, "worker"),
+				"system_extensions": strings("siderolabs/iscsi-tools"),
+				"kernel_args":       strings("console=ttyS0"),
+			}
+
+			plan := testResourceConfig(t, r, declared)
+
+			declared["installed_system_extensions"] = tc.installedExtensions
+			declared["installed_kernel_args"] = tc.installedKernelArgs
+
+			state := testResourceConfig(t, r, declared)
+
+			req := tfresource.ModifyPlanRequest{
+				Config: plan,
+				Plan:   tfsdk.Plan{Schema: plan.Schema, Raw: plan.Raw},
+				State:  tfsdk.State{Schema: state.Schema, Raw: state.Raw},
+			}
+			resp := tfresource.ModifyPlanResponse{Plan: req.Plan}
+
+			r.ModifyPlan(t.Context(), req, &resp)
+
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected errors: %v", resp.Diagnostics)
+			}
+
+			var warnings []string
+			for _, warning := range resp.Diagnostics.Warnings() {
+				warnings = append(warnings, warning.Summary())
+			}
+
+			if !slices.Equal(warnings, tc.expected) {
+				t.Errorf("expected the warnings %v, got %v", tc.expected, warnings)
+			}
+		})
+	}
+}
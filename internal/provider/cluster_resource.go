@@ -9,18 +9,18 @@ import (
 	"terraform-provider-omni/internal/models"
 	"time"
 
-	cosiresource "github.com/cosi-project/runtime/pkg/resource"
 	"github.com/cosi-project/runtime/pkg/safe"
 	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
-	"github.com/siderolabs/omni/client/pkg/client"
-	"github.com/siderolabs/omni/client/pkg/omni/resources"
 	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 	"github.com/siderolabs/omni/client/pkg/template"
 	"github.com/siderolabs/omni/client/pkg/template/operations"
@@ -30,15 +30,18 @@ import (
 var (
 	_ resource.Resource = &omniClusterResource{}
 	// _ resource.ResourceWithConfigure   = &omniClusterResource{}
-	_ resource.ResourceWithImportState = &omniClusterResource{}
-	// _ resource.ResourceWithModifyPlan  = &omniClusterResource{}
+	_ resource.ResourceWithImportState    = &omniClusterResource{}
+	_ resource.ResourceWithModifyPlan     = &omniClusterResource{}
+	_ resource.ResourceWithValidateConfig = &omniClusterResource{}
+	_ resource.ResourceWithUpgradeState   = &omniClusterResource{}
 )
 
 type omniClusterResource struct {
-	omniClient *client.Client
-	context    context.Context
+	providerData *omniProviderData
+	context      context.Context
 }
 
+// OmniClusterResourceModelV0 is the state of version 0, before the omni_instance attribute was added.
 type OmniClusterResourceModelV0 struct {
 	ID                   types.String   `tfsdk:"id"`
 	CreatedAt            types.String   `tfsdk:"created_at"`
@@ -51,6 +54,20 @@ type OmniClusterResourceModelV0 struct {
 	YAML                 types.String   `tfsdk:"yaml"`
 }
 
+type OmniClusterResourceModelV1 struct {
+	ID                   types.String   `tfsdk:"id"`
+	CreatedAt            types.String   `tfsdk:"created_at"`
+	LastUpdated          types.String   `tfsdk:"last_updated"`
+	DeleteMachineLinks   types.Bool     `tfsdk:"delete_machine_links"`
+	MachineRemoval       types.String   `tfsdk:"machine_removal"`
+	ClusterTemplate      types.String   `tfsdk:"cluster_template"`
+	ControlPlaneTemplate types.String   `tfsdk:"control_plane_template"`
+	WorkersTemplate      []types.String `tfsdk:"workers_template"`
+	MachinesTemplate     []types.String `tfsdk:"machines_template"`
+	YAML                 types.String   `tfsdk:"yaml"`
+	OmniInstance         types.String   `tfsdk:"omni_instance"`
+}
+
 func NewOmniClusterResource() resource.Resource {
 	return &omniClusterResource{}
 }
@@ -64,25 +81,27 @@ func (r *omniClusterResource) Configure(_ context.Context, req resource.Configur
 		return
 	}
 
-	omniClient, ok := req.ProviderData.(*client.Client)
+	providerData, ok := req.ProviderData.(*omniProviderData)
 	tflog.Debug(context.Background(), "Configuring omni machine status data source")
 	if !ok {
 		resp.Diagnostics.AddError(
 			"failed to get image factory client",
-			fmt.Sprintf("Expected *client.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
 	r.context = context.Background()
-	r.omniClient = omniClient
+	r.providerData = providerData
 }
 
 func (r *omniClusterResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version:     1,
 		Description: "Omni cluster template definition.",
 		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
 			"id": schema.StringAttribute{
 				Description: "Name (ID) of cluster.",
 				Computed:    true,
@@ -99,10 +118,68 @@ func (r *omniClusterResource) Schema(_ context.Context, _ resource.SchemaRequest
 					stringplanmodifier.UseStateForUnknown(),
 				},
 			},
+			"delete_machine_links": schema.BoolAttribute{
+				Optional:           true,
+				Description:        "Controls if machine links are deleted when cluster is deleted.",
+				DeprecationMessage: "Use machine_removal = \"remove\" instead.",
+				// Default:     booldefault.StaticBool(false),
+			},
+			"machine_removal": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(machineRemovalKeep),
+				Description: "What to do with machines which leave the cluster, either because they are removed from the templates or because the cluster is deleted. " +
+					"`keep` leaves them to Omni, which resets them in the background. " +
+					"`reset` waits until Omni has reset them, wiping their Talos state and ephemeral partitions, and they are back in maintenance mode. " +
+					"`remove` also removes their machine links afterwards, so they have to join Omni again to be used.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(machineRemovalKeep, machineRemovalReset, machineRemovalRemove),
+				},
+			},
+			"cluster_template": schema.StringAttribute{
+				Description: "YAML document describing cluster.",
+				Required:    true,
+			},
+			"control_plane_template": schema.StringAttribute{
+				Description: "YAML document describing control plane machine set.",
+				Required:    true,
+			},
+			"workers_template": schema.SetAttribute{
+				ElementType: types.StringType,
+				Description: "YAML document describing workers machine sets.",
+				Required:    true,
+			},
+			"machines_template": schema.SetAttribute{
+				ElementType: types.StringType,
+				Description: "YAML document describing machines.",
+				Required:    true,
+			},
+			"yaml": schema.StringAttribute{
+				Description: "Full YAML document descripting cluster template.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// schemaV0 returns the version 0 schema, which decodes the prior state in upgrades.
+func (r *omniClusterResource) schemaV0() *schema.Schema {
+	return &schema.Schema{
+		Description: "Omni cluster template definition.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "Name (ID) of cluster.",
+				Computed:    true,
+			},
+			"last_updated": schema.StringAttribute{
+				Computed: true,
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+			},
 			"delete_machine_links": schema.BoolAttribute{
 				Optional:    true,
 				Description: "Controls if machine links are deleted when cluster is deleted.",
-				// Default:     booldefault.StaticBool(false),
 			},
 			"cluster_template": schema.StringAttribute{
 				Description: "YAML document describing cluster.",
@@ -130,15 +207,125 @@ func (r *omniClusterResource) Schema(_ context.Context, _ resource.SchemaRequest
 	}
 }
 
+// UpgradeState upgrades the state written before the omni_instance attribute was added.
+// Machines leaving the cluster were kept before the machine_removal attribute was added.
+func (r *omniClusterResource) UpgradeState(context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: stateUpgrader(r.schemaV0(), func(prior OmniClusterResourceModelV0) OmniClusterResourceModelV1 {
+			return OmniClusterResourceModelV1{
+				ID:                   prior.ID,
+				CreatedAt:            prior.CreatedAt,
+				LastUpdated:          prior.LastUpdated,
+				DeleteMachineLinks:   prior.DeleteMachineLinks,
+				MachineRemoval:       types.StringValue(machineRemovalKeep),
+				ClusterTemplate:      prior.ClusterTemplate,
+				ControlPlaneTemplate: prior.ControlPlaneTemplate,
+				WorkersTemplate:      prior.WorkersTemplate,
+				MachinesTemplate:     prior.MachinesTemplate,
+				YAML:                 prior.YAML,
+				OmniInstance:         types.StringNull(),
+			}
+		}),
+	}
+}
+
+// ValidateConfig checks the control plane topology and that no machine is listed in
+// more than one machine set. Templates which are unknown or not valid YAML are left to
+// Omni's template validation.
+func (r *omniClusterResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var (
+		controlPlaneTemplate types.String
+		workersTemplate      types.Set
+	)
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("control_plane_template"), &controlPlaneTemplate)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("workers_template"), &workersTemplate)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var machineSets []models.MachineSetYAML
+
+	if !controlPlaneTemplate.IsNull() && !controlPlaneTemplate.IsUnknown() {
+		var controlPlane models.MachineSetYAML
+		if err := yaml.Unmarshal([]byte(controlPlaneTemplate.ValueString()), &controlPlane); err == nil {
+			warning, err := checkControlPlaneTemplate(controlPlane)
+			if err != nil {
+				resp.Diagnostics.AddAttributeError(path.Root("control_plane_template"), "Invalid control plane", err.Error())
+			}
+
+			if warning != "" {
+				resp.Diagnostics.AddAttributeWarning(path.Root("control_plane_template"), "Even number of control plane machines", warning)
+			}
+
+			machineSets = append(machineSets, controlPlane)
+		}
+	}
+
+	if !workersTemplate.IsUnknown() {
+		for _, element := range workersTemplate.Elements() {
+			workerTemplate, ok := element.(types.String)
+			if !ok || workerTemplate.IsUnknown() {
+				continue
+			}
+
+			var workers models.MachineSetYAML
+			if err := yaml.Unmarshal([]byte(workerTemplate.ValueString()), &workers); err == nil {
+				machineSets = append(machineSets, workers)
+			}
+		}
+	}
+
+	if err := checkDuplicateMachines(machineSets); err != nil {
+		resp.Diagnostics.AddError("Machine in more than one machine set", err.Error())
+	}
+}
+
+// ModifyPlan checks that Omni holds the etcd backup the control plane restores from
+// before the cluster is created.
+func (r *omniClusterResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || !req.State.Raw.IsNull() || r.providerData == nil {
+		return
+	}
+
+	var plan OmniClusterResourceModelV1
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.ControlPlaneTemplate.IsUnknown() {
+		return
+	}
+
+	var controlPlane models.MachineSetYAML
+	if err := yaml.Unmarshal([]byte(plan.ControlPlaneTemplate.ValueString()), &controlPlane); err != nil || controlPlane.BootstrapSpec == nil {
+		return
+	}
+
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() || instance == nil {
+		return
+	}
+
+	err := checkEtcdBackupSnapshot(ctx, instance.state, controlPlane.BootstrapSpec.ClusterUUID, controlPlane.BootstrapSpec.Snapshot)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("control_plane_template"), "Etcd backup not found", err.Error())
+	}
+}
+
 func (r *omniClusterResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
-	if r.omniClient == nil {
+	if r.providerData == nil {
 		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
 		return
 	}
 
-	st := r.omniClient.Omni().State()
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	var config OmniClusterResourceModelV0
+	st := instance.state
+
+	var config OmniClusterResourceModelV1
 	resp.Diagnostics.Append(req.State.Get(ctx, &config)...)
 	if resp.Diagnostics.HasError() {
 		return
@@ -177,9 +364,15 @@ func (r *omniClusterResource) Read(ctx context.Context, req resource.ReadRequest
 }
 
 func (r *omniClusterResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var plan OmniClusterResourceModelV0
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	diags := req.Plan.Get(ctx, &plan)
+	var plan OmniClusterResourceModelV1
+
+	diags = req.Plan.Get(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
@@ -198,7 +391,7 @@ func (r *omniClusterResource) Create(ctx context.Context, req resource.CreateReq
 	if err != nil {
 		resp.Diagnostics.AddError("eror encountered constructing yaml", fmt.Sprintf("error: %s", err))
 	}
-	syncError := SyncClusterTemplateAndWaitForReady(ctx, r.omniClient.Omni().State(), strings.NewReader(planYAML))
+	syncError := SyncClusterTemplateAndWaitForReady(ctx, instance.state, strings.NewReader(planYAML))
 	if syncError != nil {
 		resp.Diagnostics.AddError("eror encountered syncing template", fmt.Sprintf("error: %s", syncError))
 		return
@@ -217,96 +410,51 @@ func (r *omniClusterResource) Create(ctx context.Context, req resource.CreateReq
 }
 
 func (r *omniClusterResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
-	tflog.Debug(ctx, "you reached the delete function")
-	st := r.omniClient.Omni().State()
-	var state OmniClusterResourceModelV0
-	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	tflog.Debug(ctx, "getting machine IDs now")
-
-	var machineIDList []string
-	for _, machineYAML := range state.MachinesTemplate {
-		var machineSpec models.MachinesYAML
-		// machineYAML.ValueString()
-		yaml.Unmarshal([]byte(machineYAML.ValueString()), &machineSpec)
-		machineIDList = append(machineIDList, machineSpec.Name)
+	tflog.Debug(ctx, "you reached the delete function")
+	st := instance.state
+	var state OmniClusterResourceModelV1
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	tflog.Debug(ctx, fmt.Sprintf("machine IDs get:\n%s", machineIDList))
+	machineIDs := templateMachineIDs(state.ControlPlaneTemplate, state.WorkersTemplate, state.MachinesTemplate)
 
-	machinesList, err := safe.StateList[*omni.MachineStatus](ctx, st, omni.NewMachineStatus(resources.DefaultNamespace, "").Metadata())
-	if err != nil {
-		return
-	}
-	tflog.Debug(ctx, fmt.Sprintf("machines get:\n%s", machinesList))
-	var clusterMachines []*omni.MachineStatus
-	for _, machineID := range machineIDList {
-		filteredMachine, found := machinesList.Find(func(e *omni.MachineStatus) bool {
-			if e.Metadata().ID() != machineID {
-				return false
-			}
-			return true
-		})
-		if found != false {
-			clusterMachines = append(clusterMachines, filteredMachine)
-			tflog.Debug(ctx, fmt.Sprintf("found machine:\n%s", filteredMachine.Metadata().ID()))
-		}
-	}
+	tflog.Debug(ctx, fmt.Sprintf("machines of the cluster: %s", machineIDs))
 
+	// the delete operation waits for Omni to tear down the cluster resources
 	clusterDeleteErr := operations.DeleteCluster(ctx, state.ID.ValueString(), io.Discard, st, operations.SyncOptions{})
 	if clusterDeleteErr != nil {
 		resp.Diagnostics.AddError("Error deleting cluster", fmt.Sprintf("error: %s", clusterDeleteErr))
 		return
 	}
 
-	tflog.Debug(ctx, "sent deletion, monitoring status")
-
-	for {
-		_, err := safe.StateGetByID[*omni.ClusterDestroyStatus](ctx, st, omni.NewClusterDestroyStatus(resources.DefaultNamespace, "").Metadata().ID())
-		if err != nil {
-			break
-		}
-	}
-
-	tflog.Debug(ctx, "it go deleted, checking if we want to delete machine links")
-
+	removal := state.MachineRemoval.ValueString()
 	if state.DeleteMachineLinks.ValueBool() {
-		tflog.Debug(ctx, "turns out we do want to delete machine links")
-		for _, machine := range clusterMachines {
-			destroyReady, err := st.Teardown(
-				ctx,
-				cosiresource.NewMetadata(machine.Metadata().Namespace(),
-					"Links.omni.sidero.dev",
-					machine.Metadata().ID(),
-					machine.Metadata().Version(),
-				))
-			if err != nil {
-				resp.Diagnostics.AddError("Error during teardown", fmt.Sprintf("error: %s", err))
-				return
-			}
+		removal = machineRemovalRemove
+	}
 
-			if destroyReady {
-				if err = st.Destroy(
-					ctx,
-					cosiresource.NewMetadata(machine.Metadata().Namespace(),
-						"Links.omni.sidero.dev",
-						machine.Metadata().ID(),
-						machine.Metadata().Version(),
-					)); err != nil {
-					resp.Diagnostics.AddError("Error during destroy", fmt.Sprintf("error: %s", err))
-					return
-				}
-			}
-		}
+	if err := removeMachines(ctx, st, instance.machines, machineIDs, removal); err != nil {
+		resp.Diagnostics.AddError("Error removing machines", err.Error())
+		return
 	}
 }
 
 func (r *omniClusterResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var plan OmniClusterResourceModelV0
-	var state OmniClusterResourceModelV0
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniClusterResourceModelV1
+	var state OmniClusterResourceModelV1
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 	if resp.Diagnostics.HasError() {
@@ -331,9 +479,22 @@ func (r *omniClusterResource) Update(ctx context.Context, req resource.UpdateReq
 
 	tflog.Debug(ctx, fmt.Sprintf("Constructed YAML during update:\n%s", finalYAML))
 
-	syncError := SyncClusterTemplateAndWaitForReady(ctx, r.omniClient.Omni().State(), strings.NewReader(finalYAML))
+	st := instance.state
+
+	syncError := SyncClusterTemplateAndWaitForReady(ctx, st, strings.NewReader(finalYAML))
 	if syncError != nil {
 		resp.Diagnostics.AddError("error syncing template", fmt.Sprintf("full error: %s", syncError))
+		return
+	}
+
+	leaving := leavingMachines(
+		templateMachineIDs(state.ControlPlaneTemplate, state.WorkersTemplate, state.MachinesTemplate),
+		templateMachineIDs(plan.ControlPlaneTemplate, plan.WorkersTemplate, plan.MachinesTemplate),
+	)
+
+	if err := removeMachines(ctx, st, instance.machines, leaving, plan.MachineRemoval.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Error removing machines", err.Error())
+		return
 	}
 
 	plan.YAML = types.StringValue(finalYAML)
@@ -351,10 +512,10 @@ func (r *omniClusterResource) Update(ctx context.Context, req resource.UpdateReq
 }
 
 func (r *omniClusterResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	importStateWithInstance(ctx, r.providerData, req, resp)
 }
 
-func constructYAMLTemplate(ctx context.Context, plan OmniClusterResourceModelV0) (string, error) {
+func constructYAMLTemplate(ctx context.Context, plan OmniClusterResourceModelV1) (string, error) {
 	buf := &bytes.Buffer{}
 
 	encoder := yaml.NewEncoder(buf)
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"terraform-provider-omni/internal/models"
 	"terraform-provider-omni/internal/validators"
 
@@ -17,7 +18,9 @@ import (
 
 var _ datasource.DataSource = &omniClusterTemplateDataSource{}
 
-type omniClusterTemplateDataSource struct{}
+type omniClusterTemplateDataSource struct {
+	providerData *omniProviderData
+}
 
 type OmniClusterTemplateModelV0 struct {
 	ID               types.String             `tfsdk:"id"`
@@ -27,10 +30,12 @@ type OmniClusterTemplateModelV0 struct {
 	Annotations      models.Annotations       `tfsdk:"annotations"`
 	Kubernetes       models.ClusterKubernetes `tfsdk:"kubernetes"`
 	Talos            models.ClusterTalos      `tfsdk:"talos"`
-	Features         models.ClusterFeatures   `tfsdk:"features"`
+	Features         *models.ClusterFeatures  `tfsdk:"features"`
 	Patches          []models.Patch           `tfsdk:"patches"`
 	SystemExtensions models.SystemExtensions  `tfsdk:"system_extensions"`
+	ValidateVersions types.Bool               `tfsdk:"validate_versions"`
 	YAML             types.String             `tfsdk:"yaml"`
+	OmniInstance     types.String             `tfsdk:"omni_instance"`
 }
 
 func NewOmniClusterTemplateDataSource() datasource.DataSource {
@@ -45,12 +50,25 @@ func (d *omniClusterTemplateDataSource) Configure(_ context.Context, req datasou
 	if req.ProviderData == nil {
 		return
 	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.providerData = providerData
 }
 
 func (d *omniClusterTemplateDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Description: "Creates a machine template to use within a cluster template.",
 		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceDataSourceAttribute(),
 			"id": schema.StringAttribute{
 				Computed:    true,
 				Description: "ID of the resource.",
@@ -129,6 +147,10 @@ func (d *omniClusterTemplateDataSource) Schema(_ context.Context, _ datasource.S
 			"patches": schema.ListNestedAttribute{
 				NestedObject: schema.NestedAttributeObject{
 					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Optional:    true,
+							Description: "The name of the patch. Required for inline patches unless `id_override` is set.",
+						},
 						"id_override": schema.StringAttribute{
 							Optional:    true,
 							Description: "The name (ID) of the patch.",
@@ -161,6 +183,10 @@ func (d *omniClusterTemplateDataSource) Schema(_ context.Context, _ datasource.S
 				Description: "List of system extensions installed to machine.",
 				Optional:    true,
 			},
+			"validate_versions": schema.BoolAttribute{
+				Description: "Check that Omni supports the Talos version, and that the Kubernetes version is compatible with it.",
+				Optional:    true,
+			},
 			"yaml": schema.StringAttribute{
 				Description: "Rendered YAML cluster machine template.",
 				Computed:    true,
@@ -174,6 +200,30 @@ func (d *omniClusterTemplateDataSource) Read(ctx context.Context, req datasource
 
 	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
 
+	if config.ValidateVersions.ValueBool() {
+		if d.providerData == nil {
+			resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+			return
+		}
+
+		instance, diags := d.providerData.instanceOf(ctx, req.Config)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		talosVersions, err := listTalosVersions(ctx, instance.cachedState)
+		if err != nil {
+			resp.Diagnostics.AddError("failed to get omni talos versions", err.Error())
+			return
+		}
+
+		if err := checkClusterVersions(talosVersions, config.Talos.Version.ValueString(), config.Kubernetes.Version.ValueString()); err != nil {
+			resp.Diagnostics.AddError("Unsupported cluster versions", err.Error())
+			return
+		}
+	}
+
 	yamlOutput, err := yaml.Marshal(models.ClusterYAML{
 		Kind:             string(KindCluster),
 		Name:             config.Name.ValueString(),
@@ -4,21 +4,36 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
-	"github.com/siderolabs/omni/client/pkg/client"
 )
 
 type omniDefaultMachineJoinConfigDataSource struct {
-	omniClient *client.Client
+	providerData *omniProviderData
 }
 
 type OmniDefaultMachineJoinConfigModelV0 struct {
-	ID         types.String `tfsdk:"id"`
-	KernelArgs types.List   `tfsdk:"kernel_args"`
-	ConfigYAML types.String `tfsdk:"config_yaml"`
+	ID                  types.String `tfsdk:"id"`
+	JoinTokenID         types.String `tfsdk:"join_token_id"`
+	UseGRPCTunnel       types.Bool   `tfsdk:"use_grpc_tunnel"`
+	ExtraKernelArgs     []string     `tfsdk:"extra_kernel_args"`
+	IPXEKernelURL       types.String `tfsdk:"ipxe_kernel_url"`
+	IPXEInitramfsURL    types.String `tfsdk:"ipxe_initramfs_url"`
+	KernelArgs          types.List   `tfsdk:"kernel_args"`
+	ConfigYAML          types.String `tfsdk:"config_yaml"`
+	ConfigYAMLBase64    types.String `tfsdk:"config_yaml_base64"`
+	KernelCmdline       types.String `tfsdk:"kernel_cmdline"`
+	KernelCmdlineBase64 types.String `tfsdk:"kernel_cmdline_base64"`
+	IPXEScript          types.String `tfsdk:"ipxe_script"`
+	IPXEScriptBase64    types.String `tfsdk:"ipxe_script_base64"`
+	UserData            types.String `tfsdk:"user_data"`
+	UserDataBase64      types.String `tfsdk:"user_data_base64"`
+	OmniInstance        types.String `tfsdk:"omni_instance"`
 }
 
 var _ datasource.DataSource = &omniDefaultMachineJoinConfigDataSource{}
@@ -35,9 +50,40 @@ func (d *omniDefaultMachineJoinConfigDataSource) Schema(_ context.Context, _ dat
 	resp.Schema = schema.Schema{
 		Description: "Omni default machine join config definition.",
 		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceDataSourceAttribute(),
 			"id": schema.StringAttribute{
 				Description: "Name (ID) of the join token.",
 				Computed:    true,
+				Sensitive:   true,
+			},
+			"join_token_id": schema.StringAttribute{
+				Description: "ID of the join token to generate the join config for. The default join token is used when unset.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"use_grpc_tunnel": schema.BoolAttribute{
+				Description: "Controls whether the join config tunnels SideroLink traffic over gRPC. Defaults to true.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"extra_kernel_args": schema.ListAttribute{
+				ElementType: types.StringType,
+				Description: "Additional kernel arguments (e.g. `talos.platform=metal`) prepended to the join kernel arguments in the rendered kernel command line and iPXE script.",
+				Optional:    true,
+			},
+			"ipxe_kernel_url": schema.StringAttribute{
+				Description: "URL of the Talos kernel image used in the rendered iPXE script.",
+				Optional:    true,
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("ipxe_initramfs_url")),
+				},
+			},
+			"ipxe_initramfs_url": schema.StringAttribute{
+				Description: "URL of the Talos initramfs image used in the rendered iPXE script.",
+				Optional:    true,
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("ipxe_kernel_url")),
+				},
 			},
 			"kernel_args": schema.ListAttribute{
 				ElementType: types.StringType,
@@ -50,6 +96,41 @@ func (d *omniDefaultMachineJoinConfigDataSource) Schema(_ context.Context, _ dat
 				Computed:    true,
 				Sensitive:   true,
 			},
+			"config_yaml_base64": schema.StringAttribute{
+				Description: "Base64 encoded `config_yaml`.",
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"kernel_cmdline": schema.StringAttribute{
+				Description: "Extra and join kernel arguments rendered as a single kernel command line.",
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"kernel_cmdline_base64": schema.StringAttribute{
+				Description: "Base64 encoded `kernel_cmdline`.",
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"ipxe_script": schema.StringAttribute{
+				Description: "iPXE script booting `ipxe_kernel_url` and `ipxe_initramfs_url` with `kernel_cmdline`. Only set when both URLs are provided.",
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"ipxe_script_base64": schema.StringAttribute{
+				Description: "Base64 encoded `ipxe_script`.",
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"user_data": schema.StringAttribute{
+				Description: "Multi-document Talos config with the SideroLinkConfig, EventSinkConfig and KmsgLogConfig documents, suitable as cloud image user-data.",
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"user_data_base64": schema.StringAttribute{
+				Description: "Base64 encoded `user_data`.",
+				Computed:    true,
+				Sensitive:   true,
+			},
 		},
 	}
 }
@@ -59,37 +140,48 @@ func (d *omniDefaultMachineJoinConfigDataSource) Configure(_ context.Context, re
 		return
 	}
 
-	omniClient, ok := req.ProviderData.(*client.Client)
+	providerData, ok := req.ProviderData.(*omniProviderData)
 	tflog.Debug(context.Background(), "Configuring default omni machine join config datasource")
 	if !ok {
 		resp.Diagnostics.AddError(
 			"failed to get omni client",
-			fmt.Sprintf("Expected *client.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	d.omniClient = omniClient
+	d.providerData = providerData
 }
 
 func (d *omniDefaultMachineJoinConfigDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
-	if d.omniClient == nil {
+	if d.providerData == nil {
 		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
 		return
 	}
 
+	instance, diags := d.providerData.instanceOf(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	var config OmniDefaultMachineJoinConfigModelV0
-	diags := req.Config.Get(ctx, &config)
+	diags = req.Config.Get(ctx, &config)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	managementClient := d.omniClient.Management()
-	joinConfig, err := managementClient.GetMachineJoinConfig(ctx, "", true)
+	if config.UseGRPCTunnel.IsNull() {
+		config.UseGRPCTunnel = types.BoolValue(true)
+	}
+
+	managementClient := instance.client.Management()
+	joinConfig, err := managementClient.GetMachineJoinConfig(ctx, config.JoinTokenID.ValueString(), config.UseGRPCTunnel.ValueBool())
 	if err != nil {
 		resp.Diagnostics.AddError("Error retrieving machine join confg", err.Error())
+		return
 	}
 
 	tflog.Debug(ctx, fmt.Sprintf("machine join config (kernel args):\n%s", joinConfig.GetKernelArgs()))
@@ -100,7 +192,38 @@ func (d *omniDefaultMachineJoinConfigDataSource) Read(ctx context.Context, req d
 		return
 	}
 	config.ConfigYAML = types.StringValue(joinConfig.GetConfig())
-	config.ID = types.StringValue("default")
+	config.ConfigYAMLBase64 = types.StringValue(encodeBase64(joinConfig.GetConfig()))
+
+	kernelCmdline := renderKernelCmdline(joinConfig.GetKernelArgs(), config.ExtraKernelArgs)
+	config.KernelCmdline = types.StringValue(kernelCmdline)
+	config.KernelCmdlineBase64 = types.StringValue(encodeBase64(kernelCmdline))
+
+	config.IPXEScript = types.StringNull()
+	config.IPXEScriptBase64 = types.StringNull()
+	if !config.IPXEKernelURL.IsNull() && !config.IPXEInitramfsURL.IsNull() {
+		ipxeScript, err := renderIPXEScript(config.IPXEKernelURL.ValueString(), config.IPXEInitramfsURL.ValueString(), kernelCmdline)
+		if err != nil {
+			resp.Diagnostics.AddError("Error rendering iPXE script", err.Error())
+			return
+		}
+
+		config.IPXEScript = types.StringValue(ipxeScript)
+		config.IPXEScriptBase64 = types.StringValue(encodeBase64(ipxeScript))
+	}
+
+	userData, err := renderJoinUserData(joinConfig.GetKernelArgs())
+	if err != nil {
+		resp.Diagnostics.AddError("Error rendering join config user-data", err.Error())
+		return
+	}
+	config.UserData = types.StringValue(userData)
+	config.UserDataBase64 = types.StringValue(encodeBase64(userData))
+
+	if config.JoinTokenID.IsNull() {
+		config.ID = types.StringValue("default")
+	} else {
+		config.ID = config.JoinTokenID
+	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
 	if resp.Diagnostics.HasError() {
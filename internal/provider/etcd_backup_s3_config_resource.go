@@ -0,0 +1,463 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	cosiresource "github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework-validators/resourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+var (
+	_ resource.Resource                     = &omniEtcdBackupS3ConfigResource{}
+	_ resource.ResourceWithConfigure        = &omniEtcdBackupS3ConfigResource{}
+	_ resource.ResourceWithConfigValidators = &omniEtcdBackupS3ConfigResource{}
+	_ resource.ResourceWithImportState      = &omniEtcdBackupS3ConfigResource{}
+	_ resource.ResourceWithModifyPlan       = &omniEtcdBackupS3ConfigResource{}
+	_ resource.ResourceWithValidateConfig   = &omniEtcdBackupS3ConfigResource{}
+)
+
+var (
+	s3BucketRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+	httpURLRegexp  = regexp.MustCompile(`^https?://[^\s/]+(/.*)?$`)
+)
+
+type omniEtcdBackupS3ConfigResource struct {
+	providerData *omniProviderData
+}
+
+type OmniEtcdBackupS3ConfigModelV0 struct {
+	ID                   types.String `tfsdk:"id"`
+	CreatedAt            types.String `tfsdk:"created_at"`
+	LastUpdated          types.String `tfsdk:"last_updated"`
+	Bucket               types.String `tfsdk:"bucket"`
+	Region               types.String `tfsdk:"region"`
+	Endpoint             types.String `tfsdk:"endpoint"`
+	AccessKeyID          types.String `tfsdk:"access_key_id"`
+	SecretAccessKey      types.String `tfsdk:"secret_access_key"`
+	SecretAccessKeyWO    types.String `tfsdk:"secret_access_key_wo"`
+	SessionToken         types.String `tfsdk:"session_token"`
+	SessionTokenWO       types.String `tfsdk:"session_token_wo"`
+	CredentialsWOVersion types.Int64  `tfsdk:"credentials_wo_version"`
+	ConfigurationError   types.String `tfsdk:"configuration_error"`
+	OmniInstance         types.String `tfsdk:"omni_instance"`
+}
+
+func NewOmniEtcdBackupS3ConfigResource() resource.Resource {
+	return &omniEtcdBackupS3ConfigResource{}
+}
+
+func (r *omniEtcdBackupS3ConfigResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_etcd_backup_s3_config"
+}
+
+func (r *omniEtcdBackupS3ConfigResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni etcd backup s3 config resource")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.providerData = providerData
+}
+
+func (r *omniEtcdBackupS3ConfigResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Omni etcd backup S3 storage configuration. Omni holds a single S3 configuration, so only one instance of this resource should exist per Omni instance.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
+			"id": schema.StringAttribute{
+				Description: "ID of the S3 configuration, always `" + omni.EtcdBackupS3ConfID + "`.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"last_updated": schema.StringAttribute{
+				Computed: true,
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"bucket": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the bucket etcd backups are stored in.",
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(s3BucketRegexp, "must be a valid S3 bucket name"),
+				},
+			},
+			"region": schema.StringAttribute{
+				Required:    true,
+				Description: "Region of the bucket.",
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"endpoint": schema.StringAttribute{
+				Optional:    true,
+				Description: "Endpoint of an S3 compatible storage, e.g. `https://minio.example.com:9000`. Uses AWS S3 when unset.",
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(httpURLRegexp, "must be an http:// or https:// URL"),
+				},
+			},
+			"access_key_id": schema.StringAttribute{
+				Optional:    true,
+				Description: "Access key ID used to access the bucket. Omni falls back to its own environment credentials when unset.",
+			},
+			"secret_access_key": schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Secret access key used to access the bucket. Stored in the Terraform state, use `secret_access_key_wo` to avoid that.",
+			},
+			"secret_access_key_wo": schema.StringAttribute{
+				Optional:    true,
+				WriteOnly:   true,
+				Description: "Write-only variant of `secret_access_key`, never stored in the Terraform state. Bump `credentials_wo_version` to push a new value.",
+			},
+			"session_token": schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Session token used to access the bucket with temporary credentials.",
+			},
+			"session_token_wo": schema.StringAttribute{
+				Optional:    true,
+				WriteOnly:   true,
+				Description: "Write-only variant of `session_token`, never stored in the Terraform state. Bump `credentials_wo_version` to push a new value.",
+			},
+			"credentials_wo_version": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Version of the write-only credentials. Changing it sends the current `secret_access_key_wo` and `session_token_wo` values to Omni.",
+			},
+			"configuration_error": schema.StringAttribute{
+				Computed:    true,
+				Description: "Error Omni reports for the etcd backup store, empty when the configuration works.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *omniEtcdBackupS3ConfigResource) ConfigValidators(_ context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		resourcevalidator.Conflicting(path.MatchRoot("secret_access_key"), path.MatchRoot("secret_access_key_wo")),
+		resourcevalidator.Conflicting(path.MatchRoot("session_token"), path.MatchRoot("session_token_wo")),
+	}
+}
+
+func (r *omniEtcdBackupS3ConfigResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config OmniEtcdBackupS3ConfigModelV0
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if config.AccessKeyID.IsUnknown() || config.SecretAccessKey.IsUnknown() || config.SecretAccessKeyWO.IsUnknown() {
+		return
+	}
+
+	hasSecret := !config.SecretAccessKey.IsNull() || !config.SecretAccessKeyWO.IsNull()
+
+	if !config.AccessKeyID.IsNull() && !hasSecret {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("access_key_id"),
+			"Missing secret access key",
+			"Either secret_access_key or secret_access_key_wo must be set together with access_key_id.",
+		)
+	}
+
+	if config.AccessKeyID.IsNull() && hasSecret {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("access_key_id"),
+			"Missing access key ID",
+			"access_key_id must be set together with the secret access key.",
+		)
+	}
+}
+
+// ModifyPlan plans the configuration error Omni reports as unknown when the S3 settings
+// change, as Omni checks the new settings after they are applied.
+func (r *omniEtcdBackupS3ConfigResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	var plan, state OmniEtcdBackupS3ConfigModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if etcdBackupS3SettingsChanged(plan, state) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("configuration_error"), types.StringUnknown())...)
+	}
+}
+
+func (r *omniEtcdBackupS3ConfigResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan, config OmniEtcdBackupS3ConfigModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	s3Conf := omni.NewEtcdBackupS3Conf()
+	applyEtcdBackupS3Config(s3Conf, plan, config)
+
+	st := instance.state
+
+	if err := st.Create(ctx, s3Conf); err != nil {
+		if cosistate.IsConflictError(err) {
+			resp.Diagnostics.AddError(
+				"Etcd backup S3 configuration already exists",
+				fmt.Sprintf("Omni already holds an etcd backup S3 configuration, import it with the ID %q instead.", omni.EtcdBackupS3ConfID),
+			)
+
+			return
+		}
+
+		resp.Diagnostics.AddError("Error creating etcd backup S3 configuration", err.Error())
+		return
+	}
+
+	tflog.Debug(ctx, "created etcd backup s3 configuration")
+
+	plan.ID = types.StringValue(omni.EtcdBackupS3ConfID)
+	readEtcdBackupStoreStatus(ctx, st, &plan, &resp.Diagnostics)
+
+	timeNow := types.StringValue(time.Now().Format(time.RFC850))
+	plan.CreatedAt = timeNow
+	plan.LastUpdated = timeNow
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniEtcdBackupS3ConfigResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniEtcdBackupS3ConfigModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	st := instance.state
+
+	s3Conf, err := safe.StateGetByID[*omni.EtcdBackupS3Conf](ctx, st, omni.EtcdBackupS3ConfID)
+	if err != nil {
+		if cosistate.IsNotFoundError(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.AddError("Error reading etcd backup S3 configuration", err.Error())
+		return
+	}
+
+	// secrets are kept as configured, Omni may not return them to every user
+	spec := s3Conf.TypedSpec().Value
+	config.ID = types.StringValue(omni.EtcdBackupS3ConfID)
+	config.Bucket = types.StringValue(spec.Bucket)
+	config.Region = types.StringValue(spec.Region)
+	config.Endpoint = types.StringNull()
+	config.AccessKeyID = types.StringNull()
+
+	if spec.Endpoint != "" {
+		config.Endpoint = types.StringValue(spec.Endpoint)
+	}
+
+	if spec.AccessKeyId != "" {
+		config.AccessKeyID = types.StringValue(spec.AccessKeyId)
+	}
+
+	if overallStatus, err := safe.StateGetByID[*omni.EtcdBackupOverallStatus](ctx, st, omni.EtcdBackupOverallStatusID); err == nil {
+		config.ConfigurationError = types.StringValue(overallStatus.TypedSpec().Value.ConfigurationError)
+	} else if config.ConfigurationError.IsNull() {
+		config.ConfigurationError = types.StringValue("")
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
+
+func (r *omniEtcdBackupS3ConfigResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan, config, state OmniEtcdBackupS3ConfigModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	st := instance.state
+
+	_, err := safe.StateUpdateWithConflicts(
+		ctx,
+		st,
+		omni.NewEtcdBackupS3Conf().Metadata(),
+		func(res *omni.EtcdBackupS3Conf) error {
+			applyEtcdBackupS3Config(res, plan, config)
+
+			return nil
+		},
+	)
+	if err != nil {
+		resp.Diagnostics.AddError("Error updating etcd backup S3 configuration", err.Error())
+		return
+	}
+
+	plan.ID = state.ID
+	plan.CreatedAt = state.CreatedAt
+	readEtcdBackupStoreStatus(ctx, st, &plan, &resp.Diagnostics)
+
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniEtcdBackupS3ConfigResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := instance.state.TeardownAndDestroy(ctx, omni.NewEtcdBackupS3Conf().Metadata())
+	if err != nil && !cosistate.IsNotFoundError(err) {
+		resp.Diagnostics.AddError("Error deleting etcd backup S3 configuration", err.Error())
+		return
+	}
+}
+
+func (r *omniEtcdBackupS3ConfigResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	instanceName, id := r.providerData.splitImportID(req.ID)
+
+	if id != omni.EtcdBackupS3ConfID {
+		resp.Diagnostics.AddError(
+			"Invalid import ID",
+			fmt.Sprintf("The etcd backup S3 configuration can only be imported with the ID %q, optionally prefixed with the name of an Omni instance and a slash.", omni.EtcdBackupS3ConfID),
+		)
+
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), id)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("omni_instance"), instanceName)...)
+}
+
+// applyEtcdBackupS3Config copies the planned configuration to the EtcdBackupS3Conf
+// resource. Write-only credentials are only available in the config.
+func applyEtcdBackupS3Config(res *omni.EtcdBackupS3Conf, plan OmniEtcdBackupS3ConfigModelV0, config OmniEtcdBackupS3ConfigModelV0) {
+	spec := res.TypedSpec().Value
+	spec.Bucket = plan.Bucket.ValueString()
+	spec.Region = plan.Region.ValueString()
+	spec.Endpoint = plan.Endpoint.ValueString()
+	spec.AccessKeyId = plan.AccessKeyID.ValueString()
+	spec.SecretAccessKey = plan.SecretAccessKey.ValueString()
+	spec.SessionToken = plan.SessionToken.ValueString()
+
+	if !config.SecretAccessKeyWO.IsNull() {
+		spec.SecretAccessKey = config.SecretAccessKeyWO.ValueString()
+	}
+
+	if !config.SessionTokenWO.IsNull() {
+		spec.SessionToken = config.SessionTokenWO.ValueString()
+	}
+}
+
+// readEtcdBackupStoreStatus waits for Omni to switch the etcd backup store to S3 and
+// records the configuration error it reports, if any, as a warning.
+func readEtcdBackupStoreStatus(ctx context.Context, st cosistate.State, model *OmniEtcdBackupS3ConfigModelV0, diags *diag.Diagnostics) {
+	model.ConfigurationError = types.StringValue("")
+
+	watchCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	overallStatus, err := safe.StateWatchFor[*omni.EtcdBackupOverallStatus](
+		watchCtx,
+		st,
+		omni.NewEtcdBackupOverallStatus().Metadata(),
+		cosistate.WithEventTypes(cosistate.Created, cosistate.Updated),
+		cosistate.WithCondition(func(r cosiresource.Resource) (bool, error) {
+			status, ok := r.(*omni.EtcdBackupOverallStatus)
+			if !ok {
+				return false, nil
+			}
+
+			return status.TypedSpec().Value.ConfigurationName == "s3", nil
+		}),
+	)
+	if err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("failed to read etcd backup store status: %s", err))
+		return
+	}
+
+	if configurationError := overallStatus.TypedSpec().Value.ConfigurationError; configurationError != "" {
+		model.ConfigurationError = types.StringValue(configurationError)
+		diags.AddWarning("Etcd backup S3 configuration error", fmt.Sprintf("Omni reports an error for the etcd backup S3 configuration: %s", configurationError))
+	}
+}
+
+// etcdBackupS3SettingsChanged reports whether the planned S3 settings differ from the
+// state, including the version of the write-only credentials.
+func etcdBackupS3SettingsChanged(plan, state OmniEtcdBackupS3ConfigModelV0) bool {
+	return !plan.Bucket.Equal(state.Bucket) ||
+		!plan.Region.Equal(state.Region) ||
+		!plan.Endpoint.Equal(state.Endpoint) ||
+		!plan.AccessKeyID.Equal(state.AccessKeyID) ||
+		!plan.SecretAccessKey.Equal(state.SecretAccessKey) ||
+		!plan.SessionToken.Equal(state.SessionToken) ||
+		!plan.CredentialsWOVersion.Equal(state.CredentialsWOVersion)
+}
@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+func TestApplyEtcdBackupS3Config(t *testing.T) {
+	plan := OmniEtcdBackupS3ConfigModelV0{
+		Bucket:          types.StringValue("etcd-backups"),
+		Region:          types.StringValue("eu-central-1"),
+		Endpoint:        types.StringValue("https://minio.example.com:9000"),
+		AccessKeyID:     types.StringValue("AKIA"),
+		SecretAccessKey: types.StringValue("secret"),
+		SessionToken:    types.StringNull(),
+	}
+
+	for _, tc := range []struct {
+		name                 string
+		config               OmniEtcdBackupS3ConfigModelV0
+		expectedSecret       string
+		expectedSessionToken string
+	}{
+		{
+			name:           "credentials in the state",
+			config:         OmniEtcdBackupS3ConfigModelV0{SecretAccessKeyWO: types.StringNull(), SessionTokenWO: types.StringNull()},
+			expectedSecret: "secret",
+		},
+		{
+			name:                 "write-only credentials",
+			config:               OmniEtcdBackupS3ConfigModelV0{SecretAccessKeyWO: types.StringValue("secret-wo"), SessionTokenWO: types.StringValue("token-wo")},
+			expectedSecret:       "secret-wo",
+			expectedSessionToken: "token-wo",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res := omni.NewEtcdBackupS3Conf()
+
+			applyEtcdBackupS3Config(res, plan, tc.config)
+
+			spec := res.TypedSpec().Value
+
+			if spec.Bucket != "etcd-backups" || spec.Region != "eu-central-1" || spec.Endpoint != "https://minio.example.com:9000" || spec.AccessKeyId != "AKIA" {
+				t.Errorf("unexpected settings: %v", spec)
+			}
+
+			if spec.SecretAccessKey != tc.expectedSecret || spec.SessionToken != tc.expectedSessionToken {
+				t.Errorf("expected secret %q and session token %q, got %q and %q", tc.expectedSecret, tc.expectedSessionToken, spec.SecretAccessKey, spec.SessionToken)
+			}
+		})
+	}
+}
+
+func TestEtcdBackupS3ConfigValidateConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		values  map[string]tftypes.Value
+		summary string
+	}{
+		{
+			name: "access key with secret",
+			values: map[string]tftypes.Value{
+				"access_key_id":     tftypes.NewValue(tftypes.String, "AKIA"),
+				"secret_access_key": tftypes.NewValue(tftypes.String, "secret"),
+			},
+		},
+		{
+			name: "access key with write-only secret",
+			values: map[string]tftypes.Value{
+				"access_key_id":        tftypes.NewValue(tftypes.String, "AKIA"),
+				"secret_access_key_wo": tftypes.NewValue(tftypes.String, "secret"),
+			},
+		},
+		{
+			name:   "environment credentials",
+			values: map[string]tftypes.Value{},
+		},
+		{
+			name:    "access key without secret",
+			values:  map[string]tftypes.Value{"access_key_id": tftypes.NewValue(tftypes.String, "AKIA")},
+			summary: "Missing secret access key",
+		},
+		{
+			name:    "secret without access key",
+			values:  map[string]tftypes.Value{"secret_access_key_wo": tftypes.NewValue(tftypes.String, "secret")},
+			summary: "Missing access key ID",
+		},
+		{
+			name: "unknown secret",
+			values: map[string]tftypes.Value{
+				"access_key_id":     tftypes.NewValue(tftypes.String, "AKIA"),
+				"secret_access_key": tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &omniEtcdBackupS3ConfigResource{}
+
+			tc.values["bucket"] = tftypes.NewValue(tftypes.String, "etcd-backups")
+			tc.values["region"] = tftypes.NewValue(tftypes.String, "eu-central-1")
+
+			var resp resource.ValidateConfigResponse
+
+			r.ValidateConfig(t.Context(), resource.ValidateConfigRequest{Config: testResourceConfig(t, r, tc.values)}, &resp)
+
+			switch {
+			case tc.summary == "" && resp.Diagnostics.HasError():
+				t.Errorf("unexpected error: %v", resp.Diagnostics)
+			case tc.summary != "" && (!resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != tc.summary):
+				t.Errorf("expected %q, got %v", tc.summary, resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestEtcdBackupS3SettingsChanged(t *testing.T) {
+	state := OmniEtcdBackupS3ConfigModelV0{
+		Bucket:               types.StringValue("etcd-backups"),
+		Region:               types.StringValue("eu-central-1"),
+		Endpoint:             types.StringNull(),
+		AccessKeyID:          types.StringValue("AKIA"),
+		SecretAccessKey:      types.StringNull(),
+		SessionToken:         types.StringNull(),
+		CredentialsWOVersion: types.Int64Value(1),
+		ConfigurationError:   types.StringValue(""),
+	}
+
+	unchanged := state
+	unchanged.ConfigurationError = types.StringUnknown()
+	unchanged.LastUpdated = types.StringUnknown()
+
+	if etcdBackupS3SettingsChanged(unchanged, state) {
+		t.Error("expected unchanged settings")
+	}
+
+	rotated := state
+	rotated.CredentialsWOVersion = types.Int64Value(2)
+
+	if !etcdBackupS3SettingsChanged(rotated, state) {
+		t.Error("expected a credentials rotation to change the settings")
+	}
+
+	moved := state
+	moved.Bucket = types.StringValue("etcd-backups-2")
+
+	if !etcdBackupS3SettingsChanged(moved, state) {
+		t.Error("expected a new bucket to change the settings")
+	}
+}
@@ -0,0 +1,251 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	cosiresource "github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework-validators/datasourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+var (
+	_ datasource.DataSource                     = &omniEtcdBackupsDataSource{}
+	_ datasource.DataSourceWithConfigValidators = &omniEtcdBackupsDataSource{}
+)
+
+type omniEtcdBackupsDataSource struct {
+	providerData *omniProviderData
+}
+
+type OmniEtcdBackupModel struct {
+	ID        types.String `tfsdk:"id"`
+	Snapshot  types.String `tfsdk:"snapshot"`
+	CreatedAt types.String `tfsdk:"created_at"`
+	Size      types.Int64  `tfsdk:"size"`
+}
+
+type OmniEtcdBackupsModelV0 struct {
+	ID           types.String          `tfsdk:"id"`
+	Cluster      types.String          `tfsdk:"cluster"`
+	ClusterUUID  types.String          `tfsdk:"cluster_uuid"`
+	Backups      []OmniEtcdBackupModel `tfsdk:"backups"`
+	OmniInstance types.String          `tfsdk:"omni_instance"`
+}
+
+func NewOmniEtcdBackupsDataSource() datasource.DataSource {
+	return &omniEtcdBackupsDataSource{}
+}
+
+func (d *omniEtcdBackupsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_etcd_backups"
+}
+
+func (d *omniEtcdBackupsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the etcd backups Omni holds for a cluster.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceDataSourceAttribute(),
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "ID of the data source.",
+			},
+			"cluster": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Name of the cluster to list the backups of.",
+			},
+			"cluster_uuid": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "UUID of the cluster to list the backups of. Use it to find the backups of a cluster which no longer exists.",
+			},
+			"backups": schema.ListNestedAttribute{
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Computed:    true,
+							Description: "ID of the backup.",
+						},
+						"snapshot": schema.StringAttribute{
+							Computed:    true,
+							Description: "Snapshot file name of the backup.",
+						},
+						"created_at": schema.StringAttribute{
+							Computed:    true,
+							Description: "RFC3339 timestamp of the backup creation.",
+						},
+						"size": schema.Int64Attribute{
+							Computed:    true,
+							Description: "Size of the backup in bytes.",
+						},
+					},
+				},
+				Computed:    true,
+				Description: "Backups of the cluster, sorted from the newest to the oldest.",
+			},
+		},
+	}
+}
+
+func (d *omniEtcdBackupsDataSource) ConfigValidators(_ context.Context) []datasource.ConfigValidator {
+	return []datasource.ConfigValidator{
+		datasourcevalidator.ExactlyOneOf(path.MatchRoot("cluster"), path.MatchRoot("cluster_uuid")),
+	}
+}
+
+func (d *omniEtcdBackupsDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni etcd backups data source")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.providerData = providerData
+}
+
+func (d *omniEtcdBackupsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if d.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	instance, diags := d.providerData.instanceOf(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniEtcdBackupsModelV0
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	st := instance.cachedState
+
+	clusterUUID, backups, err := listClusterEtcdBackups(ctx, st, config.Cluster.ValueString(), config.ClusterUUID.ValueString())
+	if err != nil {
+		if cosistate.IsNotFoundError(err) {
+			resp.Diagnostics.AddAttributeError(path.Root("cluster"), "Cluster not found", fmt.Sprintf("Cluster %q does not exist in Omni.", config.Cluster.ValueString()))
+			return
+		}
+
+		resp.Diagnostics.AddError("failed to get omni etcd backups", err.Error())
+		return
+	}
+
+	config.ClusterUUID = types.StringValue(clusterUUID)
+
+	tflog.Debug(ctx, fmt.Sprintf("number of etcd backups found: %d", len(backups)))
+
+	config.Backups = make([]OmniEtcdBackupModel, 0, len(backups))
+	for _, backup := range backups {
+		config.Backups = append(config.Backups, etcdBackupToModel(backup))
+
+		if config.Cluster.IsNull() {
+			if cluster, ok := backup.Metadata().Labels().Get(omni.LabelCluster); ok {
+				config.Cluster = types.StringValue(cluster)
+			}
+		}
+	}
+
+	config.ID = config.ClusterUUID
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
+
+// listClusterEtcdBackups returns the UUID and the etcd backups of a cluster. The cluster
+// is resolved to its UUID unless the UUID is given, and the backups are selected by the
+// UUID, as Omni does, so that the backups of a deleted cluster with the same name are
+// not mixed in.
+func listClusterEtcdBackups(ctx context.Context, st cosistate.State, cluster, clusterUUID string) (string, []*omni.EtcdBackup, error) {
+	if clusterUUID == "" {
+		resolved, err := safe.StateGetByID[*omni.ClusterUUID](ctx, st, cluster)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clusterUUID = resolved.TypedSpec().Value.Uuid
+	}
+
+	backups, err := listEtcdBackups(ctx, st, cosiresource.LabelEqual(omni.LabelClusterUUID, clusterUUID))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return clusterUUID, backups, nil
+}
+
+// listEtcdBackups returns the etcd backups matching the label query, sorted from the
+// newest to the oldest.
+func listEtcdBackups(ctx context.Context, st cosistate.State, labelQuery cosiresource.LabelQueryOption) ([]*omni.EtcdBackup, error) {
+	list, err := safe.StateListAll[*omni.EtcdBackup](ctx, st, cosistate.WithLabelQuery(labelQuery))
+	if err != nil {
+		return nil, err
+	}
+
+	list.SortFunc(func(a, b *omni.EtcdBackup) int {
+		return b.TypedSpec().Value.CreatedAt.AsTime().Compare(a.TypedSpec().Value.CreatedAt.AsTime())
+	})
+
+	backups := make([]*omni.EtcdBackup, 0, list.Len())
+	list.ForEach(func(backup *omni.EtcdBackup) {
+		backups = append(backups, backup)
+	})
+
+	return backups, nil
+}
+
+func etcdBackupToModel(backup *omni.EtcdBackup) OmniEtcdBackupModel {
+	spec := backup.TypedSpec().Value
+
+	return OmniEtcdBackupModel{
+		ID:        types.StringValue(backup.Metadata().ID()),
+		Snapshot:  types.StringValue(spec.Snapshot),
+		CreatedAt: types.StringValue(spec.CreatedAt.AsTime().Format(time.RFC3339)),
+		Size:      types.Int64Value(int64(spec.Size)),
+	}
+}
+
+// checkEtcdBackupSnapshot verifies that Omni holds the snapshot among the etcd backups
+// of the cluster with the given UUID.
+func checkEtcdBackupSnapshot(ctx context.Context, st cosistate.State, clusterUUID string, snapshot string) error {
+	backups, err := listEtcdBackups(ctx, st, cosiresource.LabelEqual(omni.LabelClusterUUID, clusterUUID))
+	if err != nil {
+		return fmt.Errorf("failed to list etcd backups of cluster %q: %w", clusterUUID, err)
+	}
+
+	if len(backups) == 0 {
+		return fmt.Errorf("Omni holds no etcd backups of cluster %q", clusterUUID)
+	}
+
+	snapshots := make([]string, 0, len(backups))
+	for _, backup := range backups {
+		if backup.TypedSpec().Value.Snapshot == snapshot {
+			return nil
+		}
+
+		snapshots = append(snapshots, backup.TypedSpec().Value.Snapshot)
+	}
+
+	return fmt.Errorf("snapshot %q is not among the etcd backups of cluster %q, available snapshots: %s", snapshot, clusterUUID, strings.Join(snapshots, ", "))
+}
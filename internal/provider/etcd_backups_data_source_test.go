@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	cosiresource "github.com/cosi-project/runtime/pkg/resource"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func testEtcdBackup(cluster, clusterUUID, snapshot string, createdAt time.Time) *omni.EtcdBackup {
+	backup := omni.NewEtcdBackup(cluster, createdAt)
+	backup.Metadata().Labels().Set(omni.LabelCluster, cluster)
+	backup.Metadata().Labels().Set(omni.LabelClusterUUID, clusterUUID)
+	backup.TypedSpec().Value.Snapshot = snapshot
+	backup.TypedSpec().Value.CreatedAt = timestamppb.New(createdAt)
+	backup.TypedSpec().Value.Size = 1024
+
+	return backup
+}
+
+func TestListClusterEtcdBackups(t *testing.T) {
+	ctx := t.Context()
+	st := cosistate.WrapCore(namespaced.NewState(inmem.Build))
+
+	clusterUUID := omni.NewClusterUUID("production")
+	clusterUUID.TypedSpec().Value.Uuid = "uuid-current"
+
+	createdAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, res := range []cosiresource.Resource{
+		clusterUUID,
+		// a deleted cluster with the same name
+		testEtcdBackup("production", "uuid-deleted", "deleted.snapshot", createdAt),
+		testEtcdBackup("production", "uuid-current", "older.snapshot", createdAt.Add(time.Hour)),
+		testEtcdBackup("production", "uuid-current", "newer.snapshot", createdAt.Add(2*time.Hour)),
+		testEtcdBackup("staging", "uuid-staging", "staging.snapshot", createdAt),
+	} {
+		if err := st.Create(ctx, res); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name         string
+		cluster      string
+		clusterUUID  string
+		expectedUUID string
+		expected     []string
+	}{
+		{name: "cluster", cluster: "production", expectedUUID: "uuid-current", expected: []string{"newer.snapshot", "older.snapshot"}},
+		{name: "cluster uuid", clusterUUID: "uuid-deleted", expectedUUID: "uuid-deleted", expected: []string{"deleted.snapshot"}},
+		{name: "no backups", clusterUUID: "uuid-missing", expectedUUID: "uuid-missing", expected: []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolvedUUID, backups, err := listClusterEtcdBackups(ctx, st, tc.cluster, tc.clusterUUID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			snapshots := make([]string, 0, len(backups))
+			for _, backup := range backups {
+				snapshots = append(snapshots, etcdBackupToModel(backup).Snapshot.ValueString())
+			}
+
+			if resolvedUUID != tc.expectedUUID || !slices.Equal(snapshots, tc.expected) {
+				t.Errorf("expected %q with %v, got %q with %v", tc.expectedUUID, tc.expected, resolvedUUID, snapshots)
+			}
+		})
+	}
+
+	if _, _, err := listClusterEtcdBackups(ctx, st, "missing", ""); !cosistate.IsNotFoundError(err) {
+		t.Errorf("expected a not found error for a missing cluster, got %v", err)
+	}
+
+	if err := checkEtcdBackupSnapshot(ctx, st, "uuid-current", "older.snapshot"); err != nil {
+		t.Errorf("unexpected error for an existing snapshot: %s", err)
+	}
+
+	if err := checkEtcdBackupSnapshot(ctx, st, "uuid-current", "deleted.snapshot"); err == nil {
+		t.Error("expected an error for a snapshot of another cluster")
+	}
+}
+
+func TestEtcdBackupToModel(t *testing.T) {
+	createdAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	model := etcdBackupToModel(testEtcdBackup("production", "uuid-current", "backup.snapshot", createdAt))
+
+	if model.ID.ValueString() != "production-1748779200" ||
+		model.Snapshot.ValueString() != "backup.snapshot" ||
+		model.CreatedAt.ValueString() != "2025-06-01T12:00:00Z" ||
+		model.Size.ValueInt64() != 1024 {
+		t.Errorf("unexpected model: %+v", model)
+	}
+}
@@ -0,0 +1,269 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"terraform-provider-omni/internal/models"
+	"time"
+
+	cosiresource "github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	_ resource.Resource              = &omniEtcdManualBackupResource{}
+	_ resource.ResourceWithConfigure = &omniEtcdManualBackupResource{}
+)
+
+// etcdManualBackupTimeout is how long to wait for Omni to finish a manual backup.
+const etcdManualBackupTimeout = 10 * time.Minute
+
+type omniEtcdManualBackupResource struct {
+	providerData *omniProviderData
+}
+
+type OmniEtcdManualBackupModelV0 struct {
+	ID           types.String  `tfsdk:"id"`
+	CreatedAt    types.String  `tfsdk:"created_at"`
+	LastUpdated  types.String  `tfsdk:"last_updated"`
+	Cluster      types.String  `tfsdk:"cluster"`
+	Triggers     models.Labels `tfsdk:"triggers"`
+	Snapshot     types.String  `tfsdk:"snapshot"`
+	BackupTime   types.String  `tfsdk:"backup_time"`
+	Size         types.Int64   `tfsdk:"size"`
+	OmniInstance types.String  `tfsdk:"omni_instance"`
+}
+
+func NewOmniEtcdManualBackupResource() resource.Resource {
+	return &omniEtcdManualBackupResource{}
+}
+
+func (r *omniEtcdManualBackupResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_etcd_manual_backup"
+}
+
+func (r *omniEtcdManualBackupResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni etcd manual backup resource")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.providerData = providerData
+}
+
+func (r *omniEtcdManualBackupResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Takes a manual etcd backup of an Omni cluster and waits for it to complete. " +
+			"Destroying the resource only removes it from the Terraform state, the backup is kept until Omni's retention removes it.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
+			"id": schema.StringAttribute{
+				Description: "ID of the backup.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"last_updated": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"cluster": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the cluster to back up.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"triggers": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Arbitrary values which take a new backup when changed, e.g. a hash of the cluster template.",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"snapshot": schema.StringAttribute{
+				Computed:    true,
+				Description: "Snapshot file name of the backup.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"backup_time": schema.StringAttribute{
+				Computed:    true,
+				Description: "RFC3339 timestamp of the backup creation.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"size": schema.Int64Attribute{
+				Computed:    true,
+				Description: "Size of the backup in bytes.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *omniEtcdManualBackupResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniEtcdManualBackupModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	st := instance.state
+	clusterName := plan.Cluster.ValueString()
+
+	// Omni compares the request time against the last backup with second precision
+	backupAt := time.Now().Truncate(time.Second)
+
+	manualBackup := omni.NewEtcdManualBackup(clusterName)
+	manualBackup.TypedSpec().Value.BackupAt = timestamppb.New(backupAt)
+
+	err := st.Create(ctx, manualBackup)
+	if cosistate.IsConflictError(err) {
+		_, err = safe.StateUpdateWithConflicts(ctx, st, manualBackup.Metadata(), func(res *omni.EtcdManualBackup) error {
+			res.TypedSpec().Value.BackupAt = timestamppb.New(backupAt)
+
+			return nil
+		})
+	}
+
+	if err != nil {
+		resp.Diagnostics.AddError("Error requesting etcd backup", err.Error())
+		return
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("requested etcd backup of cluster %q", clusterName))
+
+	if err := waitForEtcdBackup(ctx, st, clusterName, backupAt); err != nil {
+		resp.Diagnostics.AddError("Error waiting for etcd backup", err.Error())
+		return
+	}
+
+	backups, err := listEtcdBackups(ctx, st, cosiresource.LabelEqual(omni.LabelCluster, clusterName))
+	if err != nil {
+		resp.Diagnostics.AddError("failed to get omni etcd backups", err.Error())
+		return
+	}
+
+	if len(backups) == 0 || backups[0].TypedSpec().Value.CreatedAt.AsTime().Before(backupAt) {
+		resp.Diagnostics.AddError("Error reading etcd backup", fmt.Sprintf("Omni reported a successful backup of cluster %q, but the backup is not listed.", clusterName))
+		return
+	}
+
+	backup := etcdBackupToModel(backups[0])
+	plan.ID = backup.ID
+	plan.Snapshot = backup.Snapshot
+	plan.BackupTime = backup.CreatedAt
+	plan.Size = backup.Size
+
+	timeNow := types.StringValue(time.Now().Format(time.RFC850))
+	plan.CreatedAt = timeNow
+	plan.LastUpdated = timeNow
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// Read keeps the backup in the state even if Omni's retention removed it, so that
+// expired backups don't trigger new ones.
+func (r *omniEtcdManualBackupResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state OmniEtcdManualBackupModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// Update is never called with changes, as every configurable attribute requires replacement.
+func (r *omniEtcdManualBackupResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan OmniEtcdManualBackupModelV0
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniEtcdManualBackupResource) Delete(_ context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+}
+
+// waitForEtcdBackup waits until Omni reports the result of a backup attempted after
+// backupAt.
+func waitForEtcdBackup(ctx context.Context, st cosistate.State, clusterName string, backupAt time.Time) error {
+	watchCtx, cancel := context.WithTimeout(ctx, etcdManualBackupTimeout)
+	defer cancel()
+
+	status, err := safe.StateWatchFor[*omni.EtcdBackupStatus](
+		watchCtx,
+		st,
+		omni.NewEtcdBackupStatus(clusterName).Metadata(),
+		cosistate.WithEventTypes(cosistate.Created, cosistate.Updated),
+		cosistate.WithCondition(func(r cosiresource.Resource) (bool, error) {
+			status, ok := r.(*omni.EtcdBackupStatus)
+			if !ok {
+				return false, nil
+			}
+
+			spec := status.TypedSpec().Value
+			if spec.LastBackupAttempt == nil || spec.LastBackupAttempt.AsTime().Before(backupAt) {
+				return false, nil
+			}
+
+			return spec.Status == specs.EtcdBackupStatusSpec_Ok || spec.Status == specs.EtcdBackupStatusSpec_Error, nil
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	if spec := status.TypedSpec().Value; spec.Status == specs.EtcdBackupStatusSpec_Error {
+		return fmt.Errorf("etcd backup of cluster %q failed: %s", clusterName, spec.Error)
+	}
+
+	return nil
+}
@@ -0,0 +1,254 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/auth"
+)
+
+var (
+	_ resource.Resource                = &omniIdentityResource{}
+	_ resource.ResourceWithConfigure   = &omniIdentityResource{}
+	_ resource.ResourceWithImportState = &omniIdentityResource{}
+)
+
+type omniIdentityResource struct {
+	providerData *omniProviderData
+}
+
+type OmniIdentityModelV0 struct {
+	ID           types.String `tfsdk:"id"`
+	CreatedAt    types.String `tfsdk:"created_at"`
+	LastUpdated  types.String `tfsdk:"last_updated"`
+	Email        types.String `tfsdk:"email"`
+	UserID       types.String `tfsdk:"user_id"`
+	OmniInstance types.String `tfsdk:"omni_instance"`
+}
+
+func NewOmniIdentityResource() resource.Resource {
+	return &omniIdentityResource{}
+}
+
+func (r *omniIdentityResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_identity"
+}
+
+func (r *omniIdentityResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni identity resource")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.providerData = providerData
+}
+
+func (r *omniIdentityResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Maps an identity, the email a user signs in to Omni with, to an Omni user.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
+			"id": schema.StringAttribute{
+				Description: "ID of the identity, the email.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"last_updated": schema.StringAttribute{
+				Computed: true,
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"email": schema.StringAttribute{
+				Required:    true,
+				Description: "Email the user signs in with.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"user_id": schema.StringAttribute{
+				Required:    true,
+				Description: "ID of the `omni_user` the identity belongs to.",
+			},
+		},
+	}
+}
+
+func (r *omniIdentityResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniIdentityModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	st := instance.state
+	email := plan.Email.ValueString()
+
+	_, err := safe.StateGetByID[*auth.Identity](ctx, st, email)
+	if err == nil {
+		resp.Diagnostics.AddAttributeError(path.Root("email"), "Identity already exists", fmt.Sprintf("Identity %q already exists in Omni, import it to manage it with Terraform.", email))
+		return
+	}
+
+	if !cosistate.IsNotFoundError(err) {
+		resp.Diagnostics.AddError("Error reading identity", err.Error())
+		return
+	}
+
+	identity := auth.NewIdentity(resources.DefaultNamespace, email)
+	setIdentityUser(identity, plan.UserID.ValueString())
+
+	if err := st.Create(ctx, identity); err != nil {
+		resp.Diagnostics.AddError("Error creating identity", err.Error())
+		return
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("created identity %q of user %q", email, plan.UserID.ValueString()))
+
+	plan.ID = plan.Email
+
+	timeNow := types.StringValue(time.Now().Format(time.RFC850))
+	plan.CreatedAt = timeNow
+	plan.LastUpdated = timeNow
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniIdentityResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniIdentityModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	identity, err := safe.StateGetByID[*auth.Identity](ctx, instance.state, config.ID.ValueString())
+	if err != nil {
+		if cosistate.IsNotFoundError(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.AddError("Error reading identity", err.Error())
+		return
+	}
+
+	if _, ok := identity.Metadata().Labels().Get(auth.LabelIdentityTypeServiceAccount); ok {
+		resp.Diagnostics.AddError("Identity is a service account", fmt.Sprintf("Identity %q belongs to a service account, which can't be managed as an identity.", config.ID.ValueString()))
+		return
+	}
+
+	config.Email = config.ID
+	config.UserID = types.StringValue(identity.TypedSpec().Value.UserId)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
+
+func (r *omniIdentityResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniIdentityModelV0
+	var state OmniIdentityModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, err := safe.StateUpdateWithConflicts(ctx, instance.state, auth.NewIdentity(resources.DefaultNamespace, state.ID.ValueString()).Metadata(), func(identity *auth.Identity) error {
+		setIdentityUser(identity, plan.UserID.ValueString())
+
+		return nil
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Error updating identity", err.Error())
+		return
+	}
+
+	plan.ID = state.ID
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniIdentityResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state OmniIdentityModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := instance.state.TeardownAndDestroy(ctx, auth.NewIdentity(resources.DefaultNamespace, state.ID.ValueString()).Metadata())
+	if err != nil && !cosistate.IsNotFoundError(err) {
+		resp.Diagnostics.AddError("Error deleting identity", err.Error())
+		return
+	}
+}
+
+func (r *omniIdentityResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importStateWithInstance(ctx, r.providerData, req, resp)
+}
+
+// setIdentityUser links an identity to a user, the same way omnictl does.
+func setIdentityUser(identity *auth.Identity, userID string) {
+	identity.Metadata().Labels().Set(auth.LabelIdentityUserID, userID)
+	identity.TypedSpec().Value.UserId = userID
+}
@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/url"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/constants"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// getImageFactoryBaseURL returns the image factory Omni is configured with, falling back
+// to the public image factory when Omni doesn't report one.
+func getImageFactoryBaseURL(ctx context.Context, st state.State) (string, error) {
+	featuresConfig, err := safe.StateGetByID[*omni.FeaturesConfig](ctx, st, omni.FeaturesConfigID)
+	if err != nil {
+		if state.IsNotFoundError(err) {
+			return constants.ImageFactoryBaseURL, nil
+		}
+
+		return "", err
+	}
+
+	if baseURL := featuresConfig.TypedSpec().Value.ImageFactoryBaseUrl; baseURL != "" {
+		return baseURL, nil
+	}
+
+	return constants.ImageFactoryBaseURL, nil
+}
+
+// talosVersionTag returns the Talos version in the "vX.Y.Z" form used by image factory
+// URLs and installer image tags.
+func talosVersionTag(talosVersion string) string {
+	return "v" + strings.TrimPrefix(talosVersion, "v")
+}
+
+// imageFactoryInstallerImage returns the installer image reference for a schematic.
+func imageFactoryInstallerImage(baseURL string, schematicID string, talosVersion string, secureBoot bool) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image factory URL %q: %w", baseURL, err)
+	}
+
+	repository := "installer"
+	if secureBoot {
+		repository = "installer-secureboot"
+	}
+
+	return fmt.Sprintf("%s/%s/%s:%s", u.Host, repository, schematicID, talosVersionTag(talosVersion)), nil
+}
+
+// imageFactoryDownloadURL returns the image factory URL of the installation media built
+// from a schematic.
+func imageFactoryDownloadURL(baseURL string, schematicID string, talosVersion string, media *specs.InstallationMediaSpec, secureBoot bool) (string, error) {
+	return url.JoinPath(baseURL, "image", schematicID, talosVersionTag(talosVersion), media.GenerateFilename(false, secureBoot, true))
+}
+
+// installationMediaSupports reports whether the installation media can be generated
+// for the given Talos version and secure boot setting.
+func installationMediaSupports(media *specs.InstallationMediaSpec, talosVersion string, secureBoot bool) (bool, error) {
+	if secureBoot && media.NoSecureBoot {
+		return false, nil
+	}
+
+	if media.MinTalosVersion == "" {
+		return true, nil
+	}
+
+	minVersion, err := semver.ParseTolerant(media.MinTalosVersion)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse min Talos version %q of installation media %q: %w", media.MinTalosVersion, media.Name, err)
+	}
+
+	requestedVersion, err := semver.ParseTolerant(talosVersion)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse Talos version %q: %w", talosVersion, err)
+	}
+
+	requestedVersion.Pre = nil
+
+	return requestedVersion.GTE(minVersion), nil
+}
+
+// schematicDownloadURLs returns the image factory URLs of the installation media a
+// schematic can be downloaded as, keyed by installation media ID. Media for another
+// overlay need the schematic of that overlay, so only media with the overlay of the
+// schematic are included.
+func schematicDownloadURLs(baseURL string, schematic *OmniSchematicModelV0, mediaList iter.Seq[*omni.InstallationMedia]) (map[string]string, error) {
+	downloadURLs := map[string]string{}
+
+	for media := range mediaList {
+		spec := media.TypedSpec().Value
+		if spec.Architecture != schematic.Architecture.ValueString() || spec.Overlay != schematic.Overlay.ValueString() {
+			continue
+		}
+
+		supported, err := installationMediaSupports(spec, schematic.TalosVersion.ValueString(), schematic.SecureBoot.ValueBool())
+		if err != nil {
+			return nil, err
+		}
+
+		if !supported {
+			continue
+		}
+
+		downloadURL, err := imageFactoryDownloadURL(baseURL, schematic.ID.ValueString(), schematic.TalosVersion.ValueString(), spec, schematic.SecureBoot.ValueBool())
+		if err != nil {
+			return nil, fmt.Errorf("failed to build the download URL of installation media %q: %w", media.Metadata().ID(), err)
+		}
+
+		downloadURLs[media.Metadata().ID()] = downloadURL
+	}
+
+	return downloadURLs, nil
+}
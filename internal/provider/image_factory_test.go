@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+func TestImageFactoryURLs(t *testing.T) {
+	const schematicID = "376567988ad370138ad8b2698212367b8edcb69b5fd68c80be1f2ec7d603b4ba"
+
+	installer, err := imageFactoryInstallerImage("https://factory.talos.dev", schematicID, "1.11.2", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "factory.talos.dev/installer/" + schematicID + ":v1.11.2"; installer != expected {
+		t.Errorf("expected installer image %q, got %q", expected, installer)
+	}
+
+	installer, err = imageFactoryInstallerImage("https://factory.internal.example.com:8443", schematicID, "v1.11.2", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "factory.internal.example.com:8443/installer-secureboot/" + schematicID + ":v1.11.2"; installer != expected {
+		t.Errorf("expected installer image %q, got %q", expected, installer)
+	}
+
+	for _, tc := range []struct {
+		media      *specs.InstallationMediaSpec
+		secureBoot bool
+		expected   string
+	}{
+		{
+			media:    &specs.InstallationMediaSpec{Profile: "iso", Architecture: "amd64", Extension: "iso"},
+			expected: "https://factory.talos.dev/image/" + schematicID + "/v1.11.2/iso-amd64.iso",
+		},
+		{
+			media:      &specs.InstallationMediaSpec{Profile: "iso", Architecture: "amd64", Extension: "iso"},
+			secureBoot: true,
+			expected:   "https://factory.talos.dev/image/" + schematicID + "/v1.11.2/iso-amd64-secureboot.iso",
+		},
+		{
+			media:    &specs.InstallationMediaSpec{Profile: "rpi_generic", Architecture: "arm64", Extension: "raw.xz", Overlay: "rpi_generic"},
+			expected: "https://factory.talos.dev/image/" + schematicID + "/v1.11.2/metal-arm64.raw.xz",
+		},
+	} {
+		actual, err := imageFactoryDownloadURL("https://factory.talos.dev", schematicID, "1.11.2", tc.media, tc.secureBoot)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual != tc.expected {
+			t.Errorf("expected download URL %q, got %q", tc.expected, actual)
+		}
+	}
+}
+
+func TestInstallationMediaSupports(t *testing.T) {
+	media := &specs.InstallationMediaSpec{Name: "Turing RK1", MinTalosVersion: "1.9.0", NoSecureBoot: true}
+
+	for _, tc := range []struct {
+		talosVersion string
+		secureBoot   bool
+		expected     bool
+	}{
+		{talosVersion: "1.9.0", expected: true},
+		{talosVersion: "v1.10.0-beta.0", expected: true},
+		{talosVersion: "1.8.3", expected: false},
+		{talosVersion: "1.11.2", secureBoot: true, expected: false},
+	} {
+		actual, err := installationMediaSupports(media, tc.talosVersion, tc.secureBoot)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual != tc.expected {
+			t.Errorf("talos version %s, secure boot %t: expected %t, got %t", tc.talosVersion, tc.secureBoot, tc.expected, actual)
+		}
+	}
+}
+
+func TestSchematicDownloadURLs(t *testing.T) {
+	const schematicID = "376567988ad370138ad8b2698212367b8edcb69b5fd68c80be1f2ec7d603b4ba"
+
+	installationMedia := func(id string, spec *specs.InstallationMediaSpec) *omni.InstallationMedia {
+		media := omni.NewInstallationMedia(resources.EphemeralNamespace, id)
+		media.TypedSpec().Value = spec
+
+		return media
+	}
+
+	mediaList := []*omni.InstallationMedia{
+		installationMedia("iso-arm64", &specs.InstallationMediaSpec{Profile: "iso", Architecture: "arm64", Extension: "iso"}),
+		installationMedia("iso-amd64", &specs.InstallationMediaSpec{Profile: "iso", Architecture: "amd64", Extension: "iso"}),
+		installationMedia("rpi_generic", &specs.InstallationMediaSpec{Profile: "rpi_generic", Architecture: "arm64", Extension: "raw.xz", Overlay: "rpi_generic"}),
+		installationMedia("turing_rk1", &specs.InstallationMediaSpec{Profile: "turing_rk1", Architecture: "arm64", Extension: "raw.xz", Overlay: "turingrk1"}),
+	}
+
+	for _, tc := range []struct {
+		name     string
+		overlay  types.String
+		expected []string
+	}{
+		{name: "no overlay", overlay: types.StringNull(), expected: []string{"iso-arm64"}},
+		{name: "overlay", overlay: types.StringValue("rpi_generic"), expected: []string{"rpi_generic"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			downloadURLs, err := schematicDownloadURLs("https://factory.talos.dev", &OmniSchematicModelV0{
+				ID:           types.StringValue(schematicID),
+				TalosVersion: types.StringValue("1.11.2"),
+				Architecture: types.StringValue("arm64"),
+				SecureBoot:   types.BoolValue(false),
+				Overlay:      tc.overlay,
+			}, slices.Values(mediaList))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ids := slices.Sorted(maps.Keys(downloadURLs)); !slices.Equal(ids, tc.expected) {
+				t.Errorf("expected download URLs for %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"terraform-provider-omni/internal/models"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/dustin/go-humanize"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+type blockDevice = specs.MachineStatusSpec_HardwareStatus_BlockDevice
+
+// resolveInstallDisk returns the path of the only disk of the machine matching the selector.
+func resolveInstallDisk(ctx context.Context, st cosistate.State, machineID string, selector *models.InstallDiskSelector) (string, error) {
+	machineStatus, err := safe.StateGet[*omni.MachineStatus](ctx, st, omni.NewMachineStatus(resources.DefaultNamespace, machineID).Metadata())
+	if err != nil {
+		if cosistate.IsNotFoundError(err) {
+			return "", fmt.Errorf("machine %q is not registered in Omni", machineID)
+		}
+
+		return "", fmt.Errorf("failed to get the status of machine %q: %w", machineID, err)
+	}
+
+	hardware := machineStatus.TypedSpec().Value.GetHardware()
+	if hardware == nil {
+		return "", fmt.Errorf("machine %q has not reported its hardware to Omni yet", machineID)
+	}
+
+	disks, err := matchInstallDisks(selector, hardware.GetBlockdevices())
+	if err != nil {
+		return "", err
+	}
+
+	switch len(disks) {
+	case 0:
+		return "", fmt.Errorf("no disk of machine %q matches the disk selector, the machine has: %s", machineID, describeBlockDevices(hardware.GetBlockdevices()))
+	case 1:
+		return blockDevicePath(disks[0]), nil
+	default:
+		return "", fmt.Errorf("%d disks of machine %q match the disk selector: %s", len(disks), machineID, describeBlockDevices(disks))
+	}
+}
+
+// matchInstallDisks returns the writable block devices matching all criteria of the selector.
+// Model and bus path are matched as glob patterns, the disk type case-insensitively.
+func matchInstallDisks(selector *models.InstallDiskSelector, devices []*blockDevice) ([]*blockDevice, error) {
+	minSize, err := parseDiskSize(selector.MinSize.ValueString())
+	if err != nil {
+		return nil, err
+	}
+
+	maxSize, err := parseDiskSize(selector.MaxSize.ValueString())
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*blockDevice
+
+	for _, device := range devices {
+		if device.Readonly {
+			continue
+		}
+
+		if (minSize != 0 && device.Size < minSize) || (maxSize != 0 && device.Size > maxSize) {
+			continue
+		}
+
+		if !selector.Model.IsNull() && !matchDiskPattern(selector.Model.ValueString(), device.Model) {
+			continue
+		}
+
+		if !selector.BusPath.IsNull() && !matchDiskPattern(selector.BusPath.ValueString(), device.BusPath) {
+			continue
+		}
+
+		if !selector.Serial.IsNull() && selector.Serial.ValueString() != device.Serial {
+			continue
+		}
+
+		if !selector.WWID.IsNull() && selector.WWID.ValueString() != device.Wwid {
+			continue
+		}
+
+		if !selector.Type.IsNull() && !strings.EqualFold(selector.Type.ValueString(), device.Type) {
+			continue
+		}
+
+		matches = append(matches, device)
+	}
+
+	return matches, nil
+}
+
+// parseDiskSize parses human readable sizes such as "100GB" or "1.5TiB". An empty size is 0.
+func parseDiskSize(size string) (uint64, error) {
+	if size == "" {
+		return 0, nil
+	}
+
+	bytes, err := humanize.ParseBytes(size)
+	if err != nil {
+		return 0, fmt.Errorf("invalid disk size %q: %w", size, err)
+	}
+
+	return bytes, nil
+}
+
+func matchDiskPattern(pattern string, value string) bool {
+	matched, err := path.Match(pattern, value)
+
+	return err == nil && matched
+}
+
+func blockDevicePath(device *blockDevice) string {
+	if strings.HasPrefix(device.LinuxName, "/dev/") {
+		return device.LinuxName
+	}
+
+	return "/dev/" + strings.TrimPrefix(device.LinuxName, "/")
+}
+
+func describeBlockDevices(devices []*blockDevice) string {
+	descriptions := make([]string, 0, len(devices))
+
+	for _, device := range devices {
+		details := []string{humanize.Bytes(device.Size)}
+
+		for _, detail := range []struct {
+			name  string
+			value string
+		}{
+			{name: "type", value: device.Type},
+			{name: "model", value: device.Model},
+			{name: "serial", value: device.Serial},
+			{name: "bus path", value: device.BusPath},
+			{name: "wwid", value: device.Wwid},
+		} {
+			if detail.value != "" {
+				details = append(details, detail.name+" "+detail.value)
+			}
+		}
+
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s)", blockDevicePath(device), strings.Join(details, ", ")))
+	}
+
+	if len(descriptions) == 0 {
+		return "no disks"
+	}
+
+	return strings.Join(descriptions, "; ")
+}
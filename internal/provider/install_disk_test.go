@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"slices"
+	"terraform-provider-omni/internal/models"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/siderolabs/gen/xslices"
+)
+
+func TestMatchInstallDisks(t *testing.T) {
+	devices := []*blockDevice{
+		{LinuxName: "/dev/sda", Size: 480 * 1000 * 1000 * 1000, Model: "SAMSUNG MZ7LH480", Serial: "S45PNA0M", Type: "SSD", BusPath: "/pci0000:00/0000:00:17.0/ata1", Wwid: "naa.5002538e"},
+		{LinuxName: "/dev/sdb", Size: 4 * 1000 * 1000 * 1000 * 1000, Model: "ST4000NM0035", Serial: "ZC1B2X3Y", Type: "HDD", BusPath: "/pci0000:00/0000:00:17.0/ata2"},
+		{LinuxName: "/dev/nvme0n1", Size: 960 * 1000 * 1000 * 1000, Model: "SAMSUNG MZQL2960", Serial: "S64FNE0R", Type: "NVME", BusPath: "/pci0000:00/0000:00:1d.0"},
+		{LinuxName: "/dev/sr0", Size: 1024, Type: "CD", Readonly: true},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		selector models.InstallDiskSelector
+		expected []string
+	}{
+		{name: "empty", expected: []string{"/dev/sda", "/dev/sdb", "/dev/nvme0n1"}},
+		{name: "size range", selector: models.InstallDiskSelector{MinSize: types.StringValue("400GB"), MaxSize: types.StringValue("1TB")}, expected: []string{"/dev/sda", "/dev/nvme0n1"}},
+		{name: "model glob", selector: models.InstallDiskSelector{Model: types.StringValue("SAMSUNG*")}, expected: []string{"/dev/sda", "/dev/nvme0n1"}},
+		{name: "type", selector: models.InstallDiskSelector{Type: types.StringValue("nvme")}, expected: []string{"/dev/nvme0n1"}},
+		{name: "serial", selector: models.InstallDiskSelector{Serial: types.StringValue("ZC1B2X3Y")}, expected: []string{"/dev/sdb"}},
+		{name: "wwid", selector: models.InstallDiskSelector{WWID: types.StringValue("naa.5002538e")}, expected: []string{"/dev/sda"}},
+		{name: "bus path glob", selector: models.InstallDiskSelector{BusPath: types.StringValue("/pci0000:00/0000:00:17.0/*")}, expected: []string{"/dev/sda", "/dev/sdb"}},
+		{name: "no match", selector: models.InstallDiskSelector{Type: types.StringValue("SSD"), MinSize: types.StringValue("1TB")}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			matches, err := matchInstallDisks(&tc.selector, devices)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual := xslices.Map(matches, blockDevicePath); !slices.Equal(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+
+	if _, err := matchInstallDisks(&models.InstallDiskSelector{MinSize: types.StringValue("large")}, devices); err == nil {
+		t.Error("expected an error for an invalid size")
+	}
+}
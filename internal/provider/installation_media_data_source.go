@@ -0,0 +1,273 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/api/omni/management"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+var _ datasource.DataSource = &omniInstallationMediaDataSource{}
+
+type omniInstallationMediaDataSource struct {
+	providerData *omniProviderData
+}
+
+type OmniInstallationMediaItemModel struct {
+	ID           types.String `tfsdk:"id"`
+	Name         types.String `tfsdk:"name"`
+	Architecture types.String `tfsdk:"architecture"`
+	Profile      types.String `tfsdk:"profile"`
+	Overlay      types.String `tfsdk:"overlay"`
+	SchematicID  types.String `tfsdk:"schematic_id"`
+	FileName     types.String `tfsdk:"file_name"`
+	DownloadURL  types.String `tfsdk:"download_url"`
+}
+
+type OmniInstallationMediaModelV0 struct {
+	ID           types.String                     `tfsdk:"id"`
+	TalosVersion types.String                     `tfsdk:"talos_version"`
+	Platform     types.String                     `tfsdk:"platform"`
+	Architecture types.String                     `tfsdk:"architecture"`
+	SecureBoot   types.Bool                       `tfsdk:"secure_boot"`
+	SchematicID  types.String                     `tfsdk:"schematic_id"`
+	Media        []OmniInstallationMediaItemModel `tfsdk:"media"`
+	OmniInstance types.String                     `tfsdk:"omni_instance"`
+}
+
+func NewOmniInstallationMediaDataSource() datasource.DataSource {
+	return &omniInstallationMediaDataSource{}
+}
+
+func (d *omniInstallationMediaDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_installation_media"
+}
+
+func (d *omniInstallationMediaDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Provides installation media download URLs for the installation media known to Omni.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceDataSourceAttribute(),
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "ID of the data source.",
+			},
+			"talos_version": schema.StringAttribute{
+				Required:    true,
+				Description: "Talos version of the installation media.",
+			},
+			"platform": schema.StringAttribute{
+				Optional:    true,
+				Description: "Installation media to filter by, matched case-insensitively against the media ID, name or profile (e.g. `iso`, `aws`, `rpi_generic`).",
+			},
+			"architecture": schema.StringAttribute{
+				Optional:    true,
+				Description: "Architecture to filter installation media by.",
+				Validators: []validator.String{
+					stringvalidator.OneOf("amd64", "arm64"),
+				},
+			},
+			"secure_boot": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether to return SecureBoot installation media. Media without SecureBoot support are filtered out when set.",
+			},
+			"schematic_id": schema.StringAttribute{
+				Optional:    true,
+				Description: "Schematic to build the download URLs for, e.g. `omni_schematic.example.id`. When unset, Omni creates a default schematic for each matching installation media, including the board overlay for single-board computers.",
+			},
+			"media": schema.ListNestedAttribute{
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Computed:    true,
+							Description: "ID of the installation media.",
+						},
+						"name": schema.StringAttribute{
+							Computed:    true,
+							Description: "Human readable name of the installation media.",
+						},
+						"architecture": schema.StringAttribute{
+							Computed:    true,
+							Description: "Architecture of the installation media.",
+						},
+						"profile": schema.StringAttribute{
+							Computed:    true,
+							Description: "Image factory profile of the installation media.",
+						},
+						"overlay": schema.StringAttribute{
+							Computed:    true,
+							Description: "Board overlay always applied to the installation media, if any.",
+						},
+						"schematic_id": schema.StringAttribute{
+							Computed:    true,
+							Description: "Schematic the download URL is built for.",
+						},
+						"file_name": schema.StringAttribute{
+							Computed:    true,
+							Description: "Expected file name of the downloaded installation media.",
+						},
+						"download_url": schema.StringAttribute{
+							Computed:    true,
+							Description: "Image factory download URL of the installation media.",
+						},
+					},
+				},
+				Computed:    true,
+				Description: "Installation media matching the filters, sorted by ID.",
+			},
+		},
+	}
+}
+
+func (d *omniInstallationMediaDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni installation media data source")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.providerData = providerData
+}
+
+func (d *omniInstallationMediaDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if d.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	instance, diags := d.providerData.instanceOf(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniInstallationMediaModelV0
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	st := instance.cachedState
+
+	mediaList, err := safe.StateListAll[*omni.InstallationMedia](ctx, st)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to get omni installation media", err.Error())
+		return
+	}
+
+	baseURL, err := getImageFactoryBaseURL(ctx, st)
+	if err != nil {
+		resp.Diagnostics.AddError("Error reading image factory configuration", err.Error())
+		return
+	}
+
+	talosVersion := config.TalosVersion.ValueString()
+	secureBoot := config.SecureBoot.ValueBool()
+	platform := config.Platform.ValueString()
+
+	var matching []*omni.InstallationMedia
+	err = mediaList.ForEachErr(func(media *omni.InstallationMedia) error {
+		spec := media.TypedSpec().Value
+
+		if platform != "" &&
+			!strings.EqualFold(media.Metadata().ID(), platform) &&
+			!strings.EqualFold(spec.Name, platform) &&
+			!strings.EqualFold(spec.Profile, platform) {
+			return nil
+		}
+
+		if !config.Architecture.IsNull() && spec.Architecture != config.Architecture.ValueString() {
+			return nil
+		}
+
+		supported, err := installationMediaSupports(spec, talosVersion, secureBoot)
+		if err != nil {
+			return err
+		}
+
+		if supported {
+			matching = append(matching, media)
+		}
+
+		return nil
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Error checking installation media", err.Error())
+		return
+	}
+
+	sort.Slice(matching, func(i, j int) bool {
+		return matching[i].Metadata().ID() < matching[j].Metadata().ID()
+	})
+
+	tflog.Debug(ctx, fmt.Sprintf("number of installation media found: %d", len(matching)))
+
+	// the schematics of installation media only differ by their overlay, so create one
+	// schematic per overlay instead of one per media on every refresh
+	schematicIDs := map[string]string{}
+
+	config.Media = make([]OmniInstallationMediaItemModel, 0, len(matching))
+	for _, media := range matching {
+		spec := media.TypedSpec().Value
+
+		schematicID := config.SchematicID.ValueString()
+		if schematicID == "" {
+			schematicID = schematicIDs[spec.Overlay]
+		}
+
+		if schematicID == "" {
+			schematic, err := instance.client.Management().CreateSchematic(ctx, &management.CreateSchematicRequest{
+				TalosVersion: talosVersion,
+				MediaId:      media.Metadata().ID(),
+				SecureBoot:   secureBoot,
+			})
+			if err != nil {
+				resp.Diagnostics.AddError("Error creating schematic", fmt.Sprintf("Could not create a schematic for installation media %q: %s", media.Metadata().ID(), err))
+				return
+			}
+
+			schematicID = schematic.GetSchematicId()
+			schematicIDs[spec.Overlay] = schematicID
+		}
+
+		downloadURL, err := imageFactoryDownloadURL(baseURL, schematicID, talosVersion, spec, secureBoot)
+		if err != nil {
+			resp.Diagnostics.AddError("Error building download URL", err.Error())
+			return
+		}
+
+		config.Media = append(config.Media, OmniInstallationMediaItemModel{
+			ID:           types.StringValue(media.Metadata().ID()),
+			Name:         types.StringValue(spec.Name),
+			Architecture: types.StringValue(spec.Architecture),
+			Profile:      types.StringValue(spec.Profile),
+			Overlay:      types.StringValue(spec.Overlay),
+			SchematicID:  types.StringValue(schematicID),
+			FileName:     types.StringValue(spec.GenerateFilename(false, secureBoot, true)),
+			DownloadURL:  types.StringValue(downloadURL),
+		})
+	}
+
+	config.ID = types.StringValue(strings.Join([]string{talosVersion, platform, config.Architecture.ValueString(), fmt.Sprintf("%t", secureBoot), config.SchematicID.ValueString()}, "/"))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
@@ -0,0 +1,385 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cosiresource "github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework-timetypes/timetypes"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/siderolink"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	_ resource.Resource                = &omniJoinTokenResource{}
+	_ resource.ResourceWithConfigure   = &omniJoinTokenResource{}
+	_ resource.ResourceWithImportState = &omniJoinTokenResource{}
+)
+
+type omniJoinTokenResource struct {
+	providerData *omniProviderData
+}
+
+type OmniJoinTokenModelV0 struct {
+	ID             types.String      `tfsdk:"id"`
+	CreatedAt      types.String      `tfsdk:"created_at"`
+	LastUpdated    types.String      `tfsdk:"last_updated"`
+	Name           types.String      `tfsdk:"name"`
+	ExpirationTime timetypes.RFC3339 `tfsdk:"expiration_time"`
+	Revoked        types.Bool        `tfsdk:"revoked"`
+	State          types.String      `tfsdk:"state"`
+	IsDefault      types.Bool        `tfsdk:"is_default"`
+	UseCount       types.Int64       `tfsdk:"use_count"`
+	OmniInstance   types.String      `tfsdk:"omni_instance"`
+}
+
+func NewOmniJoinTokenResource() resource.Resource {
+	return &omniJoinTokenResource{}
+}
+
+func (r *omniJoinTokenResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_join_token"
+}
+
+func (r *omniJoinTokenResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni join token resource")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.providerData = providerData
+}
+
+func (r *omniJoinTokenResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Omni machine join token definition.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
+			"id": schema.StringAttribute{
+				Description: "ID of the join token. This is the token value machines use to join Omni.",
+				Computed:    true,
+				Sensitive:   true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"last_updated": schema.StringAttribute{
+				Computed: true,
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "Human readable name of the join token.",
+			},
+			"expiration_time": schema.StringAttribute{
+				Optional:    true,
+				CustomType:  timetypes.RFC3339Type{},
+				Description: "RFC3339 timestamp after which the join token expires. The token never expires when unset.",
+			},
+			"revoked": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Controls whether the join token is revoked. Revoked tokens can no longer be used by machines to join Omni.",
+			},
+			"state": schema.StringAttribute{
+				Computed:    true,
+				Description: "State of the join token as reported by Omni (ACTIVE, REVOKED or EXPIRED).",
+			},
+			"is_default": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the join token is the default join token of the Omni instance.",
+			},
+			"use_count": schema.Int64Attribute{
+				Computed:    true,
+				Description: "Number of machine links using the join token.",
+			},
+		},
+	}
+}
+
+func (r *omniJoinTokenResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniJoinTokenModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var ttl time.Duration
+	if !plan.ExpirationTime.IsNull() {
+		expirationTime, diags := plan.ExpirationTime.ValueRFC3339Time()
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		ttl = time.Until(expirationTime)
+		if ttl <= 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("expiration_time"),
+				"Invalid join token expiration time",
+				fmt.Sprintf("The expiration time %s is in the past.", plan.ExpirationTime.ValueString()),
+			)
+			return
+		}
+	}
+
+	tokenID, err := instance.client.Management().CreateJoinToken(ctx, plan.Name.ValueString(), ttl)
+	if err != nil {
+		resp.Diagnostics.AddError("Error creating join token", err.Error())
+		return
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("created join token %q", plan.Name.ValueString()))
+
+	st := instance.state
+
+	// the management API only accepts a TTL, so pin the exact expiration time and
+	// the revocation flag on the created token afterwards
+	if err := updateJoinToken(ctx, st, tokenID, plan); err != nil {
+		// don't leave behind a token which is not in the state
+		if destroyErr := st.TeardownAndDestroy(ctx, siderolink.NewJoinToken(resources.DefaultNamespace, tokenID).Metadata()); destroyErr != nil && !cosistate.IsNotFoundError(destroyErr) {
+			err = fmt.Errorf("%w, and failed to delete the created join token: %w", err, destroyErr)
+		}
+
+		resp.Diagnostics.AddError("Error updating join token", err.Error())
+		return
+	}
+
+	plan.ID = types.StringValue(tokenID)
+
+	if err := readJoinTokenStatus(ctx, st, &plan); err != nil {
+		resp.Diagnostics.AddError("Error reading join token status", err.Error())
+		return
+	}
+
+	timeNow := types.StringValue(time.Now().Format(time.RFC850))
+	plan.CreatedAt = timeNow
+	plan.LastUpdated = timeNow
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniJoinTokenResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniJoinTokenModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	st := instance.state
+
+	joinToken, err := safe.StateGetByID[*siderolink.JoinToken](ctx, st, config.ID.ValueString())
+	if err != nil {
+		if cosistate.IsNotFoundError(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.AddError("Error reading join token", err.Error())
+		return
+	}
+
+	spec := joinToken.TypedSpec().Value
+	config.Name = types.StringValue(spec.Name)
+	config.Revoked = types.BoolValue(spec.Revoked)
+
+	// only overwrite the configured timestamp when it points to a different instant,
+	// so that equivalent RFC3339 representations don't show up as drift
+	if spec.ExpirationTime == nil {
+		config.ExpirationTime = timetypes.NewRFC3339Null()
+	} else if configured, diags := config.ExpirationTime.ValueRFC3339Time(); config.ExpirationTime.IsNull() || diags.HasError() || !configured.Equal(spec.ExpirationTime.AsTime()) {
+		config.ExpirationTime = timetypes.NewRFC3339TimeValue(spec.ExpirationTime.AsTime())
+	}
+
+	if err := readJoinTokenStatus(ctx, st, &config); err != nil {
+		resp.Diagnostics.AddError("Error reading join token status", err.Error())
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
+
+func (r *omniJoinTokenResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniJoinTokenModelV0
+	var state OmniJoinTokenModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.ID = state.ID
+
+	st := instance.state
+
+	if err := updateJoinToken(ctx, st, plan.ID.ValueString(), plan); err != nil {
+		resp.Diagnostics.AddError("Error updating join token", err.Error())
+		return
+	}
+
+	if err := readJoinTokenStatus(ctx, st, &plan); err != nil {
+		resp.Diagnostics.AddError("Error reading join token status", err.Error())
+		return
+	}
+
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniJoinTokenResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state OmniJoinTokenModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := instance.state.TeardownAndDestroy(ctx, siderolink.NewJoinToken(resources.DefaultNamespace, state.ID.ValueString()).Metadata())
+	if err != nil && !cosistate.IsNotFoundError(err) {
+		resp.Diagnostics.AddError("Error deleting join token", err.Error())
+		return
+	}
+}
+
+func (r *omniJoinTokenResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importStateWithInstance(ctx, r.providerData, req, resp)
+}
+
+// updateJoinToken writes the name, revocation and expiration settings from the plan
+// to the JoinToken resource in Omni.
+func updateJoinToken(ctx context.Context, st cosistate.State, tokenID string, plan OmniJoinTokenModelV0) error {
+	var expirationTime *timestamppb.Timestamp
+	if !plan.ExpirationTime.IsNull() {
+		t, diags := plan.ExpirationTime.ValueRFC3339Time()
+		if diags.HasError() {
+			return fmt.Errorf("invalid expiration time %q", plan.ExpirationTime.ValueString())
+		}
+
+		expirationTime = timestamppb.New(t)
+	}
+
+	_, err := safe.StateUpdateWithConflicts(
+		ctx,
+		st,
+		siderolink.NewJoinToken(resources.DefaultNamespace, tokenID).Metadata(),
+		func(res *siderolink.JoinToken) error {
+			res.TypedSpec().Value.Name = plan.Name.ValueString()
+			res.TypedSpec().Value.Revoked = plan.Revoked.ValueBool()
+			res.TypedSpec().Value.ExpirationTime = expirationTime
+
+			return nil
+		},
+	)
+
+	return err
+}
+
+// readJoinTokenStatus waits for Omni to report the status of the join token and copies
+// the computed status fields into the model.
+func readJoinTokenStatus(ctx context.Context, st cosistate.State, model *OmniJoinTokenModelV0) error {
+	watchCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	status, err := safe.StateWatchFor[*siderolink.JoinTokenStatus](
+		watchCtx,
+		st,
+		siderolink.NewJoinTokenStatus(resources.DefaultNamespace, model.ID.ValueString()).Metadata(),
+		cosistate.WithEventTypes(cosistate.Created, cosistate.Updated),
+		cosistate.WithCondition(func(r cosiresource.Resource) (bool, error) {
+			status, ok := r.(*siderolink.JoinTokenStatus)
+			if !ok {
+				return false, nil
+			}
+
+			return joinTokenStatusSettled(*model, status.TypedSpec().Value), nil
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	model.State = types.StringValue(status.TypedSpec().Value.State.String())
+	model.IsDefault = types.BoolValue(status.TypedSpec().Value.IsDefault)
+	model.UseCount = types.Int64Value(int64(status.TypedSpec().Value.UseCount))
+
+	return nil
+}
+
+// joinTokenStatusSettled reports whether the status reflects the latest spec written by
+// the provider: revoked tokens are no longer active, and tokens which are not revoked
+// are active unless they expired.
+func joinTokenStatusSettled(model OmniJoinTokenModelV0, status *specs.JoinTokenStatusSpec) bool {
+	if status.Name != model.Name.ValueString() {
+		return false
+	}
+
+	if model.Revoked.ValueBool() {
+		return status.State != specs.JoinTokenStatusSpec_ACTIVE
+	}
+
+	return status.State != specs.JoinTokenStatusSpec_REVOKED
+}
@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/hashicorp/terraform-plugin-framework-timetypes/timetypes"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/siderolink"
+)
+
+func TestJoinTokenStatusSettled(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		revoked  bool
+		status   *specs.JoinTokenStatusSpec
+		expected bool
+	}{
+		{name: "stale name", status: &specs.JoinTokenStatusSpec{Name: "old", State: specs.JoinTokenStatusSpec_ACTIVE}},
+		{name: "active", status: &specs.JoinTokenStatusSpec{Name: "lab", State: specs.JoinTokenStatusSpec_ACTIVE}, expected: true},
+		{name: "expired", status: &specs.JoinTokenStatusSpec{Name: "lab", State: specs.JoinTokenStatusSpec_EXPIRED}, expected: true},
+		{name: "unrevoked, still revoked", status: &specs.JoinTokenStatusSpec{Name: "lab", State: specs.JoinTokenStatusSpec_REVOKED}},
+		{name: "revoked, still active", revoked: true, status: &specs.JoinTokenStatusSpec{Name: "lab", State: specs.JoinTokenStatusSpec_ACTIVE}},
+		{name: "revoked", revoked: true, status: &specs.JoinTokenStatusSpec{Name: "lab", State: specs.JoinTokenStatusSpec_REVOKED}, expected: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			model := OmniJoinTokenModelV0{Name: types.StringValue("lab"), Revoked: types.BoolValue(tc.revoked)}
+
+			if settled := joinTokenStatusSettled(model, tc.status); settled != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, settled)
+			}
+		})
+	}
+}
+
+func TestUpdateJoinToken(t *testing.T) {
+	ctx := t.Context()
+	st := cosistate.WrapCore(namespaced.NewState(inmem.Build))
+
+	joinToken := siderolink.NewJoinToken(resources.DefaultNamespace, "token")
+	joinToken.TypedSpec().Value.Name = "initial"
+	joinToken.TypedSpec().Value.Revoked = true
+
+	if err := st.Create(ctx, joinToken); err != nil {
+		t.Fatal(err)
+	}
+
+	expirationTime := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name           string
+		model          OmniJoinTokenModelV0
+		expirationTime *time.Time
+	}{
+		{
+			name:           "unrevoke with expiration",
+			model:          OmniJoinTokenModelV0{Name: types.StringValue("lab"), Revoked: types.BoolValue(false), ExpirationTime: timetypes.NewRFC3339TimeValue(expirationTime)},
+			expirationTime: &expirationTime,
+		},
+		{
+			name:  "revoke without expiration",
+			model: OmniJoinTokenModelV0{Name: types.StringValue("lab"), Revoked: types.BoolValue(true), ExpirationTime: timetypes.NewRFC3339Null()},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := updateJoinToken(ctx, st, "token", tc.model); err != nil {
+				t.Fatal(err)
+			}
+
+			joinToken, err := safe.StateGetByID[*siderolink.JoinToken](ctx, st, "token")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			spec := joinToken.TypedSpec().Value
+
+			if spec.Name != tc.model.Name.ValueString() || spec.Revoked != tc.model.Revoked.ValueBool() {
+				t.Errorf("expected name %q and revoked %v, got %q and %v", tc.model.Name.ValueString(), tc.model.Revoked.ValueBool(), spec.Name, spec.Revoked)
+			}
+
+			switch {
+			case tc.expirationTime == nil && spec.ExpirationTime != nil:
+				t.Errorf("expected no expiration time, got %s", spec.ExpirationTime.AsTime())
+			case tc.expirationTime != nil && (spec.ExpirationTime == nil || !spec.ExpirationTime.AsTime().Equal(*tc.expirationTime)):
+				t.Errorf("expected expiration time %s, got %v", tc.expirationTime, spec.ExpirationTime)
+			}
+		})
+	}
+}
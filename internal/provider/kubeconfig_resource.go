@@ -13,19 +13,19 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
-	"github.com/siderolabs/omni/client/pkg/client"
 	"github.com/siderolabs/omni/client/pkg/client/management"
 	"gopkg.in/yaml.v3"
 )
 
 var (
-	_ resource.Resource              = &omniClusterKubeConfig{}
-	_ resource.ResourceWithConfigure = &omniClusterKubeConfig{}
+	_ resource.Resource                 = &omniClusterKubeConfig{}
+	_ resource.ResourceWithConfigure    = &omniClusterKubeConfig{}
+	_ resource.ResourceWithUpgradeState = &omniClusterKubeConfig{}
 )
 
 type omniClusterKubeConfig struct {
-	omniClient *client.Client
-	context    context.Context
+	providerData *omniProviderData
+	context      context.Context
 }
 
 type KubeConfigClusterModel struct {
@@ -63,6 +63,7 @@ type KubeConfigModel struct {
 	Users    []KubeConfigUserModel    `tfsdk:"users"`
 }
 
+// OmniClusterKubeConfigModelV0 is the state of version 0, before the omni_instance attribute was added.
 type OmniClusterKubeConfigModelV0 struct {
 	ID          types.String `tfsdk:"id"`
 	CreatedAt   types.String `tfsdk:"created_at"`
@@ -76,6 +77,20 @@ type OmniClusterKubeConfigModelV0 struct {
 	YAML        types.String `tfsdk:"yaml"`
 }
 
+type OmniClusterKubeConfigModelV1 struct {
+	ID           types.String `tfsdk:"id"`
+	CreatedAt    types.String `tfsdk:"created_at"`
+	LastUpdated  types.String `tfsdk:"last_updated"`
+	User         types.String `tfsdk:"user"`
+	Groups       types.List   `tfsdk:"groups"`
+	Cluster      types.String `tfsdk:"cluster"`
+	Clusters     types.List   `tfsdk:"clusters"`
+	Contexts     types.List   `tfsdk:"contexts"`
+	Users        types.List   `tfsdk:"users"`
+	YAML         types.String `tfsdk:"yaml"`
+	OmniInstance types.String `tfsdk:"omni_instance"`
+}
+
 func NewOmniClusterKubeConfigResource() resource.Resource {
 	return &omniClusterKubeConfig{}
 }
@@ -89,24 +104,26 @@ func (r *omniClusterKubeConfig) Configure(_ context.Context, req resource.Config
 		return
 	}
 
-	omniClient, ok := req.ProviderData.(*client.Client)
+	providerData, ok := req.ProviderData.(*omniProviderData)
 	tflog.Debug(context.Background(), "Configuring omni kubeconfig resource")
 	if !ok {
 		resp.Diagnostics.AddError(
 			"failed to get image factory client",
-			fmt.Sprintf("Expected *client.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	r.omniClient = omniClient
+	r.providerData = providerData
 }
 
 func (r *omniClusterKubeConfig) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version:     1,
 		Description: "Omni cluster kubeconfig definition.",
 		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
 			"id": schema.StringAttribute{
 				Description: "Name (ID) of cluster that kubeconfig belongs to.",
 				Computed:    true,
@@ -218,10 +235,147 @@ func (r *omniClusterKubeConfig) Schema(_ context.Context, _ resource.SchemaReque
 	}
 }
 
+// schemaV0 returns the version 0 schema, which decodes the prior state in upgrades.
+func (r *omniClusterKubeConfig) schemaV0() *schema.Schema {
+	return &schema.Schema{
+		Description: "Omni cluster kubeconfig definition.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "Name (ID) of cluster that kubeconfig belongs to.",
+				Computed:    true,
+			},
+			"last_updated": schema.StringAttribute{
+				Computed: true,
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+			},
+			"cluster": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the cluster.",
+			},
+			"user": schema.StringAttribute{
+				Optional:    true,
+				Description: "User to use for generated kubeconfig.",
+			},
+			"groups": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Groups to use for generated kubeconfig.",
+			},
+			"clusters": schema.ListNestedAttribute{
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Computed:    true,
+							Description: "The name of the Kubernetes cluster.",
+						},
+						"cluster": schema.SingleNestedAttribute{
+							Attributes: map[string]schema.Attribute{
+								"server": schema.StringAttribute{
+									Computed:    true,
+									Description: "Endpoint for Kubernetes cluster.",
+								},
+							},
+							Computed:    true,
+							Description: "Kubernetes cluster information.",
+						},
+					},
+				},
+				Computed:    true,
+				Description: "Clusters defined in kubeconfig.",
+			},
+			"contexts": schema.ListNestedAttribute{
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Computed:    true,
+							Description: "The name of the Kubernetes cluster.",
+						},
+						"context": schema.SingleNestedAttribute{
+							Attributes: map[string]schema.Attribute{
+								"cluster": schema.StringAttribute{
+									Computed:    true,
+									Description: "Kubernetes cluster associated with context.",
+								},
+								"namespace": schema.StringAttribute{
+									Computed:    true,
+									Description: "Default namespace for context.",
+								},
+								"user": schema.StringAttribute{
+									Computed:    true,
+									Description: "Authenticated user associated with context.",
+								},
+							},
+							Computed:    true,
+							Description: "A context associated with kubeconfig.",
+						},
+					},
+				},
+				Computed:    true,
+				Description: "Clusters defined in kubeconfig.",
+			},
+			"users": schema.ListNestedAttribute{
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Computed:    true,
+							Description: "The name of the user.",
+						},
+						"user": schema.SingleNestedAttribute{
+							Attributes: map[string]schema.Attribute{
+								"token": schema.StringAttribute{
+									Computed:    true,
+									Description: "Token used by user to authenticate to Kubernetes cluster.",
+								},
+							},
+							Computed:    true,
+							Description: "User information.",
+						},
+					},
+				},
+				Computed:    true,
+				Description: "Clusters defined in kubeconfig.",
+			},
+			"yaml": schema.StringAttribute{
+				Description: "Returned kubeconfig in YAML.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// UpgradeState upgrades the state written before the omni_instance attribute was added.
+func (r *omniClusterKubeConfig) UpgradeState(context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: stateUpgrader(r.schemaV0(), func(prior OmniClusterKubeConfigModelV0) OmniClusterKubeConfigModelV1 {
+			return OmniClusterKubeConfigModelV1{
+				ID:           prior.ID,
+				CreatedAt:    prior.CreatedAt,
+				LastUpdated:  prior.LastUpdated,
+				User:         prior.User,
+				Groups:       prior.Groups,
+				Cluster:      prior.Cluster,
+				Clusters:     prior.Clusters,
+				Contexts:     prior.Contexts,
+				Users:        prior.Users,
+				YAML:         prior.YAML,
+				OmniInstance: types.StringNull(),
+			}
+		}),
+	}
+}
+
 func (r *omniClusterKubeConfig) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var plan OmniClusterKubeConfigModelV0
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniClusterKubeConfigModelV1
 
-	diags := req.Plan.Get(ctx, &plan)
+	diags = req.Plan.Get(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
@@ -239,7 +393,7 @@ func (r *omniClusterKubeConfig) Create(ctx context.Context, req resource.CreateR
 		groups = []string{"system:masters"}
 	}
 
-	kubeconfig, err := r.omniClient.Management().WithCluster(plan.Cluster.ValueString()).Kubeconfig(ctx, management.WithServiceAccount(24*time.Hour, user, groups...))
+	kubeconfig, err := instance.client.Management().WithCluster(plan.Cluster.ValueString()).Kubeconfig(ctx, management.WithServiceAccount(24*time.Hour, user, groups...))
 	if err != nil {
 		resp.Diagnostics.AddError("Error encountered getting cluster kubeconfig", err.Error())
 	}
@@ -348,12 +502,12 @@ func (r *omniClusterKubeConfig) Create(ctx context.Context, req resource.CreateR
 }
 
 func (r *omniClusterKubeConfig) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
-	if r.omniClient == nil {
+	if r.providerData == nil {
 		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
 		return
 	}
 
-	var config OmniClusterKubeConfigModelV0
+	var config OmniClusterKubeConfigModelV1
 	resp.Diagnostics.Append(req.State.Get(ctx, &config)...)
 	if resp.Diagnostics.HasError() {
 		return
@@ -366,8 +520,14 @@ func (r *omniClusterKubeConfig) Read(ctx context.Context, req resource.ReadReque
 }
 
 func (r *omniClusterKubeConfig) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var plan OmniClusterKubeConfigModelV0
-	var state OmniClusterKubeConfigModelV0
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniClusterKubeConfigModelV1
+	var state OmniClusterKubeConfigModelV1
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 	if resp.Diagnostics.HasError() {
@@ -381,7 +541,7 @@ func (r *omniClusterKubeConfig) Update(ctx context.Context, req resource.UpdateR
 
 	tflog.Debug(ctx, fmt.Sprintf("kubeconfig for %s in state:\n%s", state.Cluster, state.YAML.ValueString()))
 
-	kubeconfig, err := r.omniClient.Management().WithCluster(plan.Cluster.ValueString()).Kubeconfig(ctx)
+	kubeconfig, err := instance.client.Management().WithCluster(plan.Cluster.ValueString()).Kubeconfig(ctx)
 	if err != nil {
 		resp.Diagnostics.AddError("Error encountered getting cluster kubeconfig", err.Error())
 	}
@@ -406,7 +566,7 @@ func (r *omniClusterKubeConfig) Update(ctx context.Context, req resource.UpdateR
 }
 
 func (r *omniClusterKubeConfig) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
-	var state OmniClusterKubeConfigModelV0
+	var state OmniClusterKubeConfigModelV1
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
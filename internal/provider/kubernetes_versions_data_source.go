@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+var _ datasource.DataSource = &omniKubernetesVersionsDataSource{}
+
+type omniKubernetesVersionsDataSource struct {
+	providerData *omniProviderData
+}
+
+type OmniKubernetesVersionsModelV0 struct {
+	ID                 types.String `tfsdk:"id"`
+	Constraint         types.String `tfsdk:"constraint"`
+	TalosVersion       types.String `tfsdk:"talos_version"`
+	IncludePrereleases types.Bool   `tfsdk:"include_prereleases"`
+	Latest             types.String `tfsdk:"latest"`
+	Versions           []string     `tfsdk:"versions"`
+	OmniInstance       types.String `tfsdk:"omni_instance"`
+}
+
+func NewOmniKubernetesVersionsDataSource() datasource.DataSource {
+	return &omniKubernetesVersionsDataSource{}
+}
+
+func (d *omniKubernetesVersionsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_kubernetes_versions"
+}
+
+func (d *omniKubernetesVersionsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the Kubernetes versions available in Omni.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceDataSourceAttribute(),
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "ID of the data source.",
+			},
+			"constraint": schema.StringAttribute{
+				Optional:    true,
+				Description: "Semver constraint to filter versions by, e.g. `1.33.x` or `>=1.32.0 <1.34.0`.",
+			},
+			"talos_version": schema.StringAttribute{
+				Optional:    true,
+				Description: "Only return the Kubernetes versions compatible with this Talos version.",
+			},
+			"include_prereleases": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether to include pre-release versions. Defaults to false.",
+			},
+			"latest": schema.StringAttribute{
+				Computed:    true,
+				Description: "Newest version matching the filters, null when no version matches.",
+			},
+			"versions": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "Versions matching the filters, sorted from the oldest to the newest.",
+			},
+		},
+	}
+}
+
+func (d *omniKubernetesVersionsDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni kubernetes versions data source")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.providerData = providerData
+}
+
+func (d *omniKubernetesVersionsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if d.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	instance, diags := d.providerData.instanceOf(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniKubernetesVersionsModelV0
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	st := instance.cachedState
+
+	var candidates []string
+
+	if config.TalosVersion.IsNull() {
+		kubernetesVersions, err := safe.StateListAll[*omni.KubernetesVersion](ctx, st)
+		if err != nil {
+			resp.Diagnostics.AddError("failed to get omni kubernetes versions", err.Error())
+			return
+		}
+
+		kubernetesVersions.ForEach(func(kubernetesVersion *omni.KubernetesVersion) {
+			candidates = append(candidates, kubernetesVersion.TypedSpec().Value.Version)
+		})
+	} else {
+		talosVersions, err := listTalosVersions(ctx, st)
+		if err != nil {
+			resp.Diagnostics.AddError("failed to get omni talos versions", err.Error())
+			return
+		}
+
+		talosVersion, ok := findTalosVersion(talosVersions, config.TalosVersion.ValueString())
+		if !ok {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("talos_version"),
+				"Unknown Talos version",
+				fmt.Sprintf("Talos version %q is not available in Omni.", config.TalosVersion.ValueString()),
+			)
+
+			return
+		}
+
+		candidates = talosVersion.TypedSpec().Value.CompatibleKubernetesVersions
+	}
+
+	filtered, err := filterVersions(candidates, config.Constraint.ValueString(), config.IncludePrereleases.ValueBool())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("constraint"), "Invalid version constraint", err.Error())
+		return
+	}
+
+	config.Versions = filtered
+	config.Latest = types.StringNull()
+
+	if len(filtered) > 0 {
+		config.Latest = types.StringValue(filtered[len(filtered)-1])
+	}
+
+	config.ID = types.StringValue("kubernetes-versions")
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
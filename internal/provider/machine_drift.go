@@ -0,0 +1,226 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"terraform-provider-omni/internal/models"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"gopkg.in/yaml.v3"
+)
+
+// kernelArgsPatchName is the name of the machine patch which carries the kernel args of
+// a machine template.
+const kernelArgsPatchName = "kernel-args"
+
+// machineObservation is what Omni reports about the schematic, extensions and kernel
+// args of a machine. Nil fields were not reported, e.g. as the machine is not part of a
+// cluster yet.
+type machineObservation struct {
+	schematicID *string
+	extensions  []*specs.MachineExtensionsStatusSpec_Item
+	kernelArgs  *[]string
+}
+
+// observeMachine reads the schematic configuration, the extensions status and the
+// applied machine config of a machine from Omni.
+func observeMachine(ctx context.Context, st cosistate.State, machineID string) (machineObservation, error) {
+	var observation machineObservation
+
+	schematicConfiguration, err := safe.StateGetByID[*omni.SchematicConfiguration](ctx, st, machineID)
+	if err != nil && !cosistate.IsNotFoundError(err) {
+		return observation, fmt.Errorf("failed to get the schematic configuration of machine %q: %w", machineID, err)
+	}
+
+	if err == nil {
+		schematicID := schematicConfiguration.TypedSpec().Value.SchematicId
+		observation.schematicID = &schematicID
+	}
+
+	extensionsStatus, err := safe.StateGetByID[*omni.MachineExtensionsStatus](ctx, st, machineID)
+	if err != nil && !cosistate.IsNotFoundError(err) {
+		return observation, fmt.Errorf("failed to get the extensions status of machine %q: %w", machineID, err)
+	}
+
+	if err == nil {
+		observation.extensions = extensionsStatus.TypedSpec().Value.Extensions
+		if observation.extensions == nil {
+			observation.extensions = []*specs.MachineExtensionsStatusSpec_Item{}
+		}
+	}
+
+	machineConfig, err := safe.StateGetByID[*omni.RedactedClusterMachineConfig](ctx, st, machineID)
+	if err != nil && !cosistate.IsNotFoundError(err) {
+		return observation, fmt.Errorf("failed to get the config of machine %q: %w", machineID, err)
+	}
+
+	if err == nil {
+		buffer, err := machineConfig.TypedSpec().Value.GetUncompressedData()
+		if err != nil {
+			return observation, fmt.Errorf("failed to decompress the config of machine %q: %w", machineID, err)
+		}
+
+		defer buffer.Free()
+
+		kernelArgs, err := installKernelArgs(buffer.Data())
+		if err != nil {
+			return observation, fmt.Errorf("failed to parse the config of machine %q: %w", machineID, err)
+		}
+
+		observation.kernelArgs = &kernelArgs
+	}
+
+	return observation, nil
+}
+
+// installKernelArgs returns machine.install.extraKernelArgs of a Talos machine config.
+func installKernelArgs(config []byte) ([]string, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(config))
+
+	for {
+		var document struct {
+			Machine *struct {
+				Install struct {
+					ExtraKernelArgs []string `yaml:"extraKernelArgs"`
+				} `yaml:"install"`
+			} `yaml:"machine"`
+		}
+
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				return []string{}, nil
+			}
+
+			return nil, err
+		}
+
+		if document.Machine != nil {
+			if document.Machine.Install.ExtraKernelArgs == nil {
+				return []string{}, nil
+			}
+
+			return document.Machine.Install.ExtraKernelArgs, nil
+		}
+	}
+}
+
+// installedExtensions returns the extensions installed or being installed on the
+// machine. Immutable extensions, which are part of the image the machine booted from,
+// are only included when declared, as they can't be removed.
+func installedExtensions(declared []string, items []*specs.MachineExtensionsStatusSpec_Item) []string {
+	extensions := []string{}
+
+	for _, item := range items {
+		if item.Phase == specs.MachineExtensionsStatusSpec_Item_Removing {
+			continue
+		}
+
+		if item.Immutable && !slices.Contains(declared, item.Name) {
+			continue
+		}
+
+		extensions = append(extensions, item.Name)
+	}
+
+	return extensions
+}
+
+// extensionsDrift returns the installed extensions in the order of the declared ones
+// followed by the undeclared ones, or nil when they are the declared extensions.
+func extensionsDrift(declared []string, installed []string) []string {
+	if len(declared) == len(installed) && !slices.ContainsFunc(declared, func(extension string) bool {
+		return !slices.Contains(installed, extension)
+	}) {
+		return nil
+	}
+
+	drift := make([]string, 0, len(installed))
+
+	for _, extension := range declared {
+		if slices.Contains(installed, extension) {
+			drift = append(drift, extension)
+		}
+	}
+
+	for _, extension := range installed {
+		if !slices.Contains(declared, extension) {
+			drift = append(drift, extension)
+		}
+	}
+
+	return drift
+}
+
+// driftWarnings returns a warning for the declared extensions and kernel args which
+// differ from the ones the machine runs, so that the difference shows up in the plan.
+// Values which are null or unknown are not compared.
+func driftWarnings(ctx context.Context, machine string, declaredExtensions, installedExtensions, declaredKernelArgs, installedKernelArgs types.List) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	known := func(values ...types.List) bool {
+		return !slices.ContainsFunc(values, func(value types.List) bool { return value.IsNull() || value.IsUnknown() })
+	}
+
+	if known(declaredExtensions, installedExtensions) {
+		var declared, installed []string
+
+		diags.Append(declaredExtensions.ElementsAs(ctx, &declared, false)...)
+		diags.Append(installedExtensions.ElementsAs(ctx, &installed, false)...)
+
+		if !diags.HasError() && extensionsDrift(declared, installed) != nil {
+			diags.AddAttributeWarning(
+				path.Root("system_extensions"),
+				"System extensions differ from the machine",
+				fmt.Sprintf("Machine %s runs the extensions %v instead of the declared %v.", machine, installed, declared),
+			)
+		}
+	}
+
+	if known(declaredKernelArgs, installedKernelArgs) {
+		var declared, installed []string
+
+		diags.Append(declaredKernelArgs.ElementsAs(ctx, &declared, false)...)
+		diags.Append(installedKernelArgs.ElementsAs(ctx, &installed, false)...)
+
+		if !diags.HasError() && !slices.Equal(declared, installed) {
+			diags.AddAttributeWarning(
+				path.Root("kernel_args"),
+				"Kernel args differ from the machine",
+				fmt.Sprintf("Machine %s is configured with the kernel args %v instead of the declared %v.", machine, installed, declared),
+			)
+		}
+	}
+
+	return diags
+}
+
+// convertKernelArgsToPatch returns the machine patch setting the extra kernel args the
+// Talos installer adds to the boot entry.
+func convertKernelArgsToPatch(kernelArgs []string) []models.PatchYAML {
+	if kernelArgs == nil {
+		return nil
+	}
+
+	return []models.PatchYAML{
+		{
+			Name: kernelArgsPatchName,
+			Inline: map[string]any{
+				"machine": map[string]any{
+					"install": map[string]any{
+						"extraKernelArgs": kernelArgs,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"slices"
+	"strings"
+	"terraform-provider-omni/internal/models"
+	"testing"
+
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/siderolabs/omni/client/pkg/template"
+	"gopkg.in/yaml.v3"
+)
+
+func TestInstalledExtensions(t *testing.T) {
+	items := []*specs.MachineExtensionsStatusSpec_Item{
+		{Name: "siderolabs/iscsi-tools", Phase: specs.MachineExtensionsStatusSpec_Item_Installed},
+		{Name: "siderolabs/util-linux-tools", Phase: specs.MachineExtensionsStatusSpec_Item_Installing},
+		{Name: "siderolabs/qemu-guest-agent", Phase: specs.MachineExtensionsStatusSpec_Item_Removing},
+		{Name: "siderolabs/intel-ucode", Immutable: true, Phase: specs.MachineExtensionsStatusSpec_Item_Installed},
+		{Name: "siderolabs/amd-ucode", Immutable: true, Phase: specs.MachineExtensionsStatusSpec_Item_Installed},
+	}
+
+	declared := []string{"siderolabs/util-linux-tools", "siderolabs/intel-ucode", "siderolabs/iscsi-tools"}
+	installed := installedExtensions(declared, items)
+
+	expected := []string{"siderolabs/iscsi-tools", "siderolabs/util-linux-tools", "siderolabs/intel-ucode"}
+	if !slices.Equal(installed, expected) {
+		t.Fatalf("expected %v, got %v", expected, installed)
+	}
+
+	if drift := extensionsDrift(declared, installed); drift != nil {
+		t.Errorf("expected no drift, got %v", drift)
+	}
+
+	drift := extensionsDrift([]string{"siderolabs/qemu-guest-agent", "siderolabs/util-linux-tools"}, installed)
+
+	expected = []string{"siderolabs/util-linux-tools", "siderolabs/iscsi-tools", "siderolabs/intel-ucode"}
+	if !slices.Equal(drift, expected) {
+		t.Errorf("expected drift %v, got %v", expected, drift)
+	}
+}
+
+func TestInstallKernelArgs(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{
+			name: "set",
+			config: `version: v1alpha1
+machine:
+  install:
+    disk: /dev/sda
+    extraKernelArgs:
+      - console=ttyS0
+      - net.ifnames=0
+---
+apiVersion: v1alpha1
+kind: SideroLinkConfig
+apiUrl: https://omni.example.com
+`,
+			expected: []string{"console=ttyS0", "net.ifnames=0"},
+		},
+		{
+			name: "after other documents",
+			config: `apiVersion: v1alpha1
+kind: SideroLinkConfig
+apiUrl: https://omni.example.com
+---
+version: v1alpha1
+machine:
+  install:
+    extraKernelArgs:
+      - console=ttyS0
+`,
+			expected: []string{"console=ttyS0"},
+		},
+		{
+			name: "unset",
+			config: `version: v1alpha1
+machine:
+  install:
+    disk: /dev/sda
+`,
+			expected: []string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			kernelArgs, err := installKernelArgs([]byte(tc.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(kernelArgs, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, kernelArgs)
+			}
+		})
+	}
+}
+
+func TestConvertKernelArgsToPatch(t *testing.T) {
+	machineYAML, err := yaml.Marshal(models.MachinesYAML{
+		Kind:    KindMachine,
+		Name:    "00000000-0000-0000-0000-000000000000",
+		Patches: convertKernelArgsToPatch([]string{"console=ttyS0"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clusterYAML := `kind: Cluster
+name: kernel-args
+kubernetes:
+  version: v1.33.4
+talos:
+  version: v1.11.2
+---
+kind: ControlPlane
+machines:
+  - 00000000-0000-0000-0000-000000000000
+---
+`
+
+	tmpl, err := template.Load(strings.NewReader(clusterYAML + string(machineYAML)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tmpl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	resources, err := tmpl.Translate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, res := range resources {
+		patch, ok := res.(*omni.ConfigPatch)
+		if !ok {
+			continue
+		}
+
+		buffer, err := patch.TypedSpec().Value.GetUncompressedData()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		kernelArgs, err := installKernelArgs(buffer.Data())
+		buffer.Free()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(kernelArgs, []string{"console=ttyS0"}) {
+			t.Errorf("unexpected kernel args %v", kernelArgs)
+		}
+
+		return
+	}
+
+	t.Fatal("kernel args patch not found in the translated template")
+}
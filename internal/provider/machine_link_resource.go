@@ -0,0 +1,242 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/siderolink"
+)
+
+var (
+	_ resource.Resource                = &omniMachineLinkResource{}
+	_ resource.ResourceWithConfigure   = &omniMachineLinkResource{}
+	_ resource.ResourceWithImportState = &omniMachineLinkResource{}
+)
+
+type omniMachineLinkResource struct {
+	providerData *omniProviderData
+}
+
+type OmniMachineLinkModelV0 struct {
+	ID           types.String `tfsdk:"id"`
+	CreatedAt    types.String `tfsdk:"created_at"`
+	LastUpdated  types.String `tfsdk:"last_updated"`
+	MachineID    types.String `tfsdk:"machine_id"`
+	Connected    types.Bool   `tfsdk:"connected"`
+	LastEndpoint types.String `tfsdk:"last_endpoint"`
+	OmniInstance types.String `tfsdk:"omni_instance"`
+}
+
+func NewOmniMachineLinkResource() resource.Resource {
+	return &omniMachineLinkResource{}
+}
+
+func (r *omniMachineLinkResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_link"
+}
+
+func (r *omniMachineLinkResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni machine link resource")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.providerData = providerData
+}
+
+func (r *omniMachineLinkResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages the SideroLink connection of a machine to Omni. Machines create their link when they join Omni, so the resource adopts an existing link. " +
+			"Destroying the resource removes the machine from Omni, e.g. to decommission a node after it was removed from its cluster.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
+			"id": schema.StringAttribute{
+				Description: "ID of the machine link.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"last_updated": schema.StringAttribute{
+				Computed: true,
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"machine_id": schema.StringAttribute{
+				Required:    true,
+				Description: "ID of the machine. The machine has to be connected to Omni.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"connected": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the machine is connected to Omni.",
+			},
+			"last_endpoint": schema.StringAttribute{
+				Computed:    true,
+				Description: "Endpoint the machine last connected from.",
+			},
+		},
+	}
+}
+
+func (r *omniMachineLinkResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniMachineLinkModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	link, err := safe.StateGetByID[*siderolink.Link](ctx, instance.state, plan.MachineID.ValueString())
+	if err != nil {
+		if cosistate.IsNotFoundError(err) {
+			resp.Diagnostics.AddAttributeError(path.Root("machine_id"), "Machine link not found", fmt.Sprintf("Machine %q has no link, machines create their link when they join Omni.", plan.MachineID.ValueString()))
+			return
+		}
+
+		resp.Diagnostics.AddError("Error reading machine link", err.Error())
+		return
+	}
+
+	plan.ID = plan.MachineID
+	machineLinkFromLink(&plan, link)
+
+	timeNow := types.StringValue(time.Now().Format(time.RFC850))
+	plan.CreatedAt = timeNow
+	plan.LastUpdated = timeNow
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniMachineLinkResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniMachineLinkModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	link, err := safe.StateGetByID[*siderolink.Link](ctx, instance.state, config.ID.ValueString())
+	if err != nil {
+		if cosistate.IsNotFoundError(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.AddError("Error reading machine link", err.Error())
+		return
+	}
+
+	config.MachineID = config.ID
+	machineLinkFromLink(&config, link)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
+
+// Update is never called with changes, as the only configurable attribute requires replacement.
+func (r *omniMachineLinkResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan OmniMachineLinkModelV0
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// Delete refuses to remove machines which are still part of a cluster, as Omni would
+// lose track of a running cluster node.
+func (r *omniMachineLinkResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state OmniMachineLinkModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	st := instance.state
+	machineID := state.ID.ValueString()
+
+	machineStatus, err := safe.StateGetByID[*omni.MachineStatus](ctx, st, machineID)
+	if err != nil && !cosistate.IsNotFoundError(err) {
+		resp.Diagnostics.AddError("Error reading machine status", err.Error())
+		return
+	}
+
+	if err == nil && machineStatus.TypedSpec().Value.Cluster != "" {
+		resp.Diagnostics.AddError(
+			"Machine is part of a cluster",
+			fmt.Sprintf("Machine %q is part of cluster %q, remove it from the cluster before removing its link.", machineID, machineStatus.TypedSpec().Value.Cluster),
+		)
+
+		return
+	}
+
+	if err := destroyMachineLink(ctx, st, machineID); err != nil {
+		resp.Diagnostics.AddError("Error removing machine link", err.Error())
+		return
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("removed link of machine %q", machineID))
+}
+
+func (r *omniMachineLinkResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importStateWithInstance(ctx, r.providerData, req, resp)
+}
+
+func machineLinkFromLink(model *OmniMachineLinkModelV0, link *siderolink.Link) {
+	spec := link.TypedSpec().Value
+
+	model.Connected = types.BoolValue(spec.Connected)
+	model.LastEndpoint = types.StringValue(spec.LastEndpoint)
+}
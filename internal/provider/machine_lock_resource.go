@@ -0,0 +1,254 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cosiresource "github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+var (
+	_ resource.Resource                = &omniMachineLockResource{}
+	_ resource.ResourceWithConfigure   = &omniMachineLockResource{}
+	_ resource.ResourceWithImportState = &omniMachineLockResource{}
+)
+
+type omniMachineLockResource struct {
+	providerData *omniProviderData
+}
+
+type OmniMachineLockModelV0 struct {
+	ID           types.String `tfsdk:"id"`
+	CreatedAt    types.String `tfsdk:"created_at"`
+	LastUpdated  types.String `tfsdk:"last_updated"`
+	MachineID    types.String `tfsdk:"machine_id"`
+	Cluster      types.String `tfsdk:"cluster"`
+	MachineSet   types.String `tfsdk:"machine_set"`
+	OmniInstance types.String `tfsdk:"omni_instance"`
+}
+
+func NewOmniMachineLockResource() resource.Resource {
+	return &omniMachineLockResource{}
+}
+
+func (r *omniMachineLockResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_lock"
+}
+
+func (r *omniMachineLockResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni machine lock resource")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.providerData = providerData
+}
+
+func (r *omniMachineLockResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Locks a cluster machine, so that Omni holds back configuration changes and upgrades of the machine until the resource is destroyed. " +
+			"Don't combine it with `locked` of `omni_cluster_machine_template` for the same machine, as syncing the cluster template resets the lock.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
+			"id": schema.StringAttribute{
+				Description: "ID of the locked machine.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"last_updated": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"machine_id": schema.StringAttribute{
+				Required:    true,
+				Description: "ID of the machine to lock. The machine has to be part of a cluster.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"cluster": schema.StringAttribute{
+				Computed:    true,
+				Description: "Cluster the machine belongs to.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"machine_set": schema.StringAttribute{
+				Computed:    true,
+				Description: "Machine set the machine belongs to.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *omniMachineLockResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniMachineLockModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	machineSetNode, err := setMachineLocked(ctx, instance.state, plan.MachineID.ValueString(), true)
+	if err != nil {
+		if cosistate.IsNotFoundError(err) {
+			resp.Diagnostics.AddAttributeError(path.Root("machine_id"), "Machine not in a cluster", fmt.Sprintf("Machine %q is not part of a cluster, only cluster machines can be locked.", plan.MachineID.ValueString()))
+			return
+		}
+
+		resp.Diagnostics.AddError("Error locking machine", err.Error())
+		return
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("locked machine %q", plan.MachineID.ValueString()))
+
+	plan.ID = plan.MachineID
+	machineLockFromMachineSetNode(&plan, machineSetNode)
+
+	timeNow := types.StringValue(time.Now().Format(time.RFC850))
+	plan.CreatedAt = timeNow
+	plan.LastUpdated = timeNow
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// Read removes the lock from the state when the machine left its cluster or was
+// unlocked outside of Terraform, so that the next apply locks it again.
+func (r *omniMachineLockResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniMachineLockModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	machineSetNode, err := safe.StateGetByID[*omni.MachineSetNode](ctx, instance.state, config.ID.ValueString())
+	if err != nil {
+		if cosistate.IsNotFoundError(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.AddError("Error reading machine lock", err.Error())
+		return
+	}
+
+	if _, locked := machineSetNode.Metadata().Annotations().Get(omni.MachineLocked); !locked {
+		tflog.Info(ctx, fmt.Sprintf("machine %q was unlocked outside of terraform", config.ID.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	config.MachineID = config.ID
+	machineLockFromMachineSetNode(&config, machineSetNode)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
+
+// Update is never called with changes, as the only configurable attribute requires replacement.
+func (r *omniMachineLockResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan OmniMachineLockModelV0
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniMachineLockResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state OmniMachineLockModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, err := setMachineLocked(ctx, instance.state, state.ID.ValueString(), false)
+	if err != nil && !cosistate.IsNotFoundError(err) {
+		resp.Diagnostics.AddError("Error unlocking machine", err.Error())
+		return
+	}
+}
+
+func (r *omniMachineLockResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importStateWithInstance(ctx, r.providerData, req, resp)
+}
+
+// setMachineLocked sets or removes the lock annotation on the machine set node of a
+// cluster machine, the same way omnictl does.
+func setMachineLocked(ctx context.Context, st cosistate.State, machineID string, locked bool) (*omni.MachineSetNode, error) {
+	return safe.StateUpdateWithConflicts(ctx, st, cosiresource.NewMetadata(resources.DefaultNamespace, omni.MachineSetNodeType, machineID, cosiresource.VersionUndefined), func(res *omni.MachineSetNode) error {
+		if locked {
+			res.Metadata().Annotations().Set(omni.MachineLocked, "")
+		} else {
+			res.Metadata().Annotations().Delete(omni.MachineLocked)
+		}
+
+		return nil
+	})
+}
+
+func machineLockFromMachineSetNode(model *OmniMachineLockModelV0, machineSetNode *omni.MachineSetNode) {
+	cluster, _ := machineSetNode.Metadata().Labels().Get(omni.LabelCluster)
+	machineSet, _ := machineSetNode.Metadata().Labels().Get(omni.LabelMachineSet)
+
+	model.Cluster = types.StringValue(cluster)
+	model.MachineSet = types.StringValue(machineSet)
+}
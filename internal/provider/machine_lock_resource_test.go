@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+func TestSetMachineLocked(t *testing.T) {
+	ctx := t.Context()
+	st := cosistate.WrapCore(namespaced.NewState(inmem.Build))
+
+	machineSetNode := omni.NewMachineSetNode(resources.DefaultNamespace, "machine", omni.NewMachineSet(resources.DefaultNamespace, "production-workers"))
+	machineSetNode.Metadata().Labels().Set(omni.LabelCluster, "production")
+	machineSetNode.Metadata().Labels().Set(omni.LabelMachineSet, "production-workers")
+
+	if err := st.Create(ctx, machineSetNode); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		locked bool
+	}{
+		{name: "lock", locked: true},
+		{name: "lock again", locked: true},
+		{name: "unlock", locked: false},
+		{name: "unlock again", locked: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			updated, err := setMachineLocked(ctx, st, "machine", tc.locked)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stored, err := safe.StateGetByID[*omni.MachineSetNode](ctx, st, "machine")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, res := range []*omni.MachineSetNode{updated, stored} {
+				if _, locked := res.Metadata().Annotations().Get(omni.MachineLocked); locked != tc.locked {
+					t.Errorf("expected locked %v, got annotations %v", tc.locked, res.Metadata().Annotations().Raw())
+				}
+			}
+
+			var model OmniMachineLockModelV0
+
+			machineLockFromMachineSetNode(&model, stored)
+
+			if model.Cluster.ValueString() != "production" || model.MachineSet.ValueString() != "production-workers" {
+				t.Errorf("unexpected cluster %s and machine set %s", model.Cluster, model.MachineSet)
+			}
+		})
+	}
+
+	if _, err := setMachineLocked(ctx, st, "unallocated", true); !cosistate.IsNotFoundError(err) {
+		t.Errorf("expected a not found error for a machine outside of a cluster, got %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	cosiresource "github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/api/omni/management"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+var (
+	_ resource.Resource              = &omniMachineMaintenanceUpgradeResource{}
+	_ resource.ResourceWithConfigure = &omniMachineMaintenanceUpgradeResource{}
+)
+
+// maintenanceUpgradeTimeout is how long to wait for a machine to come back with the new
+// Talos version.
+const maintenanceUpgradeTimeout = 15 * time.Minute
+
+var talosVersionRegexp = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?$`)
+
+type omniMachineMaintenanceUpgradeResource struct {
+	providerData *omniProviderData
+}
+
+type OmniMachineMaintenanceUpgradeModelV0 struct {
+	ID           types.String `tfsdk:"id"`
+	CreatedAt    types.String `tfsdk:"created_at"`
+	LastUpdated  types.String `tfsdk:"last_updated"`
+	MachineID    types.String `tfsdk:"machine_id"`
+	TalosVersion types.String `tfsdk:"talos_version"`
+	OmniInstance types.String `tfsdk:"omni_instance"`
+}
+
+func NewOmniMachineMaintenanceUpgradeResource() resource.Resource {
+	return &omniMachineMaintenanceUpgradeResource{}
+}
+
+func (r *omniMachineMaintenanceUpgradeResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_maintenance_upgrade"
+}
+
+func (r *omniMachineMaintenanceUpgradeResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni machine maintenance upgrade resource")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.providerData = providerData
+}
+
+func (r *omniMachineMaintenanceUpgradeResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Upgrades Talos on a machine running in maintenance mode, e.g. before adding it to a cluster, and waits for the machine to report the new version. " +
+			"Machines in a cluster are upgraded through the Talos version of the cluster instead. " +
+			"Destroying the resource only removes it from the Terraform state, the machine keeps its Talos version.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
+			"id": schema.StringAttribute{
+				Description: "ID of the upgraded machine.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"last_updated": schema.StringAttribute{
+				Computed: true,
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"machine_id": schema.StringAttribute{
+				Required:    true,
+				Description: "ID of the machine to upgrade. The machine has to run in maintenance mode.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"talos_version": schema.StringAttribute{
+				Required:    true,
+				Description: "Talos version to upgrade the machine to, e.g. `1.10.5`. Changing it upgrades the machine again.",
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(talosVersionRegexp, "must be a Talos version such as 1.10.5"),
+				},
+			},
+		},
+	}
+}
+
+func (r *omniMachineMaintenanceUpgradeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniMachineMaintenanceUpgradeModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.upgrade(ctx, instance, plan.MachineID.ValueString(), plan.TalosVersion.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Error upgrading machine", err.Error())
+		return
+	}
+
+	plan.ID = plan.MachineID
+
+	timeNow := types.StringValue(time.Now().Format(time.RFC850))
+	plan.CreatedAt = timeNow
+	plan.LastUpdated = timeNow
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// Read reports the Talos version of the machine as drift while it is in maintenance
+// mode. Once the machine joined a cluster, its version is managed by the cluster.
+func (r *omniMachineMaintenanceUpgradeResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniMachineMaintenanceUpgradeModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	machineStatus, err := safe.StateGetByID[*omni.MachineStatus](ctx, instance.state, config.ID.ValueString())
+	if err != nil {
+		if cosistate.IsNotFoundError(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.AddError("Error reading machine status", err.Error())
+		return
+	}
+
+	spec := machineStatus.TypedSpec().Value
+	if spec.Maintenance && spec.TalosVersion != "" && !sameTalosVersion(spec.TalosVersion, config.TalosVersion.ValueString()) {
+		config.TalosVersion = types.StringValue(strings.TrimPrefix(spec.TalosVersion, "v"))
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
+
+func (r *omniMachineMaintenanceUpgradeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniMachineMaintenanceUpgradeModelV0
+	var state OmniMachineMaintenanceUpgradeModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.upgrade(ctx, instance, plan.MachineID.ValueString(), plan.TalosVersion.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Error upgrading machine", err.Error())
+		return
+	}
+
+	plan.ID = state.ID
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniMachineMaintenanceUpgradeResource) Delete(_ context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+}
+
+// upgrade requests the Talos upgrade of a machine in maintenance mode and waits for the
+// machine to report the new version.
+func (r *omniMachineMaintenanceUpgradeResource) upgrade(ctx context.Context, instance *omniInstance, machineID string, version string) error {
+	st := instance.state
+	version = strings.TrimPrefix(version, "v")
+
+	machineStatus, err := safe.StateGetByID[*omni.MachineStatus](ctx, st, machineID)
+	if err != nil {
+		if cosistate.IsNotFoundError(err) {
+			return fmt.Errorf("machine %q is not registered in Omni", machineID)
+		}
+
+		return err
+	}
+
+	spec := machineStatus.TypedSpec().Value
+	if !spec.Maintenance {
+		return fmt.Errorf("machine %q is not running in maintenance mode, machines in a cluster are upgraded through the Talos version of the cluster", machineID)
+	}
+
+	if sameTalosVersion(spec.TalosVersion, version) {
+		tflog.Debug(ctx, fmt.Sprintf("machine %q already runs Talos %s", machineID, version))
+		return nil
+	}
+
+	talosVersions, err := listTalosVersions(ctx, st)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := findTalosVersion(talosVersions, version); !ok {
+		return fmt.Errorf("Talos version %s is not available in Omni", version)
+	}
+
+	// the management client doesn't wrap the maintenance upgrade, so call the service
+	// directly through the connection of the resource API client
+	_, err = management.NewManagementServiceClient(instance.client.Omni()).MaintenanceUpgrade(ctx, &management.MaintenanceUpgradeRequest{
+		MachineId: machineID,
+		Version:   version,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to request the upgrade of machine %q to Talos %s: %w", machineID, version, err)
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("requested upgrade of machine %q to Talos %s", machineID, version))
+
+	watchCtx, cancel := context.WithTimeout(ctx, maintenanceUpgradeTimeout)
+	defer cancel()
+
+	_, err = safe.StateWatchFor[*omni.MachineStatus](
+		watchCtx,
+		st,
+		omni.NewMachineStatus(resources.DefaultNamespace, machineID).Metadata(),
+		cosistate.WithEventTypes(cosistate.Created, cosistate.Updated),
+		cosistate.WithCondition(func(r cosiresource.Resource) (bool, error) {
+			machineStatus, ok := r.(*omni.MachineStatus)
+			if !ok {
+				return false, nil
+			}
+
+			return sameTalosVersion(machineStatus.TypedSpec().Value.TalosVersion, version), nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed waiting for machine %q to run Talos %s: %w", machineID, version, err)
+	}
+
+	return nil
+}
+
+func sameTalosVersion(a string, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	cosiresource "github.com/cosi-project/runtime/pkg/resource"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+func TestMaintenanceUpgradeValidation(t *testing.T) {
+	ctx := t.Context()
+	st := cosistate.WrapCore(namespaced.NewState(inmem.Build))
+
+	maintenance := omni.NewMachineStatus(resources.DefaultNamespace, "maintenance")
+	maintenance.TypedSpec().Value.Maintenance = true
+	maintenance.TypedSpec().Value.TalosVersion = "v1.10.5"
+
+	allocated := omni.NewMachineStatus(resources.DefaultNamespace, "allocated")
+	allocated.TypedSpec().Value.TalosVersion = "v1.10.5"
+
+	talosVersion := omni.NewTalosVersion(resources.DefaultNamespace, "1.10.5")
+	talosVersion.TypedSpec().Value.Version = "1.10.5"
+
+	for _, res := range []cosiresource.Resource{maintenance, allocated, talosVersion} {
+		if err := st.Create(ctx, res); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &omniMachineMaintenanceUpgradeResource{}
+	instance := &omniInstance{state: st}
+
+	for _, tc := range []struct {
+		name    string
+		machine string
+		version string
+		err     string
+	}{
+		{name: "current version", machine: "maintenance", version: "1.10.5"},
+		{name: "current version with prefix", machine: "maintenance", version: "v1.10.5"},
+		{name: "unregistered machine", machine: "missing", version: "1.10.5", err: "is not registered in Omni"},
+		{name: "machine in a cluster", machine: "allocated", version: "1.10.6", err: "is not running in maintenance mode"},
+		{name: "unavailable version", machine: "maintenance", version: "1.10.6", err: "is not available in Omni"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := r.upgrade(ctx, instance, tc.machine, tc.version)
+
+			switch {
+			case tc.err == "" && err != nil:
+				t.Errorf("unexpected error: %s", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Errorf("expected an error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
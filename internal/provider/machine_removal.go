@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"terraform-provider-omni/internal/models"
+	"time"
+
+	cosiresource "github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/siderolink"
+	"gopkg.in/yaml.v3"
+)
+
+// Modes of handling machines which leave a cluster.
+const (
+	machineRemovalKeep   = "keep"
+	machineRemovalReset  = "reset"
+	machineRemovalRemove = "remove"
+)
+
+// machineRemovalTimeout is how long to wait for a machine to be reset or for its link
+// to be torn down.
+const machineRemovalTimeout = 10 * time.Minute
+
+// destroyMachineLink tears down the link of a machine, waits for Omni to release it
+// and destroys it. A machine without a link is not an error.
+func destroyMachineLink(ctx context.Context, st cosistate.State, machineID string) error {
+	teardownCtx, cancel := context.WithTimeout(ctx, machineRemovalTimeout)
+	defer cancel()
+
+	err := st.TeardownAndDestroy(teardownCtx, siderolink.NewLink(resources.DefaultNamespace, machineID, nil).Metadata())
+	if err != nil && !cosistate.IsNotFoundError(err) {
+		return fmt.Errorf("failed to remove link of machine %q: %w", machineID, err)
+	}
+
+	return nil
+}
+
+// waitForMachineReset waits until Omni has reset a machine which left its cluster and
+// the machine is back in maintenance mode. Resetting wipes the Talos state and
+// ephemeral partitions of the machine. Machines which are gone or disconnected can't be
+// reset, so they are not waited for.
+func waitForMachineReset(ctx context.Context, st cosistate.State, machines *machineStatusCache, machineID string) error {
+	machineStatuses, err := lookupMachineStatuses(ctx, st, machines, machineStatusQuery{id: machineID})
+	if err != nil {
+		return err
+	}
+
+	if len(machineStatuses) == 0 {
+		return nil
+	}
+
+	watchCtx, cancel := context.WithTimeout(ctx, machineRemovalTimeout)
+	defer cancel()
+
+	_, err = safe.StateWatchFor[*omni.MachineStatus](
+		watchCtx,
+		st,
+		omni.NewMachineStatus(resources.DefaultNamespace, machineID).Metadata(),
+		cosistate.WithEventTypes(cosistate.Created, cosistate.Updated),
+		cosistate.WithCondition(func(r cosiresource.Resource) (bool, error) {
+			machineStatus, ok := r.(*omni.MachineStatus)
+			if !ok {
+				return false, nil
+			}
+
+			spec := machineStatus.TypedSpec().Value
+
+			return !spec.Connected || (spec.Cluster == "" && spec.Maintenance), nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed waiting for machine %q to be reset: %w", machineID, err)
+	}
+
+	return nil
+}
+
+// removeMachines handles the machines which left a cluster according to the removal
+// mode. The machines are looked up in the machine status cache when it is not nil.
+func removeMachines(ctx context.Context, st cosistate.State, machines *machineStatusCache, machineIDs []string, mode string) error {
+	if mode == "" || mode == machineRemovalKeep {
+		return nil
+	}
+
+	for _, machineID := range machineIDs {
+		tflog.Debug(ctx, fmt.Sprintf("waiting for machine %q to be reset", machineID))
+
+		if err := waitForMachineReset(ctx, st, machines, machineID); err != nil {
+			return err
+		}
+
+		if mode != machineRemovalRemove {
+			continue
+		}
+
+		tflog.Debug(ctx, fmt.Sprintf("removing link of machine %q", machineID))
+
+		if err := destroyMachineLink(ctx, st, machineID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// templateMachineIDs returns the IDs of the machines listed in the machine sets and
+// machine documents of a cluster template, sorted.
+func templateMachineIDs(controlPlaneTemplate types.String, workersTemplate []types.String, machinesTemplate []types.String) []string {
+	var machineIDs []string
+
+	for _, machineSetTemplate := range append([]types.String{controlPlaneTemplate}, workersTemplate...) {
+		var machineSet models.MachineSetYAML
+		if err := yaml.Unmarshal([]byte(machineSetTemplate.ValueString()), &machineSet); err != nil {
+			continue
+		}
+
+		machineIDs = append(machineIDs, machineSet.Machines...)
+	}
+
+	for _, machineTemplate := range machinesTemplate {
+		var machine models.MachinesYAML
+		if err := yaml.Unmarshal([]byte(machineTemplate.ValueString()), &machine); err != nil || machine.Name == "" {
+			continue
+		}
+
+		machineIDs = append(machineIDs, machine.Name)
+	}
+
+	slices.Sort(machineIDs)
+
+	return slices.Compact(machineIDs)
+}
+
+// leavingMachines returns the machines of the current template which the planned
+// template no longer lists.
+func leavingMachines(current []string, planned []string) []string {
+	var leaving []string
+
+	for _, machineID := range current {
+		if !slices.Contains(planned, machineID) {
+			leaving = append(leaving, machineID)
+		}
+	}
+
+	return leaving
+}
@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestTemplateMachineIDs(t *testing.T) {
+	machineIDs := templateMachineIDs(
+		types.StringValue("kind: ControlPlane\nmachines:\n  - cp-1\n"),
+		[]types.String{
+			types.StringValue("kind: Workers\nname: workers\nmachines:\n  - worker-2\n  - worker-1\n"),
+			types.StringValue("kind: Workers\nname: large\nmachineClass:\n  name: large\n  size: 2\n"),
+		},
+		[]types.String{
+			types.StringValue("kind: Machine\nname: cp-1\n"),
+			types.StringValue("kind: Machine\nname: spare\n"),
+			types.StringValue("not: [valid"),
+		},
+	)
+
+	expected := []string{"cp-1", "spare", "worker-1", "worker-2"}
+	if !slices.Equal(machineIDs, expected) {
+		t.Errorf("expected %v, got %v", expected, machineIDs)
+	}
+}
+
+func TestLeavingMachines(t *testing.T) {
+	leaving := leavingMachines([]string{"a", "b", "c"}, []string{"a", "c", "d"})
+
+	if !slices.Equal(leaving, []string{"b"}) {
+		t.Errorf("expected [b], got %v", leaving)
+	}
+
+	if leaving := leavingMachines([]string{"a"}, []string{"a"}); leaving != nil {
+		t.Errorf("expected no leaving machines, got %v", leaving)
+	}
+}
@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"terraform-provider-omni/internal/models"
+)
+
+// checkControlPlaneSize returns an error for a control plane without machines, and a
+// warning for an even number of machines, which tolerates no more etcd member failures
+// than one machine less.
+func checkControlPlaneSize(count int) (warning string, err error) {
+	if count == 0 {
+		return "", errors.New("the control plane needs at least one machine")
+	}
+
+	if count%2 == 0 {
+		return fmt.Sprintf(
+			"The control plane has %d machines. etcd keeps quorum while a majority of its members is healthy, "+
+				"so %d machines tolerate as many failures as %d. Use an odd number of control plane machines.",
+			count, count, count-1,
+		), nil
+	}
+
+	return "", nil
+}
+
+// checkControlPlaneTemplate checks a ControlPlane document of a cluster template.
+func checkControlPlaneTemplate(controlPlane models.MachineSetYAML) (warning string, err error) {
+	if controlPlane.Name != "" {
+		return "", fmt.Errorf("the control plane can not have a custom name, got %q", controlPlane.Name)
+	}
+
+	if controlPlane.MachineClass == nil {
+		return checkControlPlaneSize(len(controlPlane.Machines))
+	}
+
+	// machine class sizes can also be "unlimited"
+	size, err := strconv.Atoi(controlPlane.MachineClass.Size)
+	if err != nil {
+		return "", nil
+	}
+
+	return checkControlPlaneSize(size)
+}
+
+// checkDuplicateMachines returns an error for every machine which is listed in more
+// than one of the machine sets.
+func checkDuplicateMachines(machineSets []models.MachineSetYAML) error {
+	machineSetsByMachine := map[string][]string{}
+
+	for _, machineSet := range machineSets {
+		name := machineSetDisplayName(machineSet)
+
+		for _, machine := range machineSet.Machines {
+			if !slices.Contains(machineSetsByMachine[machine], name) {
+				machineSetsByMachine[machine] = append(machineSetsByMachine[machine], name)
+			}
+		}
+	}
+
+	machines := make([]string, 0, len(machineSetsByMachine))
+	for machine, names := range machineSetsByMachine {
+		if len(names) > 1 {
+			machines = append(machines, machine)
+		}
+	}
+
+	slices.Sort(machines)
+
+	errs := make([]error, 0, len(machines))
+	for _, machine := range machines {
+		errs = append(errs, fmt.Errorf("machine %q is listed in more than one machine set: %v", machine, machineSetsByMachine[machine]))
+	}
+
+	return errors.Join(errs...)
+}
+
+func machineSetDisplayName(machineSet models.MachineSetYAML) string {
+	switch {
+	case machineSet.Kind == KindControlPlane:
+		return "control plane"
+	case machineSet.Name != "":
+		return machineSet.Name
+	default:
+		return "workers"
+	}
+}
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"strings"
+	"terraform-provider-omni/internal/models"
+	"testing"
+)
+
+func TestCheckControlPlaneTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		controlPlane models.MachineSetYAML
+		warning      bool
+		err          bool
+	}{
+		{name: "single machine", controlPlane: models.MachineSetYAML{Machines: []string{"a"}}},
+		{name: "three machines", controlPlane: models.MachineSetYAML{Machines: []string{"a", "b", "c"}}},
+		{name: "two machines", controlPlane: models.MachineSetYAML{Machines: []string{"a", "b"}}, warning: true},
+		{name: "no machines", controlPlane: models.MachineSetYAML{}, err: true},
+		{name: "custom name", controlPlane: models.MachineSetYAML{Name: "cp", Machines: []string{"a"}}, err: true},
+		{name: "machine class", controlPlane: models.MachineSetYAML{MachineClass: &models.MachineClassYAML{Name: "cp", Size: "3"}}},
+		{name: "even machine class", controlPlane: models.MachineSetYAML{MachineClass: &models.MachineClassYAML{Name: "cp", Size: "4"}}, warning: true},
+		{name: "unlimited machine class", controlPlane: models.MachineSetYAML{MachineClass: &models.MachineClassYAML{Name: "cp", Size: "unlimited"}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			warning, err := checkControlPlaneTemplate(tc.controlPlane)
+
+			if (err != nil) != tc.err {
+				t.Errorf("expected error %t, got %v", tc.err, err)
+			}
+
+			if (warning != "") != tc.warning {
+				t.Errorf("expected warning %t, got %q", tc.warning, warning)
+			}
+		})
+	}
+}
+
+func TestCheckDuplicateMachines(t *testing.T) {
+	machineSets := []models.MachineSetYAML{
+		{Kind: KindControlPlane, Machines: []string{"a", "b", "c"}},
+		{Kind: KindWorkers, Machines: []string{"d", "e"}},
+		{Kind: KindWorkers, Name: "gpu", Machines: []string{"f"}},
+	}
+
+	if err := checkDuplicateMachines(machineSets); err != nil {
+		t.Fatal(err)
+	}
+
+	machineSets[1].Machines = append(machineSets[1].Machines, "c")
+	machineSets[2].Machines = append(machineSets[2].Machines, "d", "c")
+
+	err := checkDuplicateMachines(machineSets)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	expected := `machine "c" is listed in more than one machine set: [control plane workers gpu]
+machine "d" is listed in more than one machine set: [workers gpu]`
+
+	if strings.TrimSpace(err.Error()) != expected {
+		t.Errorf("unexpected error:\n%s", err)
+	}
+}
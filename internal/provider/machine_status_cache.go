@@ -0,0 +1,357 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// Modes of looking up machine statuses.
+const (
+	machineCacheList  = "list"
+	machineCacheWatch = "watch"
+)
+
+const (
+	// machineStatusCacheSyncTimeout is how long lookups wait for the initial contents
+	// of the watch before they fall back to listing the machine statuses. Lookups do
+	// not wait when the watch fails before its initial contents arrive.
+	machineStatusCacheSyncTimeout = time.Minute
+
+	// machineStatusCacheRewatchInterval is how long to wait before watching again
+	// after the watch failed.
+	machineStatusCacheRewatchInterval = 5 * time.Second
+)
+
+// machineStatusQuery selects machine statuses. Empty fields match all machines.
+type machineStatusQuery struct {
+	id      string
+	cluster string
+	labels  map[string]string
+}
+
+func (q machineStatusQuery) matches(machineStatus *omni.MachineStatus) bool {
+	if q.id != "" && machineStatus.Metadata().ID() != q.id {
+		return false
+	}
+
+	if q.cluster != "" && machineStatus.TypedSpec().Value.GetCluster() != q.cluster {
+		return false
+	}
+
+	for key, value := range q.labels {
+		if labelValue, ok := machineStatus.Metadata().Labels().Get(key); !ok || labelValue != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// machineStatusCache keeps the machine statuses in memory, indexed by ID, cluster and
+// label, and up to date with a watch started on the first lookup. The watch lasts for
+// the lifetime of the provider process, and is started again when it fails.
+type machineStatusCache struct {
+	st cosistate.State
+
+	start sync.Once
+
+	// settled is closed once the first watch bootstrapped or failed.
+	settled     chan struct{}
+	settledOnce sync.Once
+
+	mu        sync.RWMutex
+	healthy   bool
+	byID      map[string]*omni.MachineStatus
+	byCluster map[string]map[string]*omni.MachineStatus
+	byLabel   map[string]map[string]map[string]*omni.MachineStatus
+}
+
+func newMachineStatusCache(st cosistate.State) *machineStatusCache {
+	return &machineStatusCache{
+		st:      st,
+		settled: make(chan struct{}),
+	}
+}
+
+// lookupMachineStatuses returns the machine statuses matching the query, sorted by ID.
+// They come from the cache when it is not nil and in sync with Omni. Otherwise a
+// machine is looked up by ID, and all machine statuses are listed for other queries,
+// so that callers share the cached list.
+func lookupMachineStatuses(ctx context.Context, st cosistate.State, cache *machineStatusCache, query machineStatusQuery) ([]*omni.MachineStatus, error) {
+	if cache != nil {
+		if machineStatuses, ok := cache.lookup(ctx, query); ok {
+			return machineStatuses, nil
+		}
+
+		tflog.Debug(ctx, "machine status cache is not in sync, looking up machine statuses in Omni")
+	}
+
+	if query.id != "" {
+		machineStatus, err := safe.StateGetByID[*omni.MachineStatus](ctx, st, query.id)
+		if err != nil {
+			if cosistate.IsNotFoundError(err) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		if !query.matches(machineStatus) {
+			return nil, nil
+		}
+
+		return []*omni.MachineStatus{machineStatus}, nil
+	}
+
+	list, err := safe.StateListAll[*omni.MachineStatus](ctx, st)
+	if err != nil {
+		return nil, err
+	}
+
+	var machineStatuses []*omni.MachineStatus
+
+	for machineStatus := range list.All() {
+		if query.matches(machineStatus) {
+			machineStatuses = append(machineStatuses, machineStatus)
+		}
+	}
+
+	return machineStatuses, nil
+}
+
+// lookup returns the cached machine statuses matching the query, and whether the
+// cache is in sync. The returned machine statuses must not be modified.
+func (c *machineStatusCache) lookup(ctx context.Context, query machineStatusQuery) ([]*omni.MachineStatus, bool) {
+	c.start.Do(func() {
+		go c.run(context.WithoutCancel(ctx))
+	})
+
+	syncCtx, cancel := context.WithTimeout(ctx, machineStatusCacheSyncTimeout)
+	defer cancel()
+
+	select {
+	case <-c.settled:
+	case <-syncCtx.Done():
+		return nil, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.healthy {
+		return nil, false
+	}
+
+	candidates := c.byID
+
+	switch {
+	case query.id != "":
+		candidates = map[string]*omni.MachineStatus{}
+
+		if machineStatus, ok := c.byID[query.id]; ok {
+			candidates[query.id] = machineStatus
+		}
+	case query.cluster != "":
+		candidates = c.byCluster[query.cluster]
+	case len(query.labels) > 0:
+		key := slices.Sorted(maps.Keys(query.labels))[0]
+		candidates = c.byLabel[key][query.labels[key]]
+	}
+
+	var machineStatuses []*omni.MachineStatus
+
+	for _, machineStatus := range candidates {
+		if query.matches(machineStatus) {
+			machineStatuses = append(machineStatuses, machineStatus)
+		}
+	}
+
+	sort.Slice(machineStatuses, func(i, j int) bool {
+		return machineStatuses[i].Metadata().ID() < machineStatuses[j].Metadata().ID()
+	})
+
+	return machineStatuses, true
+}
+
+// run watches the machine statuses until ctx is done.
+func (c *machineStatusCache) run(ctx context.Context) {
+	for {
+		err := c.watch(ctx)
+
+		c.mu.Lock()
+		c.healthy = false
+		c.mu.Unlock()
+
+		// lookups waiting for the first watch fall back to listing right away
+		c.settle()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		tflog.Warn(ctx, fmt.Sprintf("machine status watch failed, watching again in %s", machineStatusCacheRewatchInterval), map[string]any{"error": err.Error()})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(machineStatusCacheRewatchInterval):
+		}
+	}
+}
+
+// watch fills the cache with the initial machine statuses, and applies their changes
+// until the watch fails.
+func (c *machineStatusCache) watch(ctx context.Context) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan cosistate.Event)
+
+	err := c.st.WatchKind(watchCtx, omni.NewMachineStatus(resources.DefaultNamespace, "").Metadata(), events, cosistate.WithBootstrapContents(true))
+	if err != nil {
+		return err
+	}
+
+	initial := map[string]*omni.MachineStatus{}
+	bootstrapped := false
+
+	for {
+		var event cosistate.Event
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event = <-events:
+		}
+
+		switch event.Type { //nolint:exhaustive
+		case cosistate.Errored:
+			return event.Error
+		case cosistate.Bootstrapped:
+			c.reset(initial)
+
+			bootstrapped = true
+		case cosistate.Created, cosistate.Updated:
+			machineStatus, ok := event.Resource.(*omni.MachineStatus)
+			if !ok {
+				continue
+			}
+
+			if bootstrapped {
+				c.put(machineStatus)
+			} else {
+				initial[machineStatus.Metadata().ID()] = machineStatus
+			}
+		case cosistate.Destroyed:
+			if bootstrapped {
+				c.remove(event.Resource.Metadata().ID())
+			} else {
+				delete(initial, event.Resource.Metadata().ID())
+			}
+		}
+	}
+}
+
+// reset replaces the contents of the cache, and marks it in sync.
+func (c *machineStatusCache) reset(machineStatuses map[string]*omni.MachineStatus) {
+	c.mu.Lock()
+
+	c.byID = map[string]*omni.MachineStatus{}
+	c.byCluster = map[string]map[string]*omni.MachineStatus{}
+	c.byLabel = map[string]map[string]map[string]*omni.MachineStatus{}
+
+	for _, machineStatus := range machineStatuses {
+		c.index(machineStatus)
+	}
+
+	c.healthy = true
+
+	c.mu.Unlock()
+
+	c.settle()
+}
+
+func (c *machineStatusCache) settle() {
+	c.settledOnce.Do(func() { close(c.settled) })
+}
+
+func (c *machineStatusCache) put(machineStatus *omni.MachineStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.unindex(machineStatus.Metadata().ID())
+	c.index(machineStatus)
+}
+
+func (c *machineStatusCache) remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.unindex(id)
+}
+
+func (c *machineStatusCache) index(machineStatus *omni.MachineStatus) {
+	id := machineStatus.Metadata().ID()
+
+	c.byID[id] = machineStatus
+
+	if cluster := machineStatus.TypedSpec().Value.GetCluster(); cluster != "" {
+		if c.byCluster[cluster] == nil {
+			c.byCluster[cluster] = map[string]*omni.MachineStatus{}
+		}
+
+		c.byCluster[cluster][id] = machineStatus
+	}
+
+	for key, value := range machineStatus.Metadata().Labels().Raw() {
+		if c.byLabel[key] == nil {
+			c.byLabel[key] = map[string]map[string]*omni.MachineStatus{}
+		}
+
+		if c.byLabel[key][value] == nil {
+			c.byLabel[key][value] = map[string]*omni.MachineStatus{}
+		}
+
+		c.byLabel[key][value][id] = machineStatus
+	}
+}
+
+func (c *machineStatusCache) unindex(id string) {
+	machineStatus, ok := c.byID[id]
+	if !ok {
+		return
+	}
+
+	delete(c.byID, id)
+
+	if cluster := machineStatus.TypedSpec().Value.GetCluster(); cluster != "" {
+		delete(c.byCluster[cluster], id)
+
+		if len(c.byCluster[cluster]) == 0 {
+			delete(c.byCluster, cluster)
+		}
+	}
+
+	for key, value := range machineStatus.Metadata().Labels().Raw() {
+		delete(c.byLabel[key][value], id)
+
+		if len(c.byLabel[key][value]) == 0 {
+			delete(c.byLabel[key], value)
+		}
+
+		if len(c.byLabel[key]) == 0 {
+			delete(c.byLabel, key)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+func testMachineStatus(id string, cluster string, labels map[string]string) *omni.MachineStatus {
+	machineStatus := omni.NewMachineStatus(resources.DefaultNamespace, id)
+	machineStatus.TypedSpec().Value.Cluster = cluster
+
+	for key, value := range labels {
+		machineStatus.Metadata().Labels().Set(key, value)
+	}
+
+	return machineStatus
+}
+
+func machineStatusIDs(machineStatuses []*omni.MachineStatus) []string {
+	ids := make([]string, 0, len(machineStatuses))
+
+	for _, machineStatus := range machineStatuses {
+		ids = append(ids, machineStatus.Metadata().ID())
+	}
+
+	return ids
+}
+
+func TestLookupMachineStatuses(t *testing.T) {
+	ctx := t.Context()
+	st := cosistate.WrapCore(namespaced.NewState(inmem.Build))
+
+	for _, machineStatus := range []*omni.MachineStatus{
+		testMachineStatus("machine-1", "cluster-a", map[string]string{"zone": "a", "rack": "1"}),
+		testMachineStatus("machine-2", "cluster-a", map[string]string{"zone": "b"}),
+		testMachineStatus("machine-3", "", map[string]string{"zone": "a", "rack": "2"}),
+	} {
+		if err := st.Create(ctx, machineStatus); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cache := newMachineStatusCache(st)
+
+	for _, tc := range []struct {
+		name     string
+		query    machineStatusQuery
+		expected []string
+	}{
+		{name: "all", expected: []string{"machine-1", "machine-2", "machine-3"}},
+		{name: "id", query: machineStatusQuery{id: "machine-2"}, expected: []string{"machine-2"}},
+		{name: "missing id", query: machineStatusQuery{id: "machine-4"}, expected: []string{}},
+		{name: "cluster", query: machineStatusQuery{cluster: "cluster-a"}, expected: []string{"machine-1", "machine-2"}},
+		{name: "labels", query: machineStatusQuery{labels: map[string]string{"zone": "a", "rack": "2"}}, expected: []string{"machine-3"}},
+		{name: "cluster and labels", query: machineStatusQuery{cluster: "cluster-a", labels: map[string]string{"zone": "a"}}, expected: []string{"machine-1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, cache := range []*machineStatusCache{nil, cache} {
+				machineStatuses, err := lookupMachineStatuses(ctx, st, cache, tc.query)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if ids := machineStatusIDs(machineStatuses); !slices.Equal(ids, tc.expected) {
+					t.Errorf("expected %v with cache %v, got %v", tc.expected, cache != nil, ids)
+				}
+			}
+		})
+	}
+}
+
+func TestMachineStatusCacheWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	st := cosistate.WrapCore(namespaced.NewState(inmem.Build))
+
+	if err := st.Create(ctx, testMachineStatus("machine-1", "cluster-a", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := newMachineStatusCache(st)
+
+	if _, ok := cache.lookup(ctx, machineStatusQuery{}); !ok {
+		t.Fatal("expected the cache to be in sync")
+	}
+
+	if err := st.Create(ctx, testMachineStatus("machine-2", "cluster-a", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := safe.StateUpdateWithConflicts(ctx, st, omni.NewMachineStatus(resources.DefaultNamespace, "machine-1").Metadata(), func(r *omni.MachineStatus) error {
+		r.TypedSpec().Value.Cluster = "cluster-b"
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForCache := func(query machineStatusQuery, expected []string) {
+		t.Helper()
+
+		deadline := time.Now().Add(10 * time.Second)
+
+		for {
+			machineStatuses, ok := cache.lookup(ctx, query)
+			if ids := machineStatusIDs(machineStatuses); ok && slices.Equal(ids, expected) {
+				return
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %v for %+v, got %v", expected, query, machineStatusIDs(machineStatuses))
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitForCache(machineStatusQuery{cluster: "cluster-a"}, []string{"machine-2"})
+	waitForCache(machineStatusQuery{cluster: "cluster-b"}, []string{"machine-1"})
+
+	if err := st.Destroy(ctx, omni.NewMachineStatus(resources.DefaultNamespace, "machine-2").Metadata()); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForCache(machineStatusQuery{}, []string{"machine-1"})
+	waitForCache(machineStatusQuery{cluster: "cluster-a"}, []string{})
+}
+
+// failingWatchState fails every watch.
+type failingWatchState struct {
+	cosistate.CoreState
+}
+
+func (s *failingWatchState) WatchKind(context.Context, resource.Kind, chan<- cosistate.Event, ...cosistate.WatchKindOption) error {
+	return errors.New("watch is not permitted")
+}
+
+func TestMachineStatusCacheWatchFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	st := cosistate.WrapCore(&failingWatchState{CoreState: namespaced.NewState(inmem.Build)})
+
+	if err := st.Create(ctx, testMachineStatus("machine-1", "cluster-a", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := newMachineStatusCache(st)
+
+	start := time.Now()
+
+	if _, ok := cache.lookup(ctx, machineStatusQuery{}); ok {
+		t.Fatal("expected the cache not to be in sync")
+	}
+
+	if elapsed := time.Since(start); elapsed > machineStatusCacheRewatchInterval {
+		t.Errorf("expected the lookup not to wait for the sync timeout, waited %s", elapsed)
+	}
+
+	machineStatuses, err := lookupMachineStatuses(ctx, st, cache, machineStatusQuery{cluster: "cluster-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := machineStatusIDs(machineStatuses); !slices.Equal(ids, []string{"machine-1"}) {
+		t.Errorf("expected the lookup to fall back to the state, got %v", ids)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"math/big"
 	"time"
 
-	"github.com/cosi-project/runtime/pkg/safe"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
@@ -15,8 +14,6 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/siderolabs/gen/xslices"
-	"github.com/siderolabs/omni/client/pkg/client"
-	"github.com/siderolabs/omni/client/pkg/omni/resources"
 	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 )
 
@@ -26,7 +23,7 @@ const (
 )
 
 type omniMachineStatusDataSource struct {
-	omniClient *client.Client
+	providerData *omniProviderData
 }
 
 type omniMachineStatusHardwareProcessor struct {
@@ -102,6 +99,7 @@ type machineInfo struct {
 type omniMachineStatusDataSourceModelV0 struct {
 	Filters      *omniMachineStatusSearchFilters `tfsdk:"filters"`
 	MachinesInfo []machineInfo                   `tfsdk:"machines"`
+	OmniInstance types.String                    `tfsdk:"omni_instance"`
 }
 
 var _ datasource.DataSource = &omniMachineStatusDataSource{}
@@ -118,6 +116,7 @@ func (d *omniMachineStatusDataSource) Schema(_ context.Context, _ datasource.Sch
 	resp.Schema = schema.Schema{
 		Description: "Provides a list of Omni machine statuses.",
 		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceDataSourceAttribute(),
 			"filters": schema.SingleNestedAttribute{
 				Attributes: map[string]schema.Attribute{
 					"labels": schema.MapAttribute{
@@ -231,43 +230,52 @@ func (d *omniMachineStatusDataSource) Configure(_ context.Context, req datasourc
 		return
 	}
 
-	omniClient, ok := req.ProviderData.(*client.Client)
+	providerData, ok := req.ProviderData.(*omniProviderData)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"failed to get image factory client",
-			fmt.Sprintf("Expected *client.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	d.omniClient = omniClient
+	d.providerData = providerData
 }
 
 func (d *omniMachineStatusDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
-	if d.omniClient == nil {
+	if d.providerData == nil {
 		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
 		return
 	}
 
-	st := d.omniClient.Omni().State()
+	instance, diags := d.providerData.instanceOf(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	st := instance.cachedState
 
 	var config omniMachineStatusDataSourceModelV0
 
 	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
 
-	machines, err := safe.StateList[*omni.MachineStatus](ctx, st, omni.NewMachineStatus(resources.DefaultNamespace, "").Metadata())
+	var query machineStatusQuery
+	if config.Filters != nil {
+		query = machineStatusQuery{
+			id:      config.Filters.ID.ValueString(),
+			cluster: config.Filters.Cluster.ValueString(),
+			labels:  config.Filters.Labels,
+		}
+	}
+
+	machinesSlice, err := lookupMachineStatuses(ctx, st, instance.machines, query)
 	if err != nil {
 		resp.Diagnostics.AddError("failed to get omni machine statuses", err.Error())
 		return
 	}
 
-	var machinesSlice []*omni.MachineStatus
-	machines.All()(func(m *omni.MachineStatus) bool {
-		machinesSlice = append(machinesSlice, m)
-		return true
-	})
-
 	tflog.Debug(ctx, fmt.Sprintf("number of machines found: %d", len(machinesSlice)))
 
 	if config.Filters != nil {
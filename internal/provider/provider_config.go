@@ -0,0 +1,190 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/siderolabs/go-api-signature/pkg/serviceaccount"
+	"github.com/siderolabs/omni/client/pkg/client"
+	omniconfig "github.com/siderolabs/omni/client/pkg/omnictl/config"
+)
+
+// Environment variables the provider configuration falls back to.
+const (
+	envEndpoint              = "OMNI_ENDPOINT"
+	envServiceAccountKey     = "OMNI_SERVICE_ACCOUNT_KEY"
+	envServiceAccountKeyFile = "OMNI_SERVICE_ACCOUNT_KEY_FILE"
+	envConfig                = "OMNI_CONFIG"
+	envContext               = "OMNI_CONTEXT"
+)
+
+// clientConfig is the resolved configuration of the Omni API client. It authenticates
+// either with a service account key or with the user account of an omniconfig context.
+type clientConfig struct {
+	endpoint          string
+	serviceAccountKey string
+	contextName       string
+	identity          string
+}
+
+func (c clientConfig) options() []client.Option {
+	if c.serviceAccountKey != "" {
+		return []client.Option{client.WithServiceAccount(c.serviceAccountKey)}
+	}
+
+	return []client.Option{client.WithUserAccount(c.contextName, c.identity)}
+}
+
+// resolveClientConfig resolves the client configuration from the provider
+// configuration, the environment and the omniconfig file. Configured values take
+// precedence over the environment, and a service account key over the omniconfig.
+func resolveClientConfig(config OmniProviderModelV0) (clientConfig, diag.Diagnostics) {
+	var (
+		resolved clientConfig
+		diags    diag.Diagnostics
+	)
+
+	resolved.endpoint = stringOrEnv(config.Endpoint, envEndpoint)
+
+	var keyFile string
+
+	switch {
+	case !config.ServiceAccountKey.IsNull():
+		resolved.serviceAccountKey = config.ServiceAccountKey.ValueString()
+	case !config.ServiceAccountKeyFile.IsNull():
+		keyFile = config.ServiceAccountKeyFile.ValueString()
+	case os.Getenv(envServiceAccountKey) != "":
+		resolved.serviceAccountKey = os.Getenv(envServiceAccountKey)
+	default:
+		keyFile = os.Getenv(envServiceAccountKeyFile)
+	}
+
+	if keyFile != "" {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			diags.AddAttributeError(path.Root("service_account_key_file"), "Unable to Read Omni Service Account Key", err.Error())
+			return resolved, diags
+		}
+
+		resolved.serviceAccountKey = strings.TrimSpace(string(key))
+	}
+
+	configPath := stringOrEnv(config.ConfigPath, envConfig)
+	contextName := stringOrEnv(config.Context, envContext)
+
+	switch {
+	case resolved.serviceAccountKey != "":
+		if _, err := serviceaccount.Decode(resolved.serviceAccountKey); err != nil {
+			diags.AddAttributeError(
+				path.Root("service_account_key"),
+				"Invalid Omni Service Account Key",
+				"The Omni API service account key is not a valid base64 encoded service account key, as created by omnictl or the Omni UI: "+err.Error(),
+			)
+		}
+	case configPath != "" || contextName != "":
+		diags.Append(resolveOmniconfigContext(&resolved, configPath, contextName)...)
+	default:
+		diags.AddAttributeError(
+			path.Root("service_account_key"),
+			"Missing Omni Credentials",
+			"The provider cannot create the Omni API client as there are no credentials for the Omni API. "+
+				"Set the service account key in the configuration or use the "+envServiceAccountKey+" environment variable, "+
+				"set a file with the key with service_account_key_file or the "+envServiceAccountKeyFile+" environment variable, "+
+				"or use an omniconfig context with config_path and context or the "+envConfig+" and "+envContext+" environment variables. "+
+				"If any of them is already set, ensure the value is not empty.",
+		)
+	}
+
+	if diags.HasError() {
+		return resolved, diags
+	}
+
+	if resolved.endpoint == "" {
+		diags.AddAttributeError(
+			path.Root("endpoint"),
+			"Missing Omni API Endpoint",
+			"The provider cannot create the Omni API client as there is a missing or empty value for the Omni API endpoint. "+
+				"Set the endpoint value in the configuration or use the "+envEndpoint+" environment variable. "+
+				"If either is already set, ensure the value is not empty.",
+		)
+
+		return resolved, diags
+	}
+
+	if err := validateEndpoint(resolved.endpoint); err != nil {
+		diags.AddAttributeError(path.Root("endpoint"), "Invalid Omni API Endpoint", "The provider cannot create the Omni API client as the Omni API endpoint "+err.Error()+".")
+	}
+
+	return resolved, diags
+}
+
+// resolveOmniconfigContext loads a context of an omniconfig file, which omnictl writes.
+// An empty path uses the default location of the file, an empty context name the
+// current context of the file.
+func resolveOmniconfigContext(resolved *clientConfig, configPath string, contextName string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	conf, err := omniconfig.Init(configPath, false)
+	if err != nil {
+		diags.AddAttributeError(path.Root("config_path"), "Unable to Load Omniconfig", err.Error())
+		return diags
+	}
+
+	if contextName == "" {
+		contextName = conf.Context
+	}
+
+	configContext, err := conf.GetContext(contextName)
+	if err != nil {
+		diags.AddAttributeError(path.Root("context"), "Unknown Omniconfig Context", fmt.Sprintf("The omniconfig %s has no context %q.", conf.Path, contextName))
+		return diags
+	}
+
+	if configContext.Auth.SideroV1.Identity == "" {
+		diags.AddAttributeError(path.Root("context"), "Missing Omniconfig Identity", fmt.Sprintf("The context %q of the omniconfig %s has no identity to authenticate with.", contextName, conf.Path))
+		return diags
+	}
+
+	resolved.contextName = contextName
+	resolved.identity = configContext.Auth.SideroV1.Identity
+
+	if resolved.endpoint == "" && configContext.URL != omniconfig.PlaceholderURL {
+		resolved.endpoint = configContext.URL
+	}
+
+	return diags
+}
+
+// validateEndpoint checks that the endpoint is a URL the Omni client can connect to.
+func validateEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid URL: %w", endpoint, err)
+	}
+
+	switch {
+	case u.Scheme != "https" && u.Scheme != "http" && u.Scheme != "grpc":
+		return fmt.Errorf("%q must use the https, http or grpc scheme, e.g. https://example.omni.siderolabs.io", endpoint)
+	case u.Hostname() == "":
+		return fmt.Errorf("%q has no host", endpoint)
+	case u.Path != "" && u.Path != "/", u.RawQuery != "", u.Fragment != "":
+		return fmt.Errorf("%q must not have a path, query or fragment", endpoint)
+	}
+
+	return nil
+}
+
+// stringOrEnv returns the configured value, or the environment variable if the value
+// is not configured.
+func stringOrEnv(value types.String, envVar string) string {
+	if value.IsNull() || value.IsUnknown() {
+		return os.Getenv(envVar)
+	}
+
+	return value.ValueString()
+}
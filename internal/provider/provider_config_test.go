@@ -0,0 +1,214 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/siderolabs/go-api-signature/pkg/pgp"
+	"github.com/siderolabs/go-api-signature/pkg/serviceaccount"
+)
+
+func testServiceAccountKey(t *testing.T) string {
+	t.Helper()
+
+	key, err := pgp.GenerateKey("terraform", "", "terraform@serviceaccount.omni.sidero.dev", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	encoded, err := serviceaccount.Encode("terraform", key)
+	if err != nil {
+		t.Fatalf("failed to encode service account: %s", err)
+	}
+
+	return encoded
+}
+
+// clearProviderEnv unsets the environment variables of the provider configuration for
+// the duration of the test.
+func clearProviderEnv(t *testing.T) {
+	t.Helper()
+
+	for _, envVar := range []string{envEndpoint, envServiceAccountKey, envServiceAccountKeyFile, envConfig, envContext} {
+		t.Setenv(envVar, "")
+	}
+}
+
+func TestResolveClientConfigServiceAccount(t *testing.T) {
+	clearProviderEnv(t)
+
+	key := testServiceAccountKey(t)
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte(key+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		config OmniProviderModelV0
+		env    map[string]string
+	}{
+		{
+			name:   "configured values",
+			config: OmniProviderModelV0{Endpoint: types.StringValue("https://omni.example.com"), ServiceAccountKey: types.StringValue(key)},
+		},
+		{
+			name:   "key file",
+			config: OmniProviderModelV0{Endpoint: types.StringValue("https://omni.example.com"), ServiceAccountKeyFile: types.StringValue(keyFile)},
+		},
+		{
+			name: "environment",
+			env:  map[string]string{envEndpoint: "https://omni.example.com", envServiceAccountKey: key},
+		},
+		{
+			name: "key file from environment",
+			env:  map[string]string{envEndpoint: "https://omni.example.com", envServiceAccountKeyFile: keyFile},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for envVar, value := range tc.env {
+				t.Setenv(envVar, value)
+			}
+
+			resolved, diags := resolveClientConfig(tc.config)
+			if diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+
+			if resolved.endpoint != "https://omni.example.com" {
+				t.Errorf("unexpected endpoint %q", resolved.endpoint)
+			}
+
+			if resolved.serviceAccountKey != key {
+				t.Errorf("unexpected service account key %q", resolved.serviceAccountKey)
+			}
+		})
+	}
+}
+
+func TestResolveClientConfigOmniconfig(t *testing.T) {
+	clearProviderEnv(t)
+
+	configPath := filepath.Join(t.TempDir(), "config")
+
+	err := os.WriteFile(configPath, []byte(`context: default
+contexts:
+  default:
+    url: https://default.example.com
+    auth:
+      siderov1:
+        identity: default@example.com
+  staging:
+    url: https://staging.example.com
+    auth:
+      siderov1:
+        identity: staging@example.com
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, diags := resolveClientConfig(OmniProviderModelV0{ConfigPath: types.StringValue(configPath)})
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	if resolved.endpoint != "https://default.example.com" || resolved.contextName != "default" || resolved.identity != "default@example.com" {
+		t.Errorf("unexpected current context config %+v", resolved)
+	}
+
+	t.Setenv(envConfig, configPath)
+	t.Setenv(envContext, "staging")
+
+	resolved, diags = resolveClientConfig(OmniProviderModelV0{Endpoint: types.StringValue("https://override.example.com")})
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	if resolved.endpoint != "https://override.example.com" || resolved.contextName != "staging" || resolved.identity != "staging@example.com" {
+		t.Errorf("unexpected staging context config %+v", resolved)
+	}
+
+	_, diags = resolveClientConfig(OmniProviderModelV0{ConfigPath: types.StringValue(configPath), Context: types.StringValue("missing")})
+	if !diags.HasError() || diags.Errors()[0].Summary() != "Unknown Omniconfig Context" {
+		t.Errorf("expected an unknown context error, got %v", diags)
+	}
+}
+
+func TestResolveClientConfigErrors(t *testing.T) {
+	clearProviderEnv(t)
+
+	key := testServiceAccountKey(t)
+
+	for _, tc := range []struct {
+		name    string
+		config  OmniProviderModelV0
+		summary string
+	}{
+		{
+			name:    "no credentials",
+			config:  OmniProviderModelV0{Endpoint: types.StringValue("https://omni.example.com")},
+			summary: "Missing Omni Credentials",
+		},
+		{
+			name:    "malformed key",
+			config:  OmniProviderModelV0{Endpoint: types.StringValue("https://omni.example.com"), ServiceAccountKey: types.StringValue("not-a-key")},
+			summary: "Invalid Omni Service Account Key",
+		},
+		{
+			name:    "missing key file",
+			config:  OmniProviderModelV0{Endpoint: types.StringValue("https://omni.example.com"), ServiceAccountKeyFile: types.StringValue(filepath.Join(t.TempDir(), "missing"))},
+			summary: "Unable to Read Omni Service Account Key",
+		},
+		{
+			name:    "missing endpoint",
+			config:  OmniProviderModelV0{ServiceAccountKey: types.StringValue(key)},
+			summary: "Missing Omni API Endpoint",
+		},
+		{
+			name:    "quoted endpoint",
+			config:  OmniProviderModelV0{Endpoint: types.StringValue(`"https://omni.example.com"`), ServiceAccountKey: types.StringValue(key)},
+			summary: "Invalid Omni API Endpoint",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, diags := resolveClientConfig(tc.config)
+			if !diags.HasError() || diags.Errors()[0].Summary() != tc.summary {
+				t.Errorf("expected %q, got %v", tc.summary, diags)
+			}
+		})
+	}
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	for _, tc := range []struct {
+		endpoint string
+		err      string
+	}{
+		{endpoint: "https://omni.example.com"},
+		{endpoint: "https://omni.example.com:8443/"},
+		{endpoint: "http://127.0.0.1:8080"},
+		{endpoint: "grpc://127.0.0.1:8080"},
+		{endpoint: "omni.example.com", err: "scheme"},
+		{endpoint: "ftp://omni.example.com", err: "scheme"},
+		{endpoint: "https://", err: "no host"},
+		{endpoint: "https://omni.example.com/api", err: "path"},
+		{endpoint: "https://omni .example.com", err: "not a valid URL"},
+	} {
+		t.Run(tc.endpoint, func(t *testing.T) {
+			err := validateEndpoint(tc.endpoint)
+
+			switch {
+			case tc.err == "" && err != nil:
+				t.Errorf("unexpected error: %s", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
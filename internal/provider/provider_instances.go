@@ -0,0 +1,279 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	datasourceschema "github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	resourceschema "github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/go-api-signature/pkg/serviceaccount"
+	"github.com/siderolabs/omni/client/pkg/client"
+)
+
+// OmniProviderInstanceModelV0 describes an Omni instance of the provider.
+type OmniProviderInstanceModelV0 struct {
+	Name              types.String `tfsdk:"name"`
+	Endpoint          types.String `tfsdk:"endpoint"`
+	ServiceAccountKey types.String `tfsdk:"service_account_key"`
+}
+
+// omniInstance is the client of an Omni instance, with the caches of its state.
+type omniInstance struct {
+	client *client.Client
+	// state is the state of the client the resources read and write through.
+	state cosistate.State
+	// cachedState is the state of the client for the data sources, caching the lists
+	// of resources.
+	cachedState cosistate.State
+	// machines caches the machine statuses, nil unless the watch mode is enabled.
+	machines *machineStatusCache
+}
+
+const omniInstanceDescription = "Name of the Omni instance of the provider to use instead of the default instance, as configured in the `instances` blocks of the provider."
+
+// omniInstanceResourceAttribute returns the omni_instance attribute of the resources.
+// Moving a resource to another instance replaces it.
+func omniInstanceResourceAttribute() resourceschema.StringAttribute {
+	return resourceschema.StringAttribute{
+		Description: omniInstanceDescription,
+		Optional:    true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+}
+
+// omniInstanceDataSourceAttribute returns the omni_instance attribute of the data sources.
+func omniInstanceDataSourceAttribute() datasourceschema.StringAttribute {
+	return datasourceschema.StringAttribute{
+		Description: omniInstanceDescription,
+		Optional:    true,
+	}
+}
+
+// omniInstanceOptions are the options shared by the clients of all Omni instances.
+// The limits and caches are separate for each instance.
+type omniInstanceOptions struct {
+	transport    transportConfig
+	retry        retryPolicy
+	rateLimit    float64
+	maxInFlight  int64
+	listCacheTTL time.Duration
+	machineCache string
+}
+
+func (o omniInstanceOptions) newInstance(endpoint string, authOptions ...client.Option) (*omniInstance, error) {
+	endpoint, transportOptions, err := o.transport.options(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	callLimiter := newCallLimiter(o.rateLimit, o.maxInFlight)
+
+	// the retries go through the limits, so that each attempt counts
+	transportOptions = append(transportOptions, client.WithGrpcOpts(slices.Concat(o.retry.grpcOptions(), callLimiter.grpcOptions())...))
+
+	omniClient, err := client.New(endpoint, slices.Concat(authOptions, transportOptions)...)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := &omniInstance{client: omniClient}
+	instance.cachedState, instance.state = newListCachingStates(omniClient.Omni().State(), o.listCacheTTL)
+
+	if o.machineCache == machineCacheWatch {
+		instance.machines = newMachineStatusCache(omniClient.Omni().State())
+	}
+
+	return instance, nil
+}
+
+// omniProviderData is passed by the provider to its resources and data sources. The
+// client of the default instance is created when the provider is configured, and the
+// clients of the named instances when they are first used.
+type omniProviderData struct {
+	defaultInstance *omniInstance
+	options         omniInstanceOptions
+	instanceConfigs map[string]OmniProviderInstanceModelV0
+
+	mu        sync.Mutex
+	instances map[string]*omniInstance
+}
+
+// attributeGetter is the plan, state or configuration of a resource or data source.
+type attributeGetter interface {
+	GetAttribute(ctx context.Context, path path.Path, target any) diag.Diagnostics
+}
+
+// instanceOf returns the Omni instance named by the omni_instance attribute of the
+// plan, state or configuration of a resource or data source.
+func (d *omniProviderData) instanceOf(ctx context.Context, source attributeGetter) (*omniInstance, diag.Diagnostics) {
+	var name types.String
+
+	diags := source.GetAttribute(ctx, path.Root("omni_instance"), &name)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	return d.instance(ctx, name)
+}
+
+// instance returns the Omni instance with the given name, the default instance when
+// the name is null. There is no instance while the name is unknown, at plan time.
+func (d *omniProviderData) instance(ctx context.Context, name types.String) (*omniInstance, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if name.IsUnknown() {
+		return nil, diags
+	}
+
+	if name.IsNull() {
+		if d.defaultInstance == nil {
+			diags.AddAttributeError(
+				path.Root("omni_instance"),
+				"Missing Omni Instance",
+				"The provider has no default Omni instance, as only named instances are configured. Set omni_instance to one of the instances of the provider.",
+			)
+		}
+
+		return d.defaultInstance, diags
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if instance, ok := d.instances[name.ValueString()]; ok {
+		return instance, diags
+	}
+
+	instanceConfig, ok := d.instanceConfigs[name.ValueString()]
+	if !ok {
+		diags.AddAttributeError(
+			path.Root("omni_instance"),
+			"Unknown Omni Instance",
+			fmt.Sprintf("The provider has no Omni instance named %q. The instances of the provider are: %v.", name.ValueString(), d.instanceNames()),
+		)
+
+		return nil, diags
+	}
+
+	tflog.Debug(ctx, "Creating Omni API client", map[string]any{"omni_instance": name.ValueString(), "omni_endpoint": instanceConfig.Endpoint.ValueString()})
+
+	instance, err := d.options.newInstance(instanceConfig.Endpoint.ValueString(), client.WithServiceAccount(instanceConfig.ServiceAccountKey.ValueString()))
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("omni_instance"),
+			"Unable to Create Omni API Client",
+			fmt.Sprintf("An unexpected error occurred when creating the Omni API client of the instance %q.\n\nOmni Client Error: %s", name.ValueString(), err),
+		)
+
+		return nil, diags
+	}
+
+	d.instances[name.ValueString()] = instance
+
+	return instance, diags
+}
+
+func (d *omniProviderData) instanceNames() []string {
+	names := make([]string, 0, len(d.instanceConfigs))
+
+	for name := range d.instanceConfigs {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// resolveInstanceConfigs validates the named instances of the provider configuration.
+func resolveInstanceConfigs(instances []OmniProviderInstanceModelV0) (map[string]OmniProviderInstanceModelV0, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	configs := make(map[string]OmniProviderInstanceModelV0, len(instances))
+
+	for i, instance := range instances {
+		instancePath := path.Root("instances").AtListIndex(i)
+
+		if _, ok := configs[instance.Name.ValueString()]; ok {
+			diags.AddAttributeError(instancePath.AtName("name"), "Duplicate Omni Instance", fmt.Sprintf("The Omni instance %q is configured more than once.", instance.Name.ValueString()))
+		}
+
+		if err := validateEndpoint(instance.Endpoint.ValueString()); err != nil {
+			diags.AddAttributeError(instancePath.AtName("endpoint"), "Invalid Omni API Endpoint", "The Omni API endpoint "+err.Error()+".")
+		}
+
+		if _, err := serviceaccount.Decode(instance.ServiceAccountKey.ValueString()); err != nil {
+			diags.AddAttributeError(
+				instancePath.AtName("service_account_key"),
+				"Invalid Omni Service Account Key",
+				"The Omni API service account key is not a valid base64 encoded service account key, as created by omnictl or the Omni UI: "+err.Error(),
+			)
+		}
+
+		configs[instance.Name.ValueString()] = instance
+	}
+
+	return configs, diags
+}
+
+// defaultInstanceConfigured reports whether the provider configuration or the
+// environment configure the default Omni instance. It is always required when there
+// are no named instances.
+func defaultInstanceConfigured(config OmniProviderModelV0) bool {
+	if len(config.Instances) == 0 {
+		return true
+	}
+
+	for _, value := range []string{
+		stringOrEnv(config.Endpoint, envEndpoint),
+		stringOrEnv(config.ServiceAccountKey, envServiceAccountKey),
+		stringOrEnv(config.ServiceAccountKeyFile, envServiceAccountKeyFile),
+		stringOrEnv(config.ConfigPath, envConfig),
+		stringOrEnv(config.Context, envContext),
+	} {
+		if value != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitImportID splits the name of a configured Omni instance and a slash off an import
+// ID, e.g. `eu/my-cluster`. The instance is null when the ID has no such prefix.
+func (d *omniProviderData) splitImportID(id string) (types.String, string) {
+	if d == nil {
+		return types.StringNull(), id
+	}
+
+	for name := range d.instanceConfigs {
+		if instanceID, ok := strings.CutPrefix(id, name+"/"); ok && instanceID != "" {
+			return types.StringValue(name), instanceID
+		}
+	}
+
+	return types.StringNull(), id
+}
+
+// importStateWithInstance imports a resource by its ID, which may be prefixed with the
+// name of an Omni instance.
+func importStateWithInstance(ctx context.Context, providerData *omniProviderData, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	instanceName, id := providerData.splitImportID(req.ID)
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), id)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("omni_instance"), instanceName)...)
+}
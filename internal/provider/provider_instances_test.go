@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func testInstanceConfig(name, endpoint, key string) OmniProviderInstanceModelV0 {
+	return OmniProviderInstanceModelV0{
+		Name:              types.StringValue(name),
+		Endpoint:          types.StringValue(endpoint),
+		ServiceAccountKey: types.StringValue(key),
+	}
+}
+
+func TestResolveInstanceConfigs(t *testing.T) {
+	key := testServiceAccountKey(t)
+
+	configs, diags := resolveInstanceConfigs([]OmniProviderInstanceModelV0{
+		testInstanceConfig("eu", "https://eu.omni.example.com", key),
+		testInstanceConfig("us", "https://us.omni.example.com", key),
+	})
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if len(configs) != 2 || configs["us"].Endpoint.ValueString() != "https://us.omni.example.com" {
+		t.Errorf("unexpected instance configs: %v", configs)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		instances []OmniProviderInstanceModelV0
+		summary   string
+	}{
+		{
+			name: "duplicate name",
+			instances: []OmniProviderInstanceModelV0{
+				testInstanceConfig("eu", "https://eu.omni.example.com", key),
+				testInstanceConfig("eu", "https://us.omni.example.com", key),
+			},
+			summary: "Duplicate Omni Instance",
+		},
+		{
+			name:      "invalid endpoint",
+			instances: []OmniProviderInstanceModelV0{testInstanceConfig("eu", "eu.omni.example.com", key)},
+			summary:   "Invalid Omni API Endpoint",
+		},
+		{
+			name:      "malformed key",
+			instances: []OmniProviderInstanceModelV0{testInstanceConfig("eu", "https://eu.omni.example.com", "not-a-key")},
+			summary:   "Invalid Omni Service Account Key",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, diags := resolveInstanceConfigs(tc.instances)
+			if !diags.HasError() || diags.Errors()[0].Summary() != tc.summary {
+				t.Errorf("expected %q, got %v", tc.summary, diags)
+			}
+		})
+	}
+}
+
+func TestDefaultInstanceConfigured(t *testing.T) {
+	clearProviderEnv(t)
+
+	instances := []OmniProviderInstanceModelV0{testInstanceConfig("eu", "https://eu.omni.example.com", "key")}
+
+	if !defaultInstanceConfigured(OmniProviderModelV0{}) {
+		t.Error("expected the default instance to be required without named instances")
+	}
+
+	if defaultInstanceConfigured(OmniProviderModelV0{Instances: instances}) {
+		t.Error("expected no default instance with only named instances")
+	}
+
+	if !defaultInstanceConfigured(OmniProviderModelV0{Instances: instances, Endpoint: types.StringValue("https://omni.example.com")}) {
+		t.Error("expected the default instance to be configured by the endpoint")
+	}
+
+	t.Setenv(envEndpoint, "https://omni.example.com")
+
+	if !defaultInstanceConfigured(OmniProviderModelV0{Instances: instances}) {
+		t.Error("expected the default instance to be configured by the environment")
+	}
+}
+
+func TestProviderDataInstance(t *testing.T) {
+	ctx := context.Background()
+	key := testServiceAccountKey(t)
+
+	defaultInstance := &omniInstance{}
+
+	data := &omniProviderData{
+		defaultInstance: defaultInstance,
+		instanceConfigs: map[string]OmniProviderInstanceModelV0{
+			"eu": testInstanceConfig("eu", "https://eu.omni.example.com", key),
+		},
+		instances: map[string]*omniInstance{},
+	}
+
+	instance, diags := data.instance(ctx, types.StringUnknown())
+	if diags.HasError() || instance != nil {
+		t.Errorf("expected no instance while the name is unknown, got %v, %v", instance, diags)
+	}
+
+	instance, diags = data.instance(ctx, types.StringNull())
+	if diags.HasError() || instance != defaultInstance {
+		t.Errorf("expected the default instance, got %v, %v", instance, diags)
+	}
+
+	instance, diags = data.instance(ctx, types.StringValue("eu"))
+	if diags.HasError() || instance == nil || instance == defaultInstance {
+		t.Fatalf("expected the eu instance, got %v, %v", instance, diags)
+	}
+
+	t.Cleanup(func() { instance.client.Close() }) //nolint:errcheck
+
+	again, diags := data.instance(ctx, types.StringValue("eu"))
+	if diags.HasError() || again != instance {
+		t.Errorf("expected the eu instance to be created once, got %v, %v", again, diags)
+	}
+
+	_, diags = data.instance(ctx, types.StringValue("us"))
+	if !diags.HasError() || diags.Errors()[0].Summary() != "Unknown Omni Instance" {
+		t.Errorf("expected an unknown instance error, got %v", diags)
+	}
+
+	data.defaultInstance = nil
+
+	_, diags = data.instance(ctx, types.StringNull())
+	if !diags.HasError() || diags.Errors()[0].Summary() != "Missing Omni Instance" {
+		t.Errorf("expected a missing instance error, got %v", diags)
+	}
+}
+
+func TestSplitImportID(t *testing.T) {
+	data := &omniProviderData{
+		instanceConfigs: map[string]OmniProviderInstanceModelV0{
+			"eu": {},
+		},
+	}
+
+	for _, tc := range []struct {
+		id       string
+		instance types.String
+		rest     string
+	}{
+		{id: "my-cluster", instance: types.StringNull(), rest: "my-cluster"},
+		{id: "eu/my-cluster", instance: types.StringValue("eu"), rest: "my-cluster"},
+		{id: "us/my-cluster", instance: types.StringNull(), rest: "us/my-cluster"},
+		{id: "eu/", instance: types.StringNull(), rest: "eu/"},
+	} {
+		t.Run(tc.id, func(t *testing.T) {
+			instance, rest := data.splitImportID(tc.id)
+			if !instance.Equal(tc.instance) || rest != tc.rest {
+				t.Errorf("expected %v, %q, got %v, %q", tc.instance, tc.rest, instance, rest)
+			}
+		})
+	}
+
+	var nilData *omniProviderData
+
+	if instance, rest := nilData.splitImportID("eu/my-cluster"); !instance.IsNull() || rest != "eu/my-cluster" {
+		t.Errorf("expected the ID unchanged without provider data, got %v, %q", instance, rest)
+	}
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+)
+
+// watchMethod is the COSI method streaming the changes of resources. Watches last
+// for as long as their callers wait, so they do not count as in-flight calls.
+const watchMethod = "/cosi.resource.State/Watch"
+
+// callLimiter bounds the rate of the calls to the Omni API and the number of calls in
+// flight. A zero rate or number of calls leaves them unbounded.
+type callLimiter struct {
+	limiter  *rate.Limiter
+	inFlight *semaphore.Weighted
+}
+
+func newCallLimiter(ratePerSecond float64, maxInFlight int64) callLimiter {
+	var limiter callLimiter
+
+	if ratePerSecond > 0 {
+		limiter.limiter = rate.NewLimiter(rate.Limit(ratePerSecond), max(1, int(ratePerSecond)))
+	}
+
+	if maxInFlight > 0 {
+		limiter.inFlight = semaphore.NewWeighted(maxInFlight)
+	}
+
+	return limiter
+}
+
+// acquire waits until a call may be sent, and returns the function to call once it is done.
+func (l callLimiter) acquire(ctx context.Context, method string) (func(), error) {
+	if l.limiter != nil {
+		if err := l.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.inFlight == nil || method == watchMethod {
+		return func() {}, nil
+	}
+
+	if err := l.inFlight.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+
+	return sync.OnceFunc(func() { l.inFlight.Release(1) }), nil
+}
+
+func (l callLimiter) grpcOptions() []grpc.DialOption {
+	if l.limiter == nil && l.inFlight == nil {
+		return nil
+	}
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(l.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(l.streamInterceptor),
+	}
+}
+
+func (l callLimiter) unaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	release, err := l.acquire(ctx, method)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (l callLimiter) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	release, err := l.acquire(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		release()
+
+		return nil, err
+	}
+
+	// the callers stop receiving on errors, and at the latest when their context is done
+	context.AfterFunc(ctx, release)
+
+	return &releasingStream{ClientStream: stream, release: release}, nil
+}
+
+// releasingStream releases its in-flight call once it has finished.
+type releasingStream struct {
+	grpc.ClientStream
+
+	release func()
+}
+
+func (s *releasingStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.release()
+	}
+
+	return err
+}
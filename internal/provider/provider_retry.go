@@ -0,0 +1,282 @@
+package provider
+
+import (
+	"context"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-timetypes/timetypes"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults of the retry policy.
+const (
+	defaultRetryMaxAttempts = 5
+	defaultRetryMinBackoff  = time.Second
+	defaultRetryMaxBackoff  = 30 * time.Second
+)
+
+// retryWindow bounds the time spent retrying a call. Retries reuse the signature of
+// the first attempt, which Omni only accepts within five minutes of its timestamp.
+const retryWindow = 4 * time.Minute
+
+// idempotentMethods are the Omni API methods which are safe to send again after
+// Omni became unavailable while it may have been processing them.
+var idempotentMethods = map[string]struct{}{
+	"/cosi.resource.State/Get":                                 {},
+	"/cosi.resource.State/List":                                {},
+	"/cosi.resource.State/Watch":                               {},
+	"/management.ManagementService/Kubeconfig":                 {},
+	"/management.ManagementService/Talosconfig":                {},
+	"/management.ManagementService/Omniconfig":                 {},
+	"/management.ManagementService/ValidateConfig":             {},
+	"/management.ManagementService/ValidateJSONSchema":         {},
+	"/management.ManagementService/ListServiceAccounts":        {},
+	"/management.ManagementService/KubernetesUpgradePreChecks": {},
+	"/management.ManagementService/CreateSchematic":            {},
+	"/management.ManagementService/GetMachineJoinConfig":       {},
+}
+
+// OmniProviderRetryModelV0 describes the retry block of the provider.
+type OmniProviderRetryModelV0 struct {
+	MaxAttempts types.Int64          `tfsdk:"max_attempts"`
+	MinBackoff  timetypes.GoDuration `tfsdk:"min_backoff"`
+	MaxBackoff  timetypes.GoDuration `tfsdk:"max_backoff"`
+}
+
+// retryPolicy retries the Omni API calls failing with transient errors, with an
+// exponential backoff.
+type retryPolicy struct {
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+}
+
+// resolveRetryPolicy builds the retry policy from the retry block, falling back to
+// the defaults for the unset values.
+func resolveRetryPolicy(config *OmniProviderRetryModelV0) (retryPolicy, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	policy := retryPolicy{
+		maxAttempts: defaultRetryMaxAttempts,
+		minBackoff:  defaultRetryMinBackoff,
+		maxBackoff:  defaultRetryMaxBackoff,
+	}
+
+	if config == nil {
+		return policy, diags
+	}
+
+	if !config.MaxAttempts.IsNull() && !config.MaxAttempts.IsUnknown() {
+		policy.maxAttempts = int(config.MaxAttempts.ValueInt64())
+	}
+
+	if !config.MinBackoff.IsNull() && !config.MinBackoff.IsUnknown() {
+		minBackoff, valueDiags := config.MinBackoff.ValueGoDuration()
+		diags.Append(valueDiags...)
+
+		policy.minBackoff = minBackoff
+	}
+
+	if !config.MaxBackoff.IsNull() && !config.MaxBackoff.IsUnknown() {
+		maxBackoff, valueDiags := config.MaxBackoff.ValueGoDuration()
+		diags.Append(valueDiags...)
+
+		policy.maxBackoff = maxBackoff
+	}
+
+	if policy.minBackoff <= 0 {
+		diags.AddAttributeError(path.Root("retry").AtName("min_backoff"), "Invalid Retry Backoff", "The minimum backoff must be positive.")
+	}
+
+	if policy.maxBackoff < policy.minBackoff {
+		diags.AddAttributeError(path.Root("retry").AtName("max_backoff"), "Invalid Retry Backoff", "The maximum backoff must not be lower than the minimum backoff.")
+	}
+
+	return policy, diags
+}
+
+// retryable reports whether a call to method failing with err may be sent again.
+// Omni rejects the calls it is too busy for before processing them, so these are
+// always retried, while calls failing because Omni is unavailable are only retried
+// when they are idempotent. gRPC also reports messages exceeding the message size
+// limits as exhausted resources, which fail the same way when sent again.
+func retryable(method string, err error) bool {
+	switch status.Code(err) { //nolint:exhaustive
+	case codes.ResourceExhausted:
+		return !strings.Contains(status.Convert(err).Message(), "message larger than max")
+	case codes.Unavailable:
+		_, ok := idempotentMethods[method]
+
+		return ok
+	default:
+		return false
+	}
+}
+
+// backoff returns the time to wait before the given retry, starting at 1.
+func (p retryPolicy) backoff(retry int) time.Duration {
+	backoff := p.maxBackoff
+
+	if shift := retry - 1; shift < 32 && p.minBackoff<<shift < p.maxBackoff {
+		backoff = p.minBackoff << shift
+	}
+
+	// jitter the backoff by up to a half, so that parallel calls do not retry at once
+	return backoff/2 + rand.N(backoff/2+1) //nolint:gosec
+}
+
+// do runs call until it succeeds, fails with an error which is not retryable, or the
+// attempts are exhausted.
+func (p retryPolicy) do(ctx context.Context, method string, call func() error) error {
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= p.maxAttempts || !retryable(method, err) {
+			return err
+		}
+
+		backoff := p.backoff(attempt)
+		if time.Since(start)+backoff > retryWindow {
+			return err
+		}
+
+		tflog.Warn(ctx, "Retrying Omni API call", map[string]any{
+			"method":  method,
+			"code":    status.Code(err).String(),
+			"error":   err.Error(),
+			"attempt": attempt,
+			"backoff": backoff.String(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// grpcOptions returns the interceptors applying the retry policy. Streams are retried
+// when they fail to be established, and server streams, like the COSI lists and
+// watches, also when they fail before their first response.
+func (p retryPolicy) grpcOptions() []grpc.DialOption {
+	if p.maxAttempts <= 1 {
+		return nil
+	}
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(p.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(p.streamInterceptor),
+	}
+}
+
+func (p retryPolicy) unaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return p.do(ctx, method, func() error {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	})
+}
+
+func (p retryPolicy) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	open := func() (grpc.ClientStream, error) {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+
+	var stream grpc.ClientStream
+
+	err := p.do(ctx, method, func() error {
+		var err error
+
+		stream, err = open()
+
+		return err
+	})
+	if err != nil || !desc.ServerStreams || desc.ClientStreams {
+		return stream, err
+	}
+
+	return &retryingClientStream{ClientStream: stream, policy: p, ctx: ctx, method: method, open: open}, nil
+}
+
+// retryingClientStream opens a server stream again when it fails before the first
+// response, as Omni reports the errors of streaming calls on the first receive. The
+// request is sent again on the new stream.
+type retryingClientStream struct {
+	grpc.ClientStream
+
+	policy retryPolicy
+	ctx    context.Context //nolint:containedctx
+	method string
+	open   func() (grpc.ClientStream, error)
+
+	request   any
+	closeSend bool
+	received  bool
+}
+
+func (s *retryingClientStream) SendMsg(m any) error {
+	s.request = m
+
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *retryingClientStream) CloseSend() error {
+	s.closeSend = true
+
+	return s.ClientStream.CloseSend()
+}
+
+func (s *retryingClientStream) RecvMsg(m any) error {
+	if s.received {
+		return s.ClientStream.RecvMsg(m)
+	}
+
+	reopen := false
+
+	err := s.policy.do(s.ctx, s.method, func() error {
+		if reopen {
+			if err := s.reopen(); err != nil {
+				return err
+			}
+		}
+
+		reopen = true
+
+		return s.ClientStream.RecvMsg(m)
+	})
+	if err == nil {
+		s.received = true
+	}
+
+	return err
+}
+
+// reopen replaces the stream with a new one carrying the same request.
+func (s *retryingClientStream) reopen() error {
+	stream, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	s.ClientStream = stream
+
+	if s.request != nil {
+		if err = stream.SendMsg(s.request); err != nil {
+			return err
+		}
+	}
+
+	if s.closeSend {
+		return stream.CloseSend()
+	}
+
+	return nil
+}
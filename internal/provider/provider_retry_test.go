@@ -0,0 +1,235 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/api/v1alpha1"
+	"github.com/hashicorp/terraform-plugin-framework-timetypes/timetypes"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		method    string
+		err       error
+		retryable bool
+	}{
+		{method: "/cosi.resource.State/List", err: status.Error(codes.Unavailable, "connection refused"), retryable: true},
+		{method: "/management.ManagementService/Kubeconfig", err: status.Error(codes.Unavailable, "connection refused"), retryable: true},
+		{method: "/cosi.resource.State/Create", err: status.Error(codes.Unavailable, "connection reset"), retryable: false},
+		{method: "/cosi.resource.State/Create", err: status.Error(codes.ResourceExhausted, "rate limited"), retryable: true},
+		{method: "/cosi.resource.State/List", err: status.Error(codes.ResourceExhausted, "grpc: received message larger than max (5242880 vs. 4194304)"), retryable: false},
+		{method: "/cosi.resource.State/Create", err: status.Error(codes.ResourceExhausted, "grpc: trying to send message larger than max (5242880 vs. 4194304)"), retryable: false},
+		{method: "/cosi.resource.State/Get", err: status.Error(codes.NotFound, "not found"), retryable: false},
+		{method: "/cosi.resource.State/Get", err: errors.New("plain error"), retryable: false},
+	} {
+		if retryable(tc.method, tc.err) != tc.retryable {
+			t.Errorf("expected retryable %v for %s failing with %v", tc.retryable, tc.method, tc.err)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := retryPolicy{maxAttempts: 10, minBackoff: time.Second, maxBackoff: 5 * time.Second}
+
+	for retry, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 64: 5 * time.Second} {
+		if backoff := policy.backoff(retry); backoff < expected/2 || backoff > expected {
+			t.Errorf("expected backoff of retry %d within [%s, %s], got %s", retry, expected/2, expected, backoff)
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	policy := retryPolicy{maxAttempts: 3, minBackoff: time.Millisecond, maxBackoff: time.Millisecond}
+
+	for _, tc := range []struct {
+		name     string
+		method   string
+		errs     []error
+		attempts int
+		err      codes.Code
+	}{
+		{
+			name:     "recovers",
+			method:   "/cosi.resource.State/List",
+			errs:     []error{status.Error(codes.Unavailable, ""), nil},
+			attempts: 2,
+			err:      codes.OK,
+		},
+		{
+			name:     "exhausts the attempts",
+			method:   "/cosi.resource.State/List",
+			errs:     []error{status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""), nil},
+			attempts: 3,
+			err:      codes.Unavailable,
+		},
+		{
+			name:     "does not resend writes",
+			method:   "/cosi.resource.State/Update",
+			errs:     []error{status.Error(codes.Unavailable, ""), nil},
+			attempts: 1,
+			err:      codes.Unavailable,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+
+			err := policy.do(t.Context(), tc.method, func() error {
+				attempts++
+
+				return tc.errs[attempts-1]
+			})
+
+			if status.Code(err) != tc.err {
+				t.Errorf("expected %s, got %v", tc.err, err)
+			}
+
+			if attempts != tc.attempts {
+				t.Errorf("expected %d attempts, got %d", tc.attempts, attempts)
+			}
+		})
+	}
+
+	attempts := 0
+
+	policy.maxBackoff = time.Hour
+	policy.minBackoff = time.Hour
+
+	err := policy.do(t.Context(), "/cosi.resource.State/Get", func() error {
+		attempts++
+
+		return status.Error(codes.Unavailable, "")
+	})
+	if status.Code(err) != codes.Unavailable || attempts != 1 {
+		t.Errorf("expected a single attempt once the backoff exceeds the retry window, got %d attempts and %v", attempts, err)
+	}
+}
+
+// failingListServer fails the first lists with the given error once the stream is
+// established, as Omni does.
+type failingListServer struct {
+	v1alpha1.UnimplementedStateServer
+
+	failures int64
+	err      error
+	lists    atomic.Int64
+}
+
+func (s *failingListServer) List(req *v1alpha1.ListRequest, srv grpc.ServerStreamingServer[v1alpha1.ListResponse]) error {
+	if s.lists.Add(1) <= s.failures {
+		return s.err
+	}
+
+	return srv.Send(&v1alpha1.ListResponse{Resource: &v1alpha1.Resource{Metadata: &v1alpha1.Metadata{Namespace: req.GetNamespace(), Type: req.GetType(), Id: "machine"}}})
+}
+
+func TestRetryPolicyStream(t *testing.T) {
+	policy := retryPolicy{maxAttempts: 3, minBackoff: time.Millisecond, maxBackoff: time.Millisecond}
+
+	for _, tc := range []struct {
+		name     string
+		failures int64
+		err      error
+		lists    int64
+		code     codes.Code
+	}{
+		{name: "recovers", failures: 2, err: status.Error(codes.Unavailable, "omni is restarting"), lists: 3, code: codes.OK},
+		{name: "exhausts the attempts", failures: 3, err: status.Error(codes.Unavailable, "omni is restarting"), lists: 3, code: codes.Unavailable},
+		{name: "does not retry other errors", failures: 1, err: status.Error(codes.PermissionDenied, "denied"), lists: 1, code: codes.PermissionDenied},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			listener := bufconn.Listen(1024 * 1024)
+			server := grpc.NewServer()
+			stateServer := &failingListServer{failures: tc.failures, err: tc.err}
+
+			v1alpha1.RegisterStateServer(server, stateServer)
+
+			go server.Serve(listener) //nolint:errcheck
+			t.Cleanup(server.Stop)
+
+			conn, err := grpc.NewClient("passthrough:///omni",
+				append(policy.grpcOptions(),
+					grpc.WithTransportCredentials(insecure.NewCredentials()),
+					grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+				)...,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Cleanup(func() { conn.Close() }) //nolint:errcheck
+
+			stream, err := v1alpha1.NewStateClient(conn).List(t.Context(), &v1alpha1.ListRequest{Namespace: "default", Type: "MachineStatuses.omni.sidero.dev"})
+			if err != nil {
+				t.Fatalf("expected the stream to be established, got %v", err)
+			}
+
+			resp, err := stream.Recv()
+			if status.Code(err) != tc.code {
+				t.Fatalf("expected %s, got %v", tc.code, err)
+			}
+
+			if err == nil {
+				if id := resp.GetResource().GetMetadata().GetId(); id != "machine" {
+					t.Errorf("expected the listed resource of the request, got %q", id)
+				}
+
+				if _, err = stream.Recv(); !errors.Is(err, io.EOF) {
+					t.Errorf("expected the end of the stream, got %v", err)
+				}
+			}
+
+			if lists := stateServer.lists.Load(); lists != tc.lists {
+				t.Errorf("expected %d lists, got %d", tc.lists, lists)
+			}
+		})
+	}
+}
+
+func TestResolveRetryPolicy(t *testing.T) {
+	policy, diags := resolveRetryPolicy(nil)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	if policy != (retryPolicy{maxAttempts: defaultRetryMaxAttempts, minBackoff: defaultRetryMinBackoff, maxBackoff: defaultRetryMaxBackoff}) {
+		t.Errorf("unexpected default policy %+v", policy)
+	}
+
+	policy, diags = resolveRetryPolicy(&OmniProviderRetryModelV0{
+		MaxAttempts: types.Int64Value(1),
+		MinBackoff:  timetypes.NewGoDurationValueFromStringMust("100ms"),
+		MaxBackoff:  timetypes.NewGoDurationNull(),
+	})
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	if policy.maxAttempts != 1 || policy.minBackoff != 100*time.Millisecond || policy.maxBackoff != defaultRetryMaxBackoff {
+		t.Errorf("unexpected policy %+v", policy)
+	}
+
+	if policy.grpcOptions() != nil {
+		t.Error("expected no interceptors with a single attempt")
+	}
+
+	_, diags = resolveRetryPolicy(&OmniProviderRetryModelV0{
+		MaxAttempts: types.Int64Null(),
+		MinBackoff:  timetypes.NewGoDurationValueFromStringMust("1m"),
+		MaxBackoff:  timetypes.NewGoDurationValueFromStringMust("10s"),
+	})
+	if !diags.HasError() || diags.Errors()[0].Summary() != "Invalid Retry Backoff" {
+		t.Errorf("expected an invalid backoff error, got %v", diags)
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
 const (
@@ -32,3 +35,39 @@ func testAccPreCheck(t *testing.T) {
 	// about the appropriate environment variables being set are common to see in a pre-check
 	// function.
 }
+
+// testResourceConfig returns the configuration of a resource with the given attribute
+// values, and all other attributes null.
+func testResourceConfig(t *testing.T, r resource.Resource, values map[string]tftypes.Value) tfsdk.Config {
+	t.Helper()
+
+	ctx := t.Context()
+
+	var resp resource.SchemaResponse
+
+	r.Schema(ctx, resource.SchemaRequest{}, &resp)
+
+	objectType, ok := resp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	if !ok {
+		t.Fatalf("unexpected schema type %T", resp.Schema.Type().TerraformType(ctx))
+	}
+
+	attributes := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+
+	for name, attributeType := range objectType.AttributeTypes {
+		attributes[name] = tftypes.NewValue(attributeType, nil)
+	}
+
+	for name, value := range values {
+		if _, ok := attributes[name]; !ok {
+			t.Fatalf("unknown attribute %q", name)
+		}
+
+		attributes[name] = value
+	}
+
+	return tfsdk.Config{
+		Schema: resp.Schema,
+		Raw:    tftypes.NewValue(objectType, attributes),
+	}
+}
@@ -0,0 +1,321 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/siderolabs/omni/client/pkg/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+)
+
+// transportConfig holds the transport options of the Omni API client.
+type transportConfig struct {
+	tlsConfig            *tls.Config
+	insecureSkipVerify   bool
+	proxyURL             *url.URL
+	tcpKeepaliveInterval time.Duration
+	maxRecvMsgSize       int
+	maxSendMsgSize       int
+	userAgent            string
+}
+
+// resolveTransportConfig builds the transport options from the provider configuration.
+func resolveTransportConfig(config OmniProviderModelV0, version string) (transportConfig, diag.Diagnostics) {
+	var (
+		transport transportConfig
+		diags     diag.Diagnostics
+	)
+
+	transport.insecureSkipVerify = config.InsecureSkipVerify.ValueBool()
+	transport.maxRecvMsgSize = int(config.MaxRecvMsgSize.ValueInt64())
+	transport.maxSendMsgSize = int(config.MaxSendMsgSize.ValueInt64())
+	transport.userAgent = providerUserAgent(version, config.UserAgent.ValueString())
+
+	if !config.TCPKeepaliveInterval.IsNull() && !config.TCPKeepaliveInterval.IsUnknown() {
+		interval, valueDiags := config.TCPKeepaliveInterval.ValueGoDuration()
+		diags.Append(valueDiags...)
+
+		transport.tcpKeepaliveInterval = interval
+	}
+
+	if proxy := config.ProxyURL.ValueString(); proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || (proxyURL.Scheme != "http" && proxyURL.Scheme != "https") || proxyURL.Host == "" {
+			diags.AddAttributeError(path.Root("proxy_url"), "Invalid Proxy URL", fmt.Sprintf("The proxy URL %q must be an http:// or https:// URL.", proxy))
+		} else {
+			transport.proxyURL = proxyURL
+		}
+	}
+
+	caBundle := config.CABundle.ValueString()
+	clientCertificate := config.ClientCertificate.ValueString()
+
+	if caBundle == "" && clientCertificate == "" {
+		return transport, diags
+	}
+
+	transport.tlsConfig = &tls.Config{
+		InsecureSkipVerify: transport.insecureSkipVerify, //nolint:gosec
+		NextProtos:         []string{"h2"},
+	}
+
+	if caBundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM([]byte(caBundle)) {
+			diags.AddAttributeError(path.Root("ca_bundle"), "Invalid CA Bundle", "The CA bundle contains no PEM encoded certificates.")
+		}
+
+		transport.tlsConfig.RootCAs = pool
+	}
+
+	if clientCertificate != "" {
+		certificate, err := tls.X509KeyPair([]byte(clientCertificate), []byte(config.ClientKey.ValueString()))
+		if err != nil {
+			diags.AddAttributeError(path.Root("client_certificate"), "Invalid Client Certificate", "The client certificate and key are not a valid PEM encoded key pair: "+err.Error())
+		}
+
+		transport.tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return transport, diags
+}
+
+// options returns the client options of the transport configuration, and the endpoint
+// to pass to the client. The client always sets up TLS itself with the system roots,
+// and its transport options override the ones passed to it, so custom TLS is done by
+// the dialer of the connection instead, and the client sees a plain endpoint.
+func (t transportConfig) options(endpoint string) (string, []client.Option, error) {
+	grpcOpts := []grpc.DialOption{
+		grpc.WithUserAgent(t.userAgent),
+	}
+
+	if t.maxRecvMsgSize > 0 || t.maxSendMsgSize > 0 {
+		grpcOpts = append(grpcOpts,
+			grpc.WithChainUnaryInterceptor(t.unaryMessageSizeInterceptor),
+			grpc.WithChainStreamInterceptor(t.streamMessageSizeInterceptor),
+		)
+	}
+
+	opts := []client.Option{}
+
+	if t.tlsConfig == nil && t.proxyURL == nil && t.tcpKeepaliveInterval == 0 {
+		opts = append(opts, client.WithInsecureSkipTLSVerify(t.insecureSkipVerify), client.WithGrpcOpts(grpcOpts...))
+
+		return endpoint, opts, nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var tlsConfig *tls.Config
+
+	if u.Scheme == "https" {
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), "443")
+		}
+
+		if t.tlsConfig != nil {
+			tlsConfig = t.tlsConfig.Clone()
+			tlsConfig.ServerName = u.Hostname()
+
+			u.Scheme = "grpc"
+		}
+	} else if t.tlsConfig != nil {
+		return "", nil, fmt.Errorf("the CA bundle and client certificate require an https endpoint, got %q", endpoint)
+	}
+
+	grpcOpts = append(grpcOpts,
+		grpc.WithContextDialer(t.dialer(tlsConfig)),
+		grpc.WithResolvers(passthroughResolverBuilder{Builder: resolver.Get("passthrough")}),
+	)
+	opts = append(opts, client.WithInsecureSkipTLSVerify(t.insecureSkipVerify), client.WithGrpcOpts(grpcOpts...))
+
+	return u.String(), opts, nil
+}
+
+// passthroughResolverBuilder replaces the DNS resolver of the connection, so that the
+// dialer gets the host name of Omni: the proxy may be the only one able to resolve it,
+// and it is matched against NO_PROXY.
+type passthroughResolverBuilder struct {
+	resolver.Builder
+}
+
+func (passthroughResolverBuilder) Scheme() string {
+	return "dns"
+}
+
+// dialer connects to Omni, through the proxy if one is configured or set in the
+// environment, and sets up TLS if tlsConfig is not nil.
+func (t transportConfig) dialer(tlsConfig *tls.Config) func(context.Context, string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		dialer := &net.Dialer{KeepAlive: t.tcpKeepaliveInterval}
+
+		proxyURL := t.proxyURL
+		if proxyURL == nil {
+			var err error
+
+			proxyURL, err = http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: "https", Host: addr}})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var (
+			conn net.Conn
+			err  error
+		)
+
+		if proxyURL != nil {
+			conn, err = dialProxy(ctx, dialer, proxyURL, addr)
+		} else {
+			conn, err = dialer.DialContext(ctx, "tcp", addr)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if tlsConfig == nil {
+			return conn, nil
+		}
+
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close() //nolint:errcheck
+
+			return nil, err
+		}
+
+		return tlsConn, nil
+	}
+}
+
+// dialProxy opens a tunnel to addr through an HTTP CONNECT proxy.
+func dialProxy(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to proxy %s: %w", proxyURL.Redacted(), err)
+	}
+
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close() //nolint:errcheck
+
+			return nil, fmt.Errorf("failed to connect to proxy %s: %w", proxyURL.Redacted(), err)
+		}
+
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)))
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)          //nolint:errcheck
+		defer conn.SetDeadline(time.Time{}) //nolint:errcheck
+	}
+
+	if err = req.Write(conn); err != nil {
+		conn.Close() //nolint:errcheck
+
+		return nil, fmt.Errorf("failed to send CONNECT to proxy %s: %w", proxyURL.Redacted(), err)
+	}
+
+	reader := bufio.NewReader(conn)
+
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close() //nolint:errcheck
+
+		return nil, fmt.Errorf("failed to read CONNECT response of proxy %s: %w", proxyURL.Redacted(), err)
+	}
+
+	resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close() //nolint:errcheck
+
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", proxyURL.Redacted(), addr, resp.Status)
+	}
+
+	if reader.Buffered() > 0 {
+		conn.Close() //nolint:errcheck
+
+		return nil, fmt.Errorf("proxy %s sent unexpected data after the CONNECT response", proxyURL.Redacted())
+	}
+
+	return conn, nil
+}
+
+// The Omni client sets its own message size limits after the ones passed to it, so the
+// limits are set on each call, where they take precedence.
+func (t transportConfig) callOptions() []grpc.CallOption {
+	var opts []grpc.CallOption
+
+	if t.maxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxCallRecvMsgSize(t.maxRecvMsgSize))
+	}
+
+	if t.maxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxCallSendMsgSize(t.maxSendMsgSize))
+	}
+
+	return opts
+}
+
+func (t transportConfig) unaryMessageSizeInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(ctx, method, req, reply, cc, append(opts, t.callOptions()...)...)
+}
+
+func (t transportConfig) streamMessageSizeInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(ctx, desc, cc, method, append(opts, t.callOptions()...)...)
+}
+
+// providerUserAgent returns the user agent of the provider, with the configured suffix.
+func providerUserAgent(version string, suffix string) string {
+	userAgent := "terraform-provider-omni/" + version
+
+	if suffix = strings.TrimSpace(suffix); suffix != "" {
+		userAgent += " " + suffix
+	}
+
+	return userAgent
+}
@@ -0,0 +1,229 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// testConnectProxy starts an HTTP CONNECT proxy, and returns its URL and a channel
+// receiving the addresses tunneled to.
+func testConnectProxy(t *testing.T) (*url.URL, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { listener.Close() }) //nolint:errcheck
+
+	tunneled := make(chan string, 1)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close() //nolint:errcheck
+
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil || req.Method != http.MethodConnect {
+					return
+				}
+
+				tunneled <- req.Host
+
+				target, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n") //nolint:errcheck
+
+					return
+				}
+
+				defer target.Close() //nolint:errcheck
+
+				io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n") //nolint:errcheck
+
+				go io.Copy(target, conn) //nolint:errcheck
+				io.Copy(conn, target)    //nolint:errcheck
+			}()
+		}
+	}()
+
+	return &url.URL{Scheme: "http", Host: listener.Addr().String()}, tunneled
+}
+
+func TestTransportDialer(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.EnableHTTP2 = true
+	server.StartTLS()
+
+	t.Cleanup(server.Close)
+
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	serverAddr := server.Listener.Addr().String()
+
+	proxyURL, tunneled := testConnectProxy(t)
+
+	transport, diags := resolveTransportConfig(OmniProviderModelV0{
+		CABundle: types.StringValue(string(caBundle)),
+		ProxyURL: types.StringValue(proxyURL.String()),
+	}, "test")
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	endpoint, _, err := transport.options("https://" + serverAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if endpoint != "grpc://"+serverAddr {
+		t.Errorf("unexpected endpoint %q", endpoint)
+	}
+
+	tlsConfig := transport.tlsConfig.Clone()
+	tlsConfig.ServerName = "127.0.0.1"
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	conn, err := transport.dialer(tlsConfig)(ctx, serverAddr)
+	if err != nil {
+		t.Fatalf("failed to dial through the proxy: %s", err)
+	}
+
+	conn.Close() //nolint:errcheck
+
+	if addr := <-tunneled; addr != serverAddr {
+		t.Errorf("unexpected tunneled address %q", addr)
+	}
+
+	if _, err = (transportConfig{}).dialer(&tls.Config{ServerName: "127.0.0.1"})(ctx, serverAddr); err == nil {
+		t.Error("expected the server certificate to be untrusted without the CA bundle")
+	}
+}
+
+func TestTransportOptionsEndpoint(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "")
+
+	for _, tc := range []struct {
+		name     string
+		config   OmniProviderModelV0
+		endpoint string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "defaults",
+			endpoint: "https://omni.example.com",
+			expected: "https://omni.example.com",
+		},
+		{
+			name:     "proxy keeps the client TLS",
+			config:   OmniProviderModelV0{ProxyURL: types.StringValue("http://proxy.example.com:3128")},
+			endpoint: "https://omni.example.com",
+			expected: "https://omni.example.com:443",
+		},
+		{
+			name:     "custom TLS",
+			config:   OmniProviderModelV0{InsecureSkipVerify: types.BoolValue(true), CABundle: types.StringValue(testCABundle(t))},
+			endpoint: "https://omni.example.com:8443",
+			expected: "grpc://omni.example.com:8443",
+		},
+		{
+			name:     "custom TLS with a plain endpoint",
+			config:   OmniProviderModelV0{CABundle: types.StringValue(testCABundle(t))},
+			endpoint: "http://omni.example.com",
+			err:      true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			transport, diags := resolveTransportConfig(tc.config, "test")
+			if diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+
+			endpoint, _, err := transport.options(tc.endpoint)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error, got endpoint %q", endpoint)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if endpoint != tc.expected {
+				t.Errorf("expected endpoint %q, got %q", tc.expected, endpoint)
+			}
+		})
+	}
+}
+
+func testCABundle(t *testing.T) string {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	server.Close()
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+}
+
+func TestResolveTransportConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		config  OmniProviderModelV0
+		summary string
+	}{
+		{
+			name:    "malformed CA bundle",
+			config:  OmniProviderModelV0{CABundle: types.StringValue("not a certificate")},
+			summary: "Invalid CA Bundle",
+		},
+		{
+			name:    "malformed client certificate",
+			config:  OmniProviderModelV0{ClientCertificate: types.StringValue("not a certificate"), ClientKey: types.StringValue("not a key")},
+			summary: "Invalid Client Certificate",
+		},
+		{
+			name:    "proxy without scheme",
+			config:  OmniProviderModelV0{ProxyURL: types.StringValue("proxy.example.com:3128")},
+			summary: "Invalid Proxy URL",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, diags := resolveTransportConfig(tc.config, "test")
+			if !diags.HasError() || diags.Errors()[0].Summary() != tc.summary {
+				t.Errorf("expected %q, got %v", tc.summary, diags)
+			}
+		})
+	}
+}
+
+func TestProviderUserAgent(t *testing.T) {
+	if userAgent := providerUserAgent("1.2.3", ""); userAgent != "terraform-provider-omni/1.2.3" {
+		t.Errorf("unexpected user agent %q", userAgent)
+	}
+
+	if userAgent := providerUserAgent("1.2.3", " platform-team "); userAgent != "terraform-provider-omni/1.2.3 platform-team" {
+		t.Errorf("unexpected user agent %q", userAgent)
+	}
+}
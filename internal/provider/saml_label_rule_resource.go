@@ -0,0 +1,278 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/pkg/cosi/labels"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/auth"
+)
+
+var (
+	_ resource.Resource                   = &omniSAMLLabelRuleResource{}
+	_ resource.ResourceWithConfigure      = &omniSAMLLabelRuleResource{}
+	_ resource.ResourceWithImportState    = &omniSAMLLabelRuleResource{}
+	_ resource.ResourceWithValidateConfig = &omniSAMLLabelRuleResource{}
+)
+
+type omniSAMLLabelRuleResource struct {
+	providerData *omniProviderData
+}
+
+type OmniSAMLLabelRuleModelV0 struct {
+	ID                       types.String `tfsdk:"id"`
+	CreatedAt                types.String `tfsdk:"created_at"`
+	LastUpdated              types.String `tfsdk:"last_updated"`
+	Name                     types.String `tfsdk:"name"`
+	MatchLabels              []string     `tfsdk:"match_labels"`
+	AssignRoleOnRegistration types.String `tfsdk:"assign_role_on_registration"`
+	OmniInstance             types.String `tfsdk:"omni_instance"`
+}
+
+func NewOmniSAMLLabelRuleResource() resource.Resource {
+	return &omniSAMLLabelRuleResource{}
+}
+
+func (r *omniSAMLLabelRuleResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_saml_label_rule"
+}
+
+func (r *omniSAMLLabelRuleResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni saml label rule resource")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.providerData = providerData
+}
+
+func (r *omniSAMLLabelRuleResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Assigns an Omni role to users signing in through SAML for the first time, based on the attributes of their SAML assertion. " +
+			"Omni adds the attributes to the identity as labels prefixed with `saml.omni.sidero.dev/`.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
+			"id": schema.StringAttribute{
+				Description: "ID of the rule.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"last_updated": schema.StringAttribute{
+				Computed: true,
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the rule.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"match_labels": schema.ListAttribute{
+				ElementType: types.StringType,
+				Required:    true,
+				Description: "Label selectors matching the identity labels, e.g. `saml.omni.sidero.dev/groups/admins`. The rule applies if any of the selectors matches.",
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+			"assign_role_on_registration": schema.StringAttribute{
+				Required:    true,
+				Description: "Role assigned to the user on registration, one of `None`, `Reader`, `Operator` or `Admin`.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(userRoles...),
+				},
+			},
+		},
+	}
+}
+
+// ValidateConfig checks that the match labels are valid label selectors.
+func (r *omniSAMLLabelRuleResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var matchLabels types.List
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("match_labels"), &matchLabels)...)
+	if resp.Diagnostics.HasError() || matchLabels.IsNull() || matchLabels.IsUnknown() {
+		return
+	}
+
+	for i, element := range matchLabels.Elements() {
+		selector, ok := element.(types.String)
+		if !ok || selector.IsNull() || selector.IsUnknown() {
+			continue
+		}
+
+		if _, err := labels.ParseQuery(selector.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("match_labels").AtListIndex(i), "Invalid label selector", err.Error())
+		}
+	}
+}
+
+func (r *omniSAMLLabelRuleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniSAMLLabelRuleModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rule := auth.NewSAMLLabelRule(resources.DefaultNamespace, plan.Name.ValueString())
+	applySAMLLabelRule(rule, plan)
+
+	if err := instance.state.Create(ctx, rule); err != nil {
+		resp.Diagnostics.AddError("Error creating SAML label rule", err.Error())
+		return
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("created saml label rule %q", plan.Name.ValueString()))
+
+	plan.ID = plan.Name
+
+	timeNow := types.StringValue(time.Now().Format(time.RFC850))
+	plan.CreatedAt = timeNow
+	plan.LastUpdated = timeNow
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniSAMLLabelRuleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniSAMLLabelRuleModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rule, err := safe.StateGetByID[*auth.SAMLLabelRule](ctx, instance.state, config.ID.ValueString())
+	if err != nil {
+		if cosistate.IsNotFoundError(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.AddError("Error reading SAML label rule", err.Error())
+		return
+	}
+
+	samlLabelRuleToModel(rule, &config)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
+
+func (r *omniSAMLLabelRuleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniSAMLLabelRuleModelV0
+	var state OmniSAMLLabelRuleModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, err := safe.StateUpdateWithConflicts(ctx, instance.state, auth.NewSAMLLabelRule(resources.DefaultNamespace, state.ID.ValueString()).Metadata(), func(rule *auth.SAMLLabelRule) error {
+		applySAMLLabelRule(rule, plan)
+
+		return nil
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Error updating SAML label rule", err.Error())
+		return
+	}
+
+	plan.ID = state.ID
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniSAMLLabelRuleResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.State)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state OmniSAMLLabelRuleModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := instance.state.TeardownAndDestroy(ctx, auth.NewSAMLLabelRule(resources.DefaultNamespace, state.ID.ValueString()).Metadata())
+	if err != nil && !cosistate.IsNotFoundError(err) {
+		resp.Diagnostics.AddError("Error deleting SAML label rule", err.Error())
+		return
+	}
+}
+
+func (r *omniSAMLLabelRuleResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importStateWithInstance(ctx, r.providerData, req, resp)
+}
+
+func applySAMLLabelRule(rule *auth.SAMLLabelRule, plan OmniSAMLLabelRuleModelV0) {
+	rule.TypedSpec().Value.MatchLabels = plan.MatchLabels
+	rule.TypedSpec().Value.AssignRoleOnRegistration = plan.AssignRoleOnRegistration.ValueString()
+}
+
+func samlLabelRuleToModel(rule *auth.SAMLLabelRule, model *OmniSAMLLabelRuleModelV0) {
+	model.ID = types.StringValue(rule.Metadata().ID())
+	model.Name = model.ID
+	model.MatchLabels = nonNilStrings(rule.TypedSpec().Value.MatchLabels)
+	model.AssignRoleOnRegistration = types.StringValue(rule.TypedSpec().Value.AssignRoleOnRegistration)
+}
@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/auth"
+)
+
+func TestSAMLLabelRuleRoundTrip(t *testing.T) {
+	ctx := t.Context()
+	st := cosistate.WrapCore(namespaced.NewState(inmem.Build))
+
+	plan := OmniSAMLLabelRuleModelV0{
+		Name:                     types.StringValue("admins"),
+		MatchLabels:              []string{"saml.omni.sidero.dev/groups/admins", "saml.omni.sidero.dev/role=admin"},
+		AssignRoleOnRegistration: types.StringValue(userRoleAdmin),
+	}
+
+	rule := auth.NewSAMLLabelRule(resources.DefaultNamespace, "admins")
+	applySAMLLabelRule(rule, plan)
+
+	if err := st.Create(ctx, rule); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := safe.StateGetByID[*auth.SAMLLabelRule](ctx, st, "admins")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var model OmniSAMLLabelRuleModelV0
+
+	samlLabelRuleToModel(stored, &model)
+
+	if model.ID.ValueString() != "admins" || model.Name.ValueString() != "admins" ||
+		!slices.Equal(model.MatchLabels, plan.MatchLabels) || model.AssignRoleOnRegistration.ValueString() != userRoleAdmin {
+		t.Errorf("unexpected model %+v", model)
+	}
+
+	samlLabelRuleToModel(auth.NewSAMLLabelRule(resources.DefaultNamespace, "empty"), &model)
+
+	if model.MatchLabels == nil || len(model.MatchLabels) != 0 {
+		t.Errorf("expected empty match labels, got %v", model.MatchLabels)
+	}
+}
+
+func TestSAMLLabelRuleValidateConfig(t *testing.T) {
+	matchLabels := func(values ...tftypes.Value) tftypes.Value {
+		return tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, values)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		matchLabels tftypes.Value
+		invalid     []int
+	}{
+		{
+			name:        "existence and equality",
+			matchLabels: matchLabels(tftypes.NewValue(tftypes.String, "saml.omni.sidero.dev/groups/admins"), tftypes.NewValue(tftypes.String, "saml.omni.sidero.dev/role=admin")),
+		},
+		{
+			name:        "set based",
+			matchLabels: matchLabels(tftypes.NewValue(tftypes.String, "saml.omni.sidero.dev/role in (admin, operator)")),
+		},
+		{
+			name:        "unknown selector",
+			matchLabels: matchLabels(tftypes.NewValue(tftypes.String, tftypes.UnknownValue)),
+		},
+		{
+			name:        "unknown list",
+			matchLabels: tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, tftypes.UnknownValue),
+		},
+		{
+			name:        "invalid selector",
+			matchLabels: matchLabels(tftypes.NewValue(tftypes.String, "saml.omni.sidero.dev/role=admin"), tftypes.NewValue(tftypes.String, "saml.omni.sidero.dev/role in (admin")),
+			invalid:     []int{1},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &omniSAMLLabelRuleResource{}
+
+			var resp resource.ValidateConfigResponse
+
+			r.ValidateConfig(t.Context(), resource.ValidateConfigRequest{
+				Config: testResourceConfig(t, r, map[string]tftypes.Value{
+					"name":                        tftypes.NewValue(tftypes.String, "admins"),
+					"match_labels":                tc.matchLabels,
+					"assign_role_on_registration": tftypes.NewValue(tftypes.String, userRoleAdmin),
+				}),
+			}, &resp)
+
+			var invalid []int
+
+			for _, d := range resp.Diagnostics.Errors() {
+				withPath, ok := d.(diag.DiagnosticWithPath)
+				if !ok {
+					continue
+				}
+
+				for i := range 2 {
+					if withPath.Path().Equal(path.Root("match_labels").AtListIndex(i)) {
+						invalid = append(invalid, i)
+					}
+				}
+			}
+
+			if !slices.Equal(invalid, tc.invalid) || len(invalid) != resp.Diagnostics.ErrorsCount() {
+				t.Errorf("expected invalid selectors %v, got %v", tc.invalid, resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestSAMLLabelRuleRoleValidation(t *testing.T) {
+	var schemaResp resource.SchemaResponse
+
+	(&omniSAMLLabelRuleResource{}).Schema(t.Context(), resource.SchemaRequest{}, &schemaResp)
+
+	attribute, ok := schemaResp.Schema.Attributes["assign_role_on_registration"].(schema.StringAttribute)
+	if !ok {
+		t.Fatal("unexpected assign_role_on_registration attribute")
+	}
+
+	for _, tc := range []struct {
+		role  string
+		valid bool
+	}{
+		{role: userRoleNone, valid: true},
+		{role: userRoleReader, valid: true},
+		{role: userRoleOperator, valid: true},
+		{role: userRoleAdmin, valid: true},
+		{role: "Owner"},
+		{role: "admin"},
+	} {
+		t.Run(tc.role, func(t *testing.T) {
+			var resp validator.StringResponse
+
+			for _, v := range attribute.Validators {
+				v.ValidateString(t.Context(), validator.StringRequest{
+					Path:        path.Root("assign_role_on_registration"),
+					ConfigValue: types.StringValue(tc.role),
+				}, &resp)
+			}
+
+			if valid := !resp.Diagnostics.HasError(); valid != tc.valid {
+				t.Errorf("expected valid %v, got %v", tc.valid, resp.Diagnostics)
+			}
+		})
+	}
+}
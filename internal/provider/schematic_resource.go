@@ -0,0 +1,381 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"terraform-provider-omni/internal/models"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/api/omni/management"
+	"github.com/siderolabs/omni/client/pkg/meta"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	_ resource.Resource              = &omniSchematicResource{}
+	_ resource.ResourceWithConfigure = &omniSchematicResource{}
+)
+
+var metaKeyRegexp = regexp.MustCompile(`^[0-9]+$`)
+
+type omniSchematicResource struct {
+	providerData *omniProviderData
+}
+
+type OmniSchematicModelV0 struct {
+	ID                       types.String            `tfsdk:"id"`
+	CreatedAt                types.String            `tfsdk:"created_at"`
+	TalosVersion             types.String            `tfsdk:"talos_version"`
+	Architecture             types.String            `tfsdk:"architecture"`
+	Extensions               models.SystemExtensions `tfsdk:"extensions"`
+	ExtraKernelArgs          []string                `tfsdk:"extra_kernel_args"`
+	MetaValues               map[string]string       `tfsdk:"meta_values"`
+	InitialLabels            models.Labels           `tfsdk:"initial_labels"`
+	MediaID                  types.String            `tfsdk:"media_id"`
+	SecureBoot               types.Bool              `tfsdk:"secure_boot"`
+	UseGRPCTunnel            types.Bool              `tfsdk:"use_grpc_tunnel"`
+	JoinToken                types.String            `tfsdk:"join_token"`
+	Overlay                  types.String            `tfsdk:"overlay"`
+	PXEURL                   types.String            `tfsdk:"pxe_url"`
+	GRPCTunnelEnabled        types.Bool              `tfsdk:"grpc_tunnel_enabled"`
+	InstallerImage           types.String            `tfsdk:"installer_image"`
+	InstallerSecureBootImage types.String            `tfsdk:"installer_secureboot_image"`
+	DownloadURLs             map[string]string       `tfsdk:"download_urls"`
+	OmniInstance             types.String            `tfsdk:"omni_instance"`
+}
+
+func NewOmniSchematicResource() resource.Resource {
+	return &omniSchematicResource{}
+}
+
+func (r *omniSchematicResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_schematic"
+}
+
+func (r *omniSchematicResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni schematic resource")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.providerData = providerData
+}
+
+func (r *omniSchematicResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Omni image factory schematic definition. Schematics are immutable, so any change creates a new schematic.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceResourceAttribute(),
+			"id": schema.StringAttribute{
+				Description: "ID of the schematic in the image factory.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"created_at": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"talos_version": schema.StringAttribute{
+				Required:    true,
+				Description: "Talos version the schematic is generated for.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"architecture": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString("amd64"),
+				Description: "Architecture used to build the per-platform download URLs. Defaults to amd64.",
+				Validators: []validator.String{
+					stringvalidator.OneOf("amd64", "arm64"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"extensions": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "List of system extensions to include, e.g. `siderolabs/qemu-guest-agent`.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"extra_kernel_args": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Extra kernel arguments baked into the schematic.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"meta_values": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Talos META values keyed by the numeric META key.",
+				Validators: []validator.Map{
+					mapvalidator.KeysAre(stringvalidator.RegexMatches(metaKeyRegexp, "must be a numeric META key")),
+				},
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"initial_labels": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Labels applied to machines when they first join Omni.",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"media_id": schema.StringAttribute{
+				Optional:    true,
+				Description: "ID of the Omni installation media the schematic is created for. Installation media for single-board computers carry the board overlay.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"secure_boot": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Controls whether the schematic is created for SecureBoot installation media.",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"use_grpc_tunnel": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Controls whether machines booted from the schematic tunnel SideroLink traffic over gRPC. Omni's own setting is used when unset.",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"join_token": schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "ID of the join token baked into the schematic. The default join token is used when unset.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"overlay": schema.StringAttribute{
+				Computed:    true,
+				Description: "Overlay applied through the selected installation media, if any.",
+			},
+			"pxe_url": schema.StringAttribute{
+				Computed:    true,
+				Description: "PXE boot URL for the schematic.",
+			},
+			"grpc_tunnel_enabled": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the SideroLink gRPC tunnel is enabled for machines booted from the schematic.",
+			},
+			"installer_image": schema.StringAttribute{
+				Computed:    true,
+				Description: "Installer image reference for the schematic.",
+			},
+			"installer_secureboot_image": schema.StringAttribute{
+				Computed:    true,
+				Description: "SecureBoot installer image reference for the schematic.",
+			},
+			"download_urls": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "Image factory download URLs keyed by installation media ID, for all media matching the architecture, overlay, Talos version and secure boot setting. " +
+					"Without an overlay, media for single-board computers are left out.",
+			},
+		},
+	}
+}
+
+func (r *omniSchematicResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	instance, diags := r.providerData.instanceOf(ctx, req.Plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OmniSchematicModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	metaValues := make(map[uint32]string, len(plan.MetaValues))
+	for key, value := range plan.MetaValues {
+		metaKey, err := strconv.ParseUint(key, 10, 32)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid META key", fmt.Sprintf("META key %q is not a number: %s", key, err))
+			return
+		}
+
+		metaValues[uint32(metaKey)] = value
+	}
+
+	if len(plan.InitialLabels) > 0 {
+		labels, err := yaml.Marshal(meta.ImageLabels{
+			Labels: plan.InitialLabels,
+		})
+		if err != nil {
+			resp.Diagnostics.AddError("Error encoding initial labels", err.Error())
+			return
+		}
+
+		metaValues[meta.LabelsMeta] = string(labels)
+	}
+
+	grpcTunnelMode := management.CreateSchematicRequest_AUTO
+	if !plan.UseGRPCTunnel.IsNull() {
+		if plan.UseGRPCTunnel.ValueBool() {
+			grpcTunnelMode = management.CreateSchematicRequest_ENABLED
+		} else {
+			grpcTunnelMode = management.CreateSchematicRequest_DISABLED
+		}
+	}
+
+	st := instance.state
+
+	plan.Overlay = types.StringNull()
+	if !plan.MediaID.IsNull() {
+		media, err := safe.StateGet[*omni.InstallationMedia](ctx, st, omni.NewInstallationMedia(resources.EphemeralNamespace, plan.MediaID.ValueString()).Metadata())
+		if err != nil {
+			resp.Diagnostics.AddError("Error reading installation media", fmt.Sprintf("Could not read installation media %q: %s", plan.MediaID.ValueString(), err))
+			return
+		}
+
+		if overlay := media.TypedSpec().Value.Overlay; overlay != "" {
+			plan.Overlay = types.StringValue(overlay)
+		}
+	}
+
+	schematic, err := instance.client.Management().CreateSchematic(ctx, &management.CreateSchematicRequest{
+		Extensions:               plan.Extensions,
+		ExtraKernelArgs:          plan.ExtraKernelArgs,
+		MetaValues:               metaValues,
+		TalosVersion:             plan.TalosVersion.ValueString(),
+		MediaId:                  plan.MediaID.ValueString(),
+		SecureBoot:               plan.SecureBoot.ValueBool(),
+		SiderolinkGrpcTunnelMode: grpcTunnelMode,
+		JoinToken:                plan.JoinToken.ValueString(),
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Error creating schematic", err.Error())
+		return
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("created schematic %s", schematic.GetSchematicId()))
+
+	plan.ID = types.StringValue(schematic.GetSchematicId())
+	plan.PXEURL = types.StringValue(schematic.GetPxeUrl())
+	plan.GRPCTunnelEnabled = types.BoolValue(schematic.GetGrpcTunnelEnabled())
+
+	baseURL, err := getImageFactoryBaseURL(ctx, st)
+	if err != nil {
+		resp.Diagnostics.AddError("Error reading image factory configuration", err.Error())
+		return
+	}
+
+	installerImage, err := imageFactoryInstallerImage(baseURL, plan.ID.ValueString(), plan.TalosVersion.ValueString(), false)
+	if err != nil {
+		resp.Diagnostics.AddError("Error building installer image reference", err.Error())
+		return
+	}
+	plan.InstallerImage = types.StringValue(installerImage)
+
+	installerSecureBootImage, err := imageFactoryInstallerImage(baseURL, plan.ID.ValueString(), plan.TalosVersion.ValueString(), true)
+	if err != nil {
+		resp.Diagnostics.AddError("Error building installer image reference", err.Error())
+		return
+	}
+	plan.InstallerSecureBootImage = types.StringValue(installerSecureBootImage)
+
+	mediaList, err := safe.StateListAll[*omni.InstallationMedia](ctx, st)
+	if err != nil {
+		resp.Diagnostics.AddError("Error listing installation media", err.Error())
+		return
+	}
+
+	plan.DownloadURLs, err = schematicDownloadURLs(baseURL, &plan, mediaList.All())
+	if err != nil {
+		resp.Diagnostics.AddError("Error building download URLs", err.Error())
+		return
+	}
+
+	plan.CreatedAt = types.StringValue(time.Now().Format(time.RFC850))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniSchematicResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	var config OmniSchematicModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Debug(ctx, "Read schematic from state.")
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
+
+func (r *omniSchematicResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// every configurable attribute requires replacement, so there is nothing to update in place
+	var plan OmniSchematicModelV0
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *omniSchematicResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// schematics are content addressed and can't be removed from the image factory
+	var state OmniSchematicModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
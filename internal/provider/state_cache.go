@@ -0,0 +1,200 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"golang.org/x/sync/singleflight"
+)
+
+// listCacheListTimeout bounds the lists shared by the callers of the list cache, which
+// are not canceled with the context of any of them.
+const listCacheListTimeout = 5 * time.Minute
+
+// listCache holds the results of List for a short time, so that the data sources
+// listing the same resources in a run share a single call. Concurrent lists of the same
+// resources wait for the first one.
+type listCache struct {
+	ttl     time.Duration
+	group   singleflight.Group
+	mu      sync.Mutex
+	entries map[string]listCacheEntry
+
+	// generations counts the writes of each resource type, so that lists which were
+	// in flight during a write are not cached.
+	generations map[string]uint64
+}
+
+type listCacheEntry struct {
+	kind    string
+	list    resource.List
+	expires time.Time
+}
+
+// listCachingState serves List from the list cache. Writes through the state drop the
+// cached lists of the kind they change.
+type listCachingState struct {
+	cosistate.CoreState
+
+	cache *listCache
+}
+
+// listCacheInvalidatingState reads from the underlying state, and drops the cached lists
+// of the kind it changes, so that the data sources see the changes of the resources.
+type listCacheInvalidatingState struct {
+	cosistate.CoreState
+
+	cache *listCache
+}
+
+// newListCachingStates wraps st with a list cache. The cached state is meant for the
+// data sources, while the resources read and write through the uncached one, so that
+// they never update a resource from a stale list. Both are st when ttl is zero.
+func newListCachingStates(st cosistate.State, ttl time.Duration) (cached, uncached cosistate.State) { //nolint:ireturn
+	if ttl <= 0 {
+		return st, st
+	}
+
+	cache := &listCache{
+		ttl:         ttl,
+		entries:     map[string]listCacheEntry{},
+		generations: map[string]uint64{},
+	}
+
+	return cosistate.WrapCore(&listCachingState{CoreState: st, cache: cache}),
+		cosistate.WrapCore(&listCacheInvalidatingState{CoreState: st, cache: cache})
+}
+
+// List serves the cached list, or lists the resources in a call shared with the
+// concurrent callers. Each caller stops waiting for the shared call when its context is
+// done, without canceling the call for the others.
+func (s *listCachingState) List(ctx context.Context, kind resource.Kind, opts ...cosistate.ListOption) (resource.List, error) {
+	var options cosistate.ListOptions
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	kindKey := listCacheKindKey(kind)
+	key := listCacheKey(kind, options)
+
+	s.cache.mu.Lock()
+	entry, ok := s.cache.entries[key]
+	generation := s.cache.generations[kindKey]
+	s.cache.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return copyList(entry.list), nil
+	}
+
+	// lists started after a write don't wait for the ones started before it
+	results := s.cache.group.DoChan(fmt.Sprintf("%s|%d", key, generation), func() (any, error) {
+		listCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), listCacheListTimeout)
+		defer cancel()
+
+		list, err := s.CoreState.List(listCtx, kind, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		s.cache.mu.Lock()
+		if s.cache.generations[kindKey] == generation {
+			s.cache.entries[key] = listCacheEntry{kind: kindKey, list: list, expires: time.Now().Add(s.cache.ttl)}
+		}
+		s.cache.mu.Unlock()
+
+		return list, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return resource.List{}, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return resource.List{}, result.Err
+		}
+
+		return copyList(result.Val.(resource.List)), nil //nolint:forcetypeassert
+	}
+}
+
+func (s *listCachingState) Create(ctx context.Context, res resource.Resource, opts ...cosistate.CreateOption) error {
+	defer s.cache.invalidate(res.Metadata())
+
+	return s.CoreState.Create(ctx, res, opts...)
+}
+
+func (s *listCachingState) Update(ctx context.Context, res resource.Resource, opts ...cosistate.UpdateOption) error {
+	defer s.cache.invalidate(res.Metadata())
+
+	return s.CoreState.Update(ctx, res, opts...)
+}
+
+func (s *listCachingState) Destroy(ctx context.Context, ptr resource.Pointer, opts ...cosistate.DestroyOption) error {
+	defer s.cache.invalidate(ptr)
+
+	return s.CoreState.Destroy(ctx, ptr, opts...)
+}
+
+func (s *listCacheInvalidatingState) Create(ctx context.Context, res resource.Resource, opts ...cosistate.CreateOption) error {
+	defer s.cache.invalidate(res.Metadata())
+
+	return s.CoreState.Create(ctx, res, opts...)
+}
+
+func (s *listCacheInvalidatingState) Update(ctx context.Context, res resource.Resource, opts ...cosistate.UpdateOption) error {
+	defer s.cache.invalidate(res.Metadata())
+
+	return s.CoreState.Update(ctx, res, opts...)
+}
+
+func (s *listCacheInvalidatingState) Destroy(ctx context.Context, ptr resource.Pointer, opts ...cosistate.DestroyOption) error {
+	defer s.cache.invalidate(ptr)
+
+	return s.CoreState.Destroy(ctx, ptr, opts...)
+}
+
+// invalidate drops the cached lists of the kind of the given resource, and keeps the
+// lists in flight from being cached.
+func (c *listCache) invalidate(kind resource.Kind) {
+	kindKey := listCacheKindKey(kind)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generations[kindKey]++
+
+	for key, entry := range c.entries {
+		if entry.kind == kindKey {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func listCacheKindKey(kind resource.Kind) string {
+	return kind.Namespace() + "/" + kind.Type()
+}
+
+func listCacheKey(kind resource.Kind, options cosistate.ListOptions) string {
+	idQuery := ""
+	if options.IDQuery.Regexp != nil {
+		idQuery = options.IDQuery.Regexp.String()
+	}
+
+	return fmt.Sprintf("%s|%s|%+v|%+v", listCacheKindKey(kind), idQuery, options.LabelQueries, options.UnmarshalOptions)
+}
+
+// copyList copies the resources of a cached list, as the callers may modify them.
+func copyList(list resource.List) resource.List {
+	items := make([]resource.Resource, 0, len(list.Items))
+
+	for _, item := range list.Items {
+		items = append(items, item.DeepCopy())
+	}
+
+	return resource.List{Items: items}
+}
@@ -0,0 +1,336 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// countingState counts the lists sent to the underlying state.
+type countingState struct {
+	cosistate.CoreState
+
+	lists atomic.Int64
+}
+
+func (s *countingState) List(ctx context.Context, kind resource.Kind, opts ...cosistate.ListOption) (resource.List, error) {
+	s.lists.Add(1)
+
+	return s.CoreState.List(ctx, kind, opts...)
+}
+
+// blockingListState holds the lists until release is closed.
+type blockingListState struct {
+	cosistate.CoreState
+
+	listing chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingListState) List(ctx context.Context, kind resource.Kind, opts ...cosistate.ListOption) (resource.List, error) {
+	list, err := s.CoreState.List(ctx, kind, opts...)
+
+	s.listing <- struct{}{}
+
+	select {
+	case <-ctx.Done():
+		return resource.List{}, ctx.Err()
+	case <-s.release:
+	}
+
+	return list, err
+}
+
+func newBlockingListState() *blockingListState {
+	return &blockingListState{
+		CoreState: namespaced.NewState(inmem.Build),
+		listing:   make(chan struct{}, 10),
+		release:   make(chan struct{}),
+	}
+}
+
+func TestListCachingState(t *testing.T) {
+	ctx := t.Context()
+
+	counting := &countingState{CoreState: namespaced.NewState(inmem.Build)}
+	st, _ := newListCachingStates(cosistate.WrapCore(counting), time.Minute)
+
+	for _, id := range []string{"machine-1", "machine-2"} {
+		if err := st.Create(ctx, omni.NewMachineStatus(resources.DefaultNamespace, id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Go(func() {
+			machines, err := safe.StateListAll[*omni.MachineStatus](ctx, st)
+			if err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			if machines.Len() != 2 {
+				t.Errorf("expected 2 machines, got %d", machines.Len())
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if lists := counting.lists.Load(); lists != 1 {
+		t.Errorf("expected a single list, got %d", lists)
+	}
+
+	machines, err := safe.StateListAll[*omni.MachineStatus](ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the cached resources are copies
+	machines.Get(0).Metadata().Labels().Set("modified", "")
+
+	if _, err = safe.StateListAll[*omni.MachineStatus](ctx, st, cosistate.WithLabelQuery(resource.LabelExists("modified"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if lists := counting.lists.Load(); lists != 2 {
+		t.Errorf("expected a separate list for a label query, got %d lists", lists)
+	}
+
+	machines, err = safe.StateListAll[*omni.MachineStatus](ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := machines.Get(0).Metadata().Labels().Get("modified"); ok {
+		t.Error("expected the modification of a listed resource not to reach the cache")
+	}
+
+	if err = st.Create(ctx, omni.NewMachineStatus(resources.DefaultNamespace, "machine-3")); err != nil {
+		t.Fatal(err)
+	}
+
+	machines, err = safe.StateListAll[*omni.MachineStatus](ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if machines.Len() != 3 {
+		t.Errorf("expected the created machine to drop the cached list, got %d machines", machines.Len())
+	}
+
+	if lists := counting.lists.Load(); lists != 3 {
+		t.Errorf("expected the list to be sent again after a create, got %d lists", lists)
+	}
+}
+
+func TestListCachingStateDisabled(t *testing.T) {
+	counting := &countingState{CoreState: namespaced.NewState(inmem.Build)}
+	st, _ := newListCachingStates(cosistate.WrapCore(counting), 0)
+
+	for range 2 {
+		if _, err := safe.StateListAll[*omni.MachineStatus](t.Context(), st); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if lists := counting.lists.Load(); lists != 2 {
+		t.Errorf("expected every list to be sent without a cache, got %d lists", lists)
+	}
+}
+
+func TestCallLimiterInFlight(t *testing.T) {
+	limiter := newCallLimiter(0, 1)
+
+	release, err := limiter.acquire(t.Context(), "/cosi.resource.State/List")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err = limiter.acquire(ctx, "/cosi.resource.State/Get"); err == nil {
+		t.Error("expected the second call to wait for the first one")
+	}
+
+	if _, err = limiter.acquire(ctx, watchMethod); err != nil {
+		t.Errorf("expected watches not to count as in-flight calls, got %v", err)
+	}
+
+	release()
+	release()
+
+	if _, err = limiter.acquire(t.Context(), "/cosi.resource.State/Get"); err != nil {
+		t.Errorf("expected the released call to make room, got %v", err)
+	}
+}
+
+// TestListCachingStatesOutsideChange changes a resource outside of the provider after
+// it was listed, as Omni controllers and other clients do.
+func TestListCachingStatesOutsideChange(t *testing.T) {
+	ctx := t.Context()
+
+	st := cosistate.WrapCore(namespaced.NewState(inmem.Build))
+	cached, uncached := newListCachingStates(st, time.Minute)
+
+	if err := uncached.Create(ctx, omni.NewMachineStatus(resources.DefaultNamespace, "machine")); err != nil {
+		t.Fatal(err)
+	}
+
+	listed, err := safe.StateListAll[*omni.MachineStatus](ctx, cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = safe.StateUpdateWithConflicts(ctx, st, listed.Get(0).Metadata(), func(res *omni.MachineStatus) error {
+		res.Metadata().Labels().Set("outside", "")
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh, err := safe.StateListAll[*omni.MachineStatus](ctx, uncached)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fresh.Get(0).Metadata().Version().Equal(listed.Get(0).Metadata().Version()) {
+		t.Fatalf("expected the uncached state to list the changed version %s", fresh.Get(0).Metadata().Version())
+	}
+
+	// updating the resource as listed from the cache would revert the outside change
+	stale := listed.Get(0)
+	stale.Metadata().Labels().Set("provider", "")
+
+	if err = uncached.Update(ctx, stale); !cosistate.IsConflictError(err) {
+		t.Fatalf("expected a conflict updating a stale version, got %v", err)
+	}
+
+	if _, err = safe.StateUpdateWithConflicts(ctx, uncached, stale.Metadata(), func(res *omni.MachineStatus) error {
+		res.Metadata().Labels().Set("provider", "")
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	machines, err := safe.StateListAll[*omni.MachineStatus](ctx, cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := machines.Get(0).Metadata().Labels()
+
+	if _, ok := labels.Get("outside"); !ok {
+		t.Error("expected the update through the uncached state to keep the outside change")
+	}
+
+	if _, ok := labels.Get("provider"); !ok {
+		t.Error("expected the update through the uncached state to drop the cached list")
+	}
+}
+
+// TestListCachingStateWriteDuringList changes the listed resources while the list is in
+// flight.
+func TestListCachingStateWriteDuringList(t *testing.T) {
+	ctx := t.Context()
+
+	blocking := newBlockingListState()
+	cached, uncached := newListCachingStates(cosistate.WrapCore(blocking), time.Minute)
+
+	listed := make(chan int, 1)
+
+	go func() {
+		machines, err := safe.StateListAll[*omni.MachineStatus](ctx, cached)
+		if err != nil {
+			t.Error(err)
+		}
+
+		listed <- machines.Len()
+	}()
+
+	<-blocking.listing
+
+	if err := uncached.Create(ctx, omni.NewMachineStatus(resources.DefaultNamespace, "machine")); err != nil {
+		t.Fatal(err)
+	}
+
+	close(blocking.release)
+
+	if count := <-listed; count != 0 {
+		t.Fatalf("expected the list started before the create to miss the machine, got %d machines", count)
+	}
+
+	machines, err := safe.StateListAll[*omni.MachineStatus](ctx, cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if machines.Len() != 1 {
+		t.Errorf("expected the list in flight during the create not to be cached, got %d machines", machines.Len())
+	}
+}
+
+// TestListCachingStateCanceledCaller cancels the caller which started a shared list.
+func TestListCachingStateCanceledCaller(t *testing.T) {
+	blocking := newBlockingListState()
+	cached, _ := newListCachingStates(cosistate.WrapCore(blocking), time.Minute)
+
+	if err := cached.Create(t.Context(), omni.NewMachineStatus(resources.DefaultNamespace, "machine")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	canceled := make(chan error, 1)
+
+	go func() {
+		_, err := safe.StateListAll[*omni.MachineStatus](ctx, cached)
+
+		canceled <- err
+	}()
+
+	<-blocking.listing
+
+	listed := make(chan int, 1)
+
+	go func() {
+		machines, err := safe.StateListAll[*omni.MachineStatus](t.Context(), cached)
+		if err != nil {
+			t.Error(err)
+		}
+
+		listed <- machines.Len()
+	}()
+
+	// let the second caller wait for the shared list
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+
+	if err := <-canceled; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the canceled caller to stop waiting, got %v", err)
+	}
+
+	close(blocking.release)
+
+	if count := <-listed; count != 1 {
+		t.Errorf("expected the shared list to survive the canceled caller, got %d machines", count)
+	}
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"terraform-provider-omni/internal/models"
+)
+
+// patchV0 is a patch of the version 0 template resource states.
+type patchV0 struct {
+	IDOverride  types.String       `tfsdk:"id_override"`
+	Labels      models.Labels      `tfsdk:"labels"`
+	Annotations models.Annotations `tfsdk:"annotations"`
+	File        *string            `tfsdk:"file"`
+	Inline      *string            `tfsdk:"inline"`
+}
+
+// patchesAttributeV0 returns the patches attribute of the version 0 template resource
+// schemas.
+func patchesAttributeV0() schema.Attribute {
+	return schema.ListNestedAttribute{
+		NestedObject: schema.NestedAttributeObject{
+			Attributes: map[string]schema.Attribute{
+				"id_override": schema.StringAttribute{
+					Optional:    true,
+					Description: "The name (ID) of the patch.",
+				},
+				"labels": schema.MapAttribute{
+					ElementType: types.StringType,
+					Optional:    true,
+					Description: "The labels of the patch.",
+				},
+				"annotations": schema.MapAttribute{
+					ElementType: types.StringType,
+					Optional:    true,
+					Description: "The annotations of the patch.",
+				},
+				"file": schema.StringAttribute{
+					Optional:    true,
+					Description: "The file path to use as input for the patch.",
+				},
+				"inline": schema.StringAttribute{
+					Optional:    true,
+					Description: "The inline patch as YAML.",
+				},
+			},
+		},
+		Optional:    true,
+		Description: "Machine-specific patches.",
+	}
+}
+
+func upgradePatchesV0(prior []patchV0) []models.Patch {
+	if prior == nil {
+		return nil
+	}
+
+	patches := make([]models.Patch, 0, len(prior))
+
+	for _, patch := range prior {
+		patches = append(patches, models.Patch{
+			IDOverride:  patch.IDOverride.ValueString(),
+			Labels:      patch.Labels,
+			Annotations: patch.Annotations,
+			File:        patch.File,
+			Inline:      patch.Inline,
+		})
+	}
+
+	return patches
+}
+
+// stateUpgrader returns the state upgrader of a resource from the model of a prior
+// schema version to the current one.
+func stateUpgrader[Prior, Current any](priorSchema *schema.Schema, upgrade func(Prior) Current) resource.StateUpgrader {
+	return resource.StateUpgrader{
+		PriorSchema: priorSchema,
+		StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+			var prior Prior
+			resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+
+			resp.Diagnostics.Append(resp.State.Set(ctx, upgrade(prior))...)
+		},
+	}
+}
@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// upgradedDefaults are the values of the attributes added since version 0 which are
+// not null after the upgrade.
+var upgradedDefaults = map[string]map[string]tftypes.Value{
+	"omni_cluster": {
+		"machine_removal": tftypes.NewValue(tftypes.String, machineRemovalKeep),
+	},
+}
+
+// TestUpgradeStateV0 upgrades the state recorded with version 0 of the resource schemas
+// through the provider server, as Terraform does when it reads an existing state.
+func TestUpgradeStateV0(t *testing.T) {
+	ctx := context.Background()
+
+	server := providerserver.NewProtocol6(New("test")())()
+
+	for typeName, r := range map[string]resource.Resource{
+		"omni_cluster":                      NewOmniClusterResource(),
+		"omni_cluster_kubeconfig":           NewOmniClusterKubeConfigResource(),
+		"omni_cluster_machine_set_template": NewOmniClusterMachineSetTemplateResource(),
+		"omni_cluster_machine_template":     NewOmniClusterMachinesTemplateResource(),
+	} {
+		t.Run(typeName, func(t *testing.T) {
+			fixture, err := os.ReadFile(filepath.Join("testdata", "state_upgrade", typeName+"_v0.json"))
+			if err != nil {
+				t.Fatalf("failed to read fixture: %s", err)
+			}
+
+			var schemaResp resource.SchemaResponse
+
+			r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+
+			if schemaResp.Schema.Version != 1 {
+				t.Fatalf("expected schema version 1, got %d", schemaResp.Schema.Version)
+			}
+
+			resp, err := server.UpgradeResourceState(ctx, &tfprotov6.UpgradeResourceStateRequest{
+				TypeName: typeName,
+				Version:  0,
+				RawState: &tfprotov6.RawState{JSON: fixture},
+			})
+			if err != nil {
+				t.Fatalf("failed to upgrade state: %s", err)
+			}
+
+			for _, diag := range resp.Diagnostics {
+				t.Errorf("unexpected diagnostic: %s: %s", diag.Summary, diag.Detail)
+			}
+
+			if resp.UpgradedState == nil {
+				t.Fatal("expected an upgraded state")
+			}
+
+			upgraded, err := resp.UpgradedState.Unmarshal(schemaResp.Schema.Type().TerraformType(ctx))
+			if err != nil {
+				t.Fatalf("failed to unmarshal upgraded state: %s", err)
+			}
+
+			priorSchema := r.(resource.ResourceWithUpgradeState).UpgradeState(ctx)[0].PriorSchema
+
+			// the fixtures hold the attributes of version 0 only, which fails on any other
+			prior, err := (&tfprotov6.RawState{JSON: fixture}).Unmarshal(priorSchema.Type().TerraformType(ctx))
+			if err != nil {
+				t.Fatalf("failed to unmarshal fixture: %s", err)
+			}
+
+			var upgradedAttributes, priorAttributes map[string]tftypes.Value
+
+			if err := upgraded.As(&upgradedAttributes); err != nil {
+				t.Fatalf("failed to read upgraded state: %s", err)
+			}
+
+			if err := prior.As(&priorAttributes); err != nil {
+				t.Fatalf("failed to read fixture: %s", err)
+			}
+
+			for name, value := range priorAttributes {
+				if !preservedByUpgrade(value, upgradedAttributes[name]) {
+					t.Errorf("attribute %q changed by the upgrade: %s -> %s", name, value, upgradedAttributes[name])
+				}
+			}
+
+			for name, value := range upgradedAttributes {
+				if _, ok := priorAttributes[name]; ok {
+					continue
+				}
+
+				if expected, ok := upgradedDefaults[typeName][name]; ok {
+					if !value.Equal(expected) {
+						t.Errorf("expected %s for the added attribute %q, got %s", expected, name, value)
+					}
+
+					continue
+				}
+
+				if !value.IsNull() {
+					t.Errorf("expected a null value for the added attribute %q, got %s", name, value)
+				}
+			}
+		})
+	}
+}
+
+// preservedByUpgrade reports whether the upgraded value keeps the prior value, ignoring
+// the attributes added to nested objects.
+func preservedByUpgrade(prior, upgraded tftypes.Value) bool {
+	if prior.IsNull() || upgraded.IsNull() {
+		return prior.IsNull() && upgraded.IsNull()
+	}
+
+	switch {
+	case prior.Type().Is(tftypes.Object{}):
+		var priorAttributes, upgradedAttributes map[string]tftypes.Value
+
+		if prior.As(&priorAttributes) != nil || upgraded.As(&upgradedAttributes) != nil {
+			return false
+		}
+
+		for name, value := range priorAttributes {
+			if !preservedByUpgrade(value, upgradedAttributes[name]) {
+				return false
+			}
+		}
+
+		return true
+	case prior.Type().Is(tftypes.List{}):
+		var priorElements, upgradedElements []tftypes.Value
+
+		if prior.As(&priorElements) != nil || upgraded.As(&upgradedElements) != nil || len(priorElements) != len(upgradedElements) {
+			return false
+		}
+
+		for i := range priorElements {
+			if !preservedByUpgrade(priorElements[i], upgradedElements[i]) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return prior.Equal(upgraded)
+	}
+}
@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+var _ datasource.DataSource = &omniTalosVersionsDataSource{}
+
+type omniTalosVersionsDataSource struct {
+	providerData *omniProviderData
+}
+
+type OmniTalosVersionModel struct {
+	Version                      types.String `tfsdk:"version"`
+	Deprecated                   types.Bool   `tfsdk:"deprecated"`
+	CompatibleKubernetesVersions []string     `tfsdk:"compatible_kubernetes_versions"`
+}
+
+type OmniTalosVersionsModelV0 struct {
+	ID                 types.String            `tfsdk:"id"`
+	Constraint         types.String            `tfsdk:"constraint"`
+	IncludeDeprecated  types.Bool              `tfsdk:"include_deprecated"`
+	IncludePrereleases types.Bool              `tfsdk:"include_prereleases"`
+	Latest             types.String            `tfsdk:"latest"`
+	Versions           []OmniTalosVersionModel `tfsdk:"versions"`
+	OmniInstance       types.String            `tfsdk:"omni_instance"`
+}
+
+func NewOmniTalosVersionsDataSource() datasource.DataSource {
+	return &omniTalosVersionsDataSource{}
+}
+
+func (d *omniTalosVersionsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_talos_versions"
+}
+
+func (d *omniTalosVersionsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the Talos versions available in Omni.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceDataSourceAttribute(),
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "ID of the data source.",
+			},
+			"constraint": schema.StringAttribute{
+				Optional:    true,
+				Description: "Semver constraint to filter versions by, e.g. `1.9.x` or `>=1.9.0 <1.11.0`.",
+			},
+			"include_deprecated": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether to include versions Omni marks as deprecated. Defaults to false.",
+			},
+			"include_prereleases": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether to include pre-release versions. Defaults to false.",
+			},
+			"latest": schema.StringAttribute{
+				Computed:    true,
+				Description: "Newest version matching the filters, null when no version matches.",
+			},
+			"versions": schema.ListNestedAttribute{
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"version": schema.StringAttribute{
+							Computed:    true,
+							Description: "Talos version.",
+						},
+						"deprecated": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether Omni marks the version as deprecated.",
+						},
+						"compatible_kubernetes_versions": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "Kubernetes versions compatible with the Talos version.",
+						},
+					},
+				},
+				Computed:    true,
+				Description: "Versions matching the filters, sorted from the oldest to the newest.",
+			},
+		},
+	}
+}
+
+func (d *omniTalosVersionsDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni talos versions data source")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.providerData = providerData
+}
+
+func (d *omniTalosVersionsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if d.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	instance, diags := d.providerData.instanceOf(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniTalosVersionsModelV0
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	talosVersions, err := listTalosVersions(ctx, instance.cachedState)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to get omni talos versions", err.Error())
+		return
+	}
+
+	selected, err := selectTalosVersions(talosVersions, config.Constraint.ValueString(), config.IncludeDeprecated.ValueBool(), config.IncludePrereleases.ValueBool())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("constraint"), "Invalid version constraint", err.Error())
+		return
+	}
+
+	config.Versions = make([]OmniTalosVersionModel, 0, len(selected))
+	config.Latest = types.StringNull()
+
+	for _, talosVersion := range selected {
+		spec := talosVersion.TypedSpec().Value
+
+		compatible, err := filterVersions(spec.CompatibleKubernetesVersions, "", true)
+		if err != nil {
+			resp.Diagnostics.AddError("Error reading talos version", err.Error())
+			return
+		}
+
+		config.Versions = append(config.Versions, OmniTalosVersionModel{
+			Version:                      types.StringValue(spec.Version),
+			Deprecated:                   types.BoolValue(spec.Deprecated),
+			CompatibleKubernetesVersions: compatible,
+		})
+		config.Latest = types.StringValue(spec.Version)
+	}
+
+	config.ID = types.StringValue("talos-versions")
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
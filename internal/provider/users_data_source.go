@@ -0,0 +1,193 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	cosiresource "github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/auth"
+)
+
+var _ datasource.DataSource = &omniUsersDataSource{}
+
+type omniUsersDataSource struct {
+	providerData *omniProviderData
+}
+
+type OmniUsersUserModel struct {
+	ID         types.String      `tfsdk:"id"`
+	Email      types.String      `tfsdk:"email"`
+	Role       types.String      `tfsdk:"role"`
+	SAMLLabels map[string]string `tfsdk:"saml_labels"`
+}
+
+type OmniUsersModelV0 struct {
+	ID           types.String         `tfsdk:"id"`
+	Role         types.String         `tfsdk:"role"`
+	Users        []OmniUsersUserModel `tfsdk:"users"`
+	OmniInstance types.String         `tfsdk:"omni_instance"`
+}
+
+func NewOmniUsersDataSource() datasource.DataSource {
+	return &omniUsersDataSource{}
+}
+
+func (d *omniUsersDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_users"
+}
+
+func (d *omniUsersDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the Omni users with their identities and roles, e.g. to audit access to Omni. Service accounts are not listed.",
+		Attributes: map[string]schema.Attribute{
+			"omni_instance": omniInstanceDataSourceAttribute(),
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "ID of the data source.",
+			},
+			"role": schema.StringAttribute{
+				Optional:    true,
+				Description: "Only list users with this role.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(userRoles...),
+				},
+			},
+			"users": schema.ListNestedAttribute{
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Computed:    true,
+							Description: "ID of the user.",
+						},
+						"email": schema.StringAttribute{
+							Computed:    true,
+							Description: "Email of the identity.",
+						},
+						"role": schema.StringAttribute{
+							Computed:    true,
+							Description: "Role of the user.",
+						},
+						"saml_labels": schema.MapAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "Attributes of the last SAML assertion of the identity.",
+						},
+					},
+				},
+				Computed:    true,
+				Description: "Identities and their users, sorted by email.",
+			},
+		},
+	}
+}
+
+func (d *omniUsersDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*omniProviderData)
+	tflog.Debug(context.Background(), "Configuring omni users data source")
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get omni client",
+			fmt.Sprintf("Expected *omniProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.providerData = providerData
+}
+
+func (d *omniUsersDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if d.providerData == nil {
+		resp.Diagnostics.AddError("omni client is not configured", "Please report this issue to the provider developers.")
+		return
+	}
+
+	instance, diags := d.providerData.instanceOf(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var config OmniUsersModelV0
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	st := instance.cachedState
+
+	identities, err := safe.StateListAll[*auth.Identity](ctx, st, cosistate.WithLabelQuery(
+		cosiresource.LabelExists(auth.LabelIdentityTypeServiceAccount, cosiresource.NotMatches),
+	))
+	if err != nil {
+		resp.Diagnostics.AddError("failed to get omni identities", err.Error())
+		return
+	}
+
+	users, err := safe.StateListAll[*auth.User](ctx, st)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to get omni users", err.Error())
+		return
+	}
+
+	config.Users = usersFromIdentities(slices.Collect(identities.All()), slices.Collect(users.All()), config.Role.ValueString())
+	config.ID = types.StringValue("users")
+
+	tflog.Debug(ctx, fmt.Sprintf("number of users found: %d", len(config.Users)))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
+
+// usersFromIdentities joins the identities with their users, the same way omnictl lists
+// users. Identities of users which no longer exist have no role.
+func usersFromIdentities(identities []*auth.Identity, users []*auth.User, role string) []OmniUsersUserModel {
+	roles := make(map[string]string, len(users))
+	for _, user := range users {
+		roles[user.Metadata().ID()] = user.TypedSpec().Value.Role
+	}
+
+	out := make([]OmniUsersUserModel, 0, len(identities))
+
+	for _, identity := range identities {
+		userID := identity.TypedSpec().Value.UserId
+
+		if role != "" && roles[userID] != role {
+			continue
+		}
+
+		samlLabels := map[string]string{}
+
+		for key, value := range identity.Metadata().Labels().Raw() {
+			if name, ok := strings.CutPrefix(key, auth.SAMLLabelPrefix); ok {
+				samlLabels[name] = value
+			}
+		}
+
+		out = append(out, OmniUsersUserModel{
+			ID:         types.StringValue(userID),
+			Email:      types.StringValue(identity.Metadata().ID()),
+			Role:       types.StringValue(roles[userID]),
+			SAMLLabels: samlLabels,
+		})
+	}
+
+	slices.SortFunc(out, func(a, b OmniUsersUserModel) int {
+		return strings.Compare(a.Email.ValueString(), b.Email.ValueString())
+	})
+
+	return out
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/auth"
+)
+
+func TestUsersFromIdentities(t *testing.T) {
+	admin := auth.NewUser(resources.DefaultNamespace, "admin-id")
+	admin.TypedSpec().Value.Role = userRoleAdmin
+
+	reader := auth.NewUser(resources.DefaultNamespace, "reader-id")
+	reader.TypedSpec().Value.Role = userRoleReader
+
+	newIdentity := func(email string, userID string) *auth.Identity {
+		identity := auth.NewIdentity(resources.DefaultNamespace, email)
+		setIdentityUser(identity, userID)
+
+		return identity
+	}
+
+	samlIdentity := newIdentity("b@example.com", "reader-id")
+	samlIdentity.Metadata().Labels().Set(auth.LabelSAMLGroups, "engineering")
+
+	identities := []*auth.Identity{
+		samlIdentity,
+		newIdentity("a@example.com", "admin-id"),
+		newIdentity("c@example.com", "deleted-id"),
+	}
+	users := []*auth.User{admin, reader}
+
+	all := usersFromIdentities(identities, users, "")
+	if len(all) != 3 {
+		t.Fatalf("expected 3 users, got %d", len(all))
+	}
+
+	for i, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if all[i].Email.ValueString() != email {
+			t.Errorf("expected user %d to be %q, got %q", i, email, all[i].Email.ValueString())
+		}
+	}
+
+	if all[1].Role.ValueString() != userRoleReader || all[1].SAMLLabels["groups"] != "engineering" {
+		t.Errorf("unexpected reader %+v", all[1])
+	}
+
+	if all[2].Role.ValueString() != "" {
+		t.Errorf("expected no role for a deleted user, got %q", all[2].Role.ValueString())
+	}
+
+	admins := usersFromIdentities(identities, users, userRoleAdmin)
+	if len(admins) != 1 || admins[0].ID.ValueString() != "admin-id" {
+		t.Errorf("expected only the admin, got %+v", admins)
+	}
+}
@@ -13,6 +13,12 @@ const (
 	KindWorkers      = "Workers"
 )
 
+// Machine set update and delete strategy types, as named by Omni.
+const (
+	machineSetStrategyRolling = "Rolling"
+	machineSetStrategyUnset   = "Unset"
+)
+
 func convertClusterTalosOptionsToYAML(talosOptions models.ClusterTalos) models.ClusterTalosYAML {
 	return models.ClusterTalosYAML{
 		Version: talosOptions.Version.ValueString(),
@@ -25,14 +31,76 @@ func convertClusterKubernetesOptionsToYAML(kubernetesOptions models.ClusterKuber
 	}
 }
 
-func convertClusterFeaturesToYAML(features models.ClusterFeatures) models.ClusterFeaturesYAML {
-	return models.ClusterFeaturesYAML{
+func convertClusterFeaturesToYAML(features *models.ClusterFeatures) models.ClusterFeaturesYAML {
+	if features == nil {
+		return models.ClusterFeaturesYAML{}
+	}
+
+	featuresYAML := models.ClusterFeaturesYAML{
 		DiskEncryption:              features.DiskEncryption.ValueBool(),
 		EnableWorkloadProxy:         features.EnableWorkloadProxy.ValueBool(),
 		UseEmbeddedDiscoveryService: features.UseEmbeddedDiscoveryService.ValueBool(),
-		BackupConfiguration: models.ClusterBackupConfigurationYAML{
+	}
+
+	if features.BackupConfiguration != nil {
+		featuresYAML.BackupConfiguration = &models.ClusterBackupConfigurationYAML{
 			Interval: features.BackupConfiguration.Interval.ValueString(),
-		},
+		}
+	}
+
+	return featuresYAML
+}
+
+func convertBootstrapSpecToYAML(bootstrapSpec *models.BootstrapSpec) *models.BootstrapSpecYAML {
+	if bootstrapSpec == nil {
+		return nil
+	}
+
+	return &models.BootstrapSpecYAML{
+		ClusterUUID: bootstrapSpec.ClusterUUID.ValueString(),
+		Snapshot:    bootstrapSpec.Snapshot.ValueString(),
+	}
+}
+
+func convertMachineClassToYAML(machineClass *models.MachineClass) *models.MachineClassYAML {
+	if machineClass == nil {
+		return nil
+	}
+
+	return &models.MachineClassYAML{
+		Name: machineClass.Name.ValueString(),
+		Size: machineClass.Size.ValueString(),
+	}
+}
+
+func convertUpdateStrategyToYAML(strategy *models.UpdateStrategy) *models.UpdateStrategyYAML {
+	if strategy == nil {
+		return nil
+	}
+
+	strategyYAML := &models.UpdateStrategyYAML{}
+
+	if !strategy.Type.IsNull() {
+		strategyType := strategy.Type.ValueString()
+		strategyYAML.Type = &strategyType
+	}
+
+	if !strategy.MaxParallelism.IsNull() {
+		strategyYAML.Rolling = &models.RollingUpdateStrategyYAML{
+			MaxParallelism: uint32(strategy.MaxParallelism.ValueInt64()),
+		}
+	}
+
+	return strategyYAML
+}
+
+func convertMachineInstallToYAML(install *models.MachineInstall) *models.MachineInstallYAML {
+	if install == nil || install.Disk.IsNull() {
+		return nil
+	}
+
+	return &models.MachineInstallYAML{
+		Disk: install.Disk.ValueString(),
 	}
 }
 
@@ -44,7 +112,14 @@ func convertPatchToYAML(patches []models.Patch) []models.PatchYAML {
 		if patch.Inline != nil && *patch.Inline != "" {
 			_ = yaml.Unmarshal([]byte(*patch.Inline), &inlineYAML)
 		}
+
+		var name string
+		if patch.Name != nil {
+			name = *patch.Name
+		}
+
 		patchYAML = append(patchYAML, models.PatchYAML{
+			Name:        name,
 			IDOverride:  patch.IDOverride,
 			Labels:      patch.Labels,
 			Annotations: patch.Annotations,
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// filterVersions returns the versions matching the semver constraint, sorted from the
+// oldest to the newest. An empty constraint matches every version.
+func filterVersions(versions []string, constraint string, includePrereleases bool) ([]string, error) {
+	matches := func(semver.Version) bool { return true }
+
+	if constraint != "" {
+		versionRange, err := semver.ParseRange(constraint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse version constraint %q: %w", constraint, err)
+		}
+
+		matches = versionRange
+	}
+
+	parsed := make([]semver.Version, 0, len(versions))
+
+	for _, version := range versions {
+		v, err := semver.ParseTolerant(version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse version %q: %w", version, err)
+		}
+
+		if len(v.Pre) > 0 && !includePrereleases {
+			continue
+		}
+
+		if matches(v) {
+			parsed = append(parsed, v)
+		}
+	}
+
+	slices.SortFunc(parsed, func(a, b semver.Version) int { return a.Compare(b) })
+
+	filtered := make([]string, 0, len(parsed))
+	for _, v := range parsed {
+		filtered = append(filtered, v.String())
+	}
+
+	return filtered, nil
+}
+
+// selectTalosVersions returns the Talos versions matching the semver constraint, sorted
+// from the oldest to the newest by their parsed versions, so that the "v" prefix and
+// pre-release suffixes compare correctly.
+func selectTalosVersions(talosVersions []*omni.TalosVersion, constraint string, includeDeprecated, includePrereleases bool) ([]*omni.TalosVersion, error) {
+	byVersion := make(map[string]*omni.TalosVersion, len(talosVersions))
+	candidates := make([]string, 0, len(talosVersions))
+
+	for _, talosVersion := range talosVersions {
+		spec := talosVersion.TypedSpec().Value
+		if spec.Deprecated && !includeDeprecated {
+			continue
+		}
+
+		v, err := semver.ParseTolerant(spec.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse version %q: %w", spec.Version, err)
+		}
+
+		byVersion[v.String()] = talosVersion
+		candidates = append(candidates, spec.Version)
+	}
+
+	filtered, err := filterVersions(candidates, constraint, includePrereleases)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]*omni.TalosVersion, 0, len(filtered))
+	for _, version := range filtered {
+		selected = append(selected, byVersion[version])
+	}
+
+	return selected, nil
+}
+
+// listTalosVersions returns the Talos versions known to Omni.
+func listTalosVersions(ctx context.Context, st state.State) ([]*omni.TalosVersion, error) {
+	list, err := safe.StateListAll[*omni.TalosVersion](ctx, st)
+	if err != nil {
+		return nil, err
+	}
+
+	talosVersions := make([]*omni.TalosVersion, 0, list.Len())
+	list.ForEach(func(talosVersion *omni.TalosVersion) {
+		talosVersions = append(talosVersions, talosVersion)
+	})
+
+	return talosVersions, nil
+}
+
+// findTalosVersion returns the Talos version resource matching the version, ignoring
+// a leading "v".
+func findTalosVersion(talosVersions []*omni.TalosVersion, version string) (*omni.TalosVersion, bool) {
+	version = strings.TrimPrefix(version, "v")
+
+	for _, talosVersion := range talosVersions {
+		if strings.TrimPrefix(talosVersion.TypedSpec().Value.Version, "v") == version {
+			return talosVersion, true
+		}
+	}
+
+	return nil, false
+}
+
+// checkClusterVersions verifies that Omni supports the Talos version, and that the
+// Kubernetes version is compatible with it.
+func checkClusterVersions(talosVersions []*omni.TalosVersion, talosVersion string, kubernetesVersion string) error {
+	found, ok := findTalosVersion(talosVersions, talosVersion)
+	if !ok {
+		available := make([]string, 0, len(talosVersions))
+		for _, v := range talosVersions {
+			available = append(available, v.TypedSpec().Value.Version)
+		}
+
+		if sorted, err := filterVersions(available, "", true); err == nil {
+			available = sorted
+		}
+
+		return fmt.Errorf("Talos version %q is not available in Omni, available versions: %s", talosVersion, strings.Join(available, ", "))
+	}
+
+	compatible := found.TypedSpec().Value.CompatibleKubernetesVersions
+	if !slices.Contains(compatible, strings.TrimPrefix(kubernetesVersion, "v")) {
+		return fmt.Errorf("Kubernetes version %q is not compatible with Talos version %q, compatible versions: %s", kubernetesVersion, talosVersion, strings.Join(compatible, ", "))
+	}
+
+	return nil
+}
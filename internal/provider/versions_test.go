@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+func TestFilterVersions(t *testing.T) {
+	versions := []string{"1.10.0", "1.9.5", "v1.9.0", "1.11.0-beta.0", "1.8.3", "1.9.6"}
+
+	for _, tc := range []struct {
+		constraint         string
+		includePrereleases bool
+		expected           []string
+	}{
+		{expected: []string{"1.8.3", "1.9.0", "1.9.5", "1.9.6", "1.10.0"}},
+		{includePrereleases: true, expected: []string{"1.8.3", "1.9.0", "1.9.5", "1.9.6", "1.10.0", "1.11.0-beta.0"}},
+		{constraint: "1.9.x", expected: []string{"1.9.0", "1.9.5", "1.9.6"}},
+		{constraint: ">=1.9.5 <1.11.0", expected: []string{"1.9.5", "1.9.6", "1.10.0"}},
+		{constraint: ">=1.12.0", expected: []string{}},
+	} {
+		actual, err := filterVersions(versions, tc.constraint, tc.includePrereleases)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(actual, tc.expected) {
+			t.Errorf("constraint %q: expected %v, got %v", tc.constraint, tc.expected, actual)
+		}
+	}
+
+	if _, err := filterVersions(versions, "latest", false); err == nil {
+		t.Error("expected an error for an invalid constraint")
+	}
+}
+
+func TestCheckClusterVersions(t *testing.T) {
+	talosVersion := omni.NewTalosVersion(resources.DefaultNamespace, "1.11.2")
+	talosVersion.TypedSpec().Value.Version = "1.11.2"
+	talosVersion.TypedSpec().Value.CompatibleKubernetesVersions = []string{"1.32.9", "1.33.4", "1.34.1"}
+
+	talosVersions := []*omni.TalosVersion{talosVersion}
+
+	for _, tc := range []struct {
+		talosVersion      string
+		kubernetesVersion string
+		valid             bool
+	}{
+		{talosVersion: "1.11.2", kubernetesVersion: "1.33.4", valid: true},
+		{talosVersion: "v1.11.2", kubernetesVersion: "v1.34.1", valid: true},
+		{talosVersion: "1.11.2", kubernetesVersion: "1.31.0"},
+		{talosVersion: "1.10.0", kubernetesVersion: "1.33.4"},
+	} {
+		err := checkClusterVersions(talosVersions, tc.talosVersion, tc.kubernetesVersion)
+		if tc.valid && err != nil {
+			t.Errorf("talos %s, kubernetes %s: unexpected error: %s", tc.talosVersion, tc.kubernetesVersion, err)
+		}
+
+		if !tc.valid && err == nil {
+			t.Errorf("talos %s, kubernetes %s: expected an error", tc.talosVersion, tc.kubernetesVersion)
+		}
+	}
+}
+
+func TestSelectTalosVersions(t *testing.T) {
+	var talosVersions []*omni.TalosVersion
+
+	for _, version := range []string{"1.10.0-alpha.1", "v1.9.0", "1.10.0", "v1.9.10", "1.9.2", "v1.11.0-beta.0", "1.8.4"} {
+		talosVersion := omni.NewTalosVersion(resources.DefaultNamespace, version)
+		talosVersion.TypedSpec().Value.Version = version
+		talosVersion.TypedSpec().Value.Deprecated = version == "1.8.4"
+
+		talosVersions = append(talosVersions, talosVersion)
+	}
+
+	for _, tc := range []struct {
+		name               string
+		constraint         string
+		includeDeprecated  bool
+		includePrereleases bool
+		expected           []string
+	}{
+		{name: "released", expected: []string{"v1.9.0", "1.9.2", "v1.9.10", "1.10.0"}},
+		{name: "prereleases", includePrereleases: true, expected: []string{"v1.9.0", "1.9.2", "v1.9.10", "1.10.0-alpha.1", "1.10.0", "v1.11.0-beta.0"}},
+		{name: "deprecated", includeDeprecated: true, expected: []string{"1.8.4", "v1.9.0", "1.9.2", "v1.9.10", "1.10.0"}},
+		{name: "constraint", constraint: "1.9.x", expected: []string{"v1.9.0", "1.9.2", "v1.9.10"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, err := selectTalosVersions(talosVersions, tc.constraint, tc.includeDeprecated, tc.includePrereleases)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := make([]string, 0, len(selected))
+			for _, talosVersion := range selected {
+				actual = append(actual, talosVersion.TypedSpec().Value.Version)
+			}
+
+			if !slices.Equal(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}